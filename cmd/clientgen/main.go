@@ -0,0 +1,138 @@
+// Command clientgen writes the typed per-kind methods of the httpclient
+// package, one set per kind registered with objectdb:
+//
+//	//go:generate go run ../../cmd/clientgen
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"go-assignment/objectdb"
+)
+
+var output = flag.String("output", "kinds_gen.go", "output file name")
+
+var tmpl = template.Must(template.New("client").Parse(`// Code generated by clientgen. DO NOT EDIT.
+
+package httpclient
+
+import (
+	"context"
+	"net/url"
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Kinds}}
+// Get{{.Name}} reads the {{.Name}} with the given ID.
+func (c *Client) Get{{.Name}}(ctx context.Context, id string) (*{{.Type}}, error) {
+	var out {{.Type}}
+	if err := c.Get(ctx, "{{.Short}}", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Create{{.Name}} stores object, which must not exist yet; see Client.Create.
+func (c *Client) Create{{.Name}}(ctx context.Context, object *{{.Type}}) error {
+	return c.Create(ctx, "{{.Short}}", object)
+}
+
+// Put{{.Name}} creates or replaces object; see Client.Put.
+func (c *Client) Put{{.Name}}(ctx context.Context, object *{{.Type}}) error {
+	return c.Put(ctx, "{{.Short}}", object)
+}
+
+// Patch{{.Name}} applies a JSON Patch to the {{.Name}} with the given ID and
+// returns the result; see Client.Patch.
+func (c *Client) Patch{{.Name}}(ctx context.Context, id string, patch []byte) (*{{.Type}}, error) {
+	var out {{.Type}}
+	if err := c.Patch(ctx, "{{.Short}}", id, patch, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Delete{{.Name}} deletes the {{.Name}} with the given ID; see Client.Delete.
+func (c *Client) Delete{{.Name}}(ctx context.Context, id string, policy objectdb.DeletePolicy) error {
+	return c.Delete(ctx, "{{.Short}}", id, policy)
+}
+
+// List{{.Name}}s lists one page of {{.Name}} objects starting at cursor,
+// "" for the first page, and returns the cursor of the next; "" means done.
+func (c *Client) List{{.Name}}s(ctx context.Context, cursor string) ([]*{{.Type}}, string, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	return listPage[*{{.Type}}](ctx, c, "{{.Short}}", query)
+}
+
+// List{{.Name}}sBySelector lists the {{.Name}} objects matching a label selector.
+func (c *Client) List{{.Name}}sBySelector(ctx context.Context, selector string) ([]*{{.Type}}, error) {
+	items, _, err := listPage[*{{.Type}}](ctx, c, "{{.Short}}", url.Values{"selector": {selector}})
+	return items, err
+}
+{{end}}`))
+
+type kindInfo struct {
+	Name  string
+	Short string
+	Type  string
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("clientgen: ")
+	flag.Parse()
+
+	imports := map[string]bool{"go-assignment/objectdb": true}
+	var kinds []kindInfo
+	for _, kind := range objectdb.Kinds() {
+		object, err := objectdb.NewObject(kind)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		t := reflect.TypeOf(object).Elem()
+		imports[t.PkgPath()] = true
+		kinds = append(kinds, kindInfo{
+			Name:  t.Name(),
+			Short: strings.ToLower(t.Name()),
+			Type:  path.Base(t.PkgPath()) + "." + t.Name(),
+		})
+	}
+
+	data := struct {
+		Imports []string
+		Kinds   []kindInfo
+	}{Kinds: kinds}
+	for imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Strings(data.Imports)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Clean(*output), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
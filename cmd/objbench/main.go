@@ -0,0 +1,315 @@
+// Command objbench measures Store, Get and List throughput and latency for
+// every combination of backend, codec and fetch batch size asked for:
+//
+//	objbench -redis redis://localhost:6379/15 -codecs json,msgpack -batch-sizes 10,100,1000
+//	objbench -server http://localhost:8080 -backends http
+//
+// It writes and then deletes persons with IDs starting with "objbench-",
+// and List reads every person, so point it at a scratch database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectdb/httpclient"
+)
+
+var (
+	redisURL    = flag.String("redis", "redis://localhost:6379/15", "Redis URL for the redis and cached backends")
+	serverURL   = flag.String("server", "http://localhost:8080", "objectserver URL for the http backend")
+	backends    = flag.String("backends", "redis,cached", "comma-separated backends: redis, cached, http")
+	codecNames  = flag.String("codecs", "json,msgpack,cbor,protobuf", "comma-separated codecs for the redis and cached backends")
+	batchSizes  = flag.String("batch-sizes", "100", "comma-separated fetch batch sizes for the redis and cached backends")
+	objects     = flag.Int("n", 1000, "objects stored and read per run")
+	lists       = flag.Int("lists", 10, "full List calls per run")
+	concurrency = flag.Int("concurrency", 8, "concurrent Store and Get calls")
+)
+
+var codecs = map[string]objectdb.Option{
+	"json":     objectdb.WithCodec(objectdb.JSONCodec{}),
+	"msgpack":  objectdb.WithMsgpack(),
+	"cbor":     objectdb.WithCBOR(),
+	"protobuf": objectdb.WithProtobuf(),
+}
+
+const kind = "*main.Person"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("objbench: ")
+	flag.Parse()
+
+	var sizes []int
+	for _, s := range splitList(*batchSizes) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid batch size '%s'", s)
+		}
+		sizes = append(sizes, n)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tCODEC\tBATCH\tOP\tOPS/S\tP50\tP99")
+	for _, name := range splitList(*backends) {
+		for _, run := range runsFor(name, sizes) {
+			results, err := run.bench(context.Background())
+			if err != nil {
+				log.Fatalf("%s %s %s: %v", run.backend, run.codec, run.batch, err)
+			}
+			for _, r := range results {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0f\t%s\t%s\n",
+					run.backend, run.codec, run.batch, r.op, r.throughput(), r.percentile(50), r.percentile(99))
+			}
+		}
+	}
+	tw.Flush()
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// run is one combination to measure. open connects a fresh target.
+type run struct {
+	backend, codec, batch string
+	open                  func() (target, error)
+}
+
+func runsFor(backend string, sizes []int) []run {
+	if backend == "http" {
+		return []run{{backend: backend, codec: "-", batch: "-", open: func() (target, error) {
+			return httpTarget{httpclient.New(*serverURL, nil)}, nil
+		}}}
+	}
+	if backend != "redis" && backend != "cached" {
+		log.Fatalf("unknown backend '%s'", backend)
+	}
+
+	var runs []run
+	for _, codec := range splitList(*codecNames) {
+		opt, ok := codecs[codec]
+		if !ok {
+			log.Fatalf("unknown codec '%s'", codec)
+		}
+		for _, size := range sizes {
+			size := size
+			runs = append(runs, run{backend: backend, codec: codec, batch: strconv.Itoa(size), open: func() (target, error) {
+				db, err := objectdb.NewRedisObjectDBFromURL(*redisURL, opt, objectdb.WithFetchBatchSize(size))
+				if err != nil {
+					return nil, err
+				}
+				if backend == "cached" {
+					return dbTarget{objectdb.NewCachedObjectDB(db)}, nil
+				}
+				return dbTarget{db}, nil
+			}})
+		}
+	}
+
+	return runs
+}
+
+func (r run) bench(ctx context.Context) ([]result, error) {
+	t, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	ids := make([]string, *objects)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("objbench-%d", i)
+	}
+	defer func() {
+		for _, id := range ids {
+			t.Delete(ctx, id)
+		}
+	}()
+
+	store, err := parallel(ctx, "store", ids, func(ctx context.Context, id string) error {
+		return t.Store(ctx, &objectdb.Person{
+			ObjectMeta: objectdb.ObjectMeta{ID: id, Name: "Bench " + id, Labels: map[string]string{"bench": "objbench"}},
+			LastName:   "Mark",
+			BirthDate:  time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	get, err := parallel(ctx, "get", ids, func(ctx context.Context, id string) error {
+		_, err := t.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := result{op: "list"}
+	start := time.Now()
+	for i := 0; i < *lists; i++ {
+		began := time.Now()
+		if _, err := t.List(ctx); err != nil {
+			return nil, err
+		}
+		list.latencies = append(list.latencies, time.Since(began))
+	}
+	list.elapsed = time.Since(start)
+
+	return []result{store, get, list}, nil
+}
+
+// parallel calls fn for every id from -concurrency goroutines and records
+// each call's latency.
+func parallel(ctx context.Context, op string, ids []string, fn func(ctx context.Context, id string) error) (result, error) {
+	work := make(chan string)
+	latencies := make(chan time.Duration, len(ids))
+	errs := make(chan error, *concurrency)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				began := time.Now()
+				if err := fn(ctx, id); err != nil {
+					errs <- fmt.Errorf("%s '%s': %w", op, id, err)
+					return
+				}
+				latencies <- time.Since(began)
+			}
+		}()
+	}
+	go func() {
+		defer close(work)
+		for _, id := range ids {
+			work <- id
+		}
+	}()
+	wg.Wait()
+
+	r := result{op: op, elapsed: time.Since(start)}
+	close(latencies)
+	for d := range latencies {
+		r.latencies = append(r.latencies, d)
+	}
+	select {
+	case err := <-errs:
+		// Drain the feeder so it does not block forever.
+		for range work {
+		}
+		return r, err
+	default:
+	}
+
+	return r, nil
+}
+
+type result struct {
+	op        string
+	elapsed   time.Duration
+	latencies []time.Duration
+}
+
+func (r result) throughput() float64 {
+	return float64(len(r.latencies)) / r.elapsed.Seconds()
+}
+
+func (r result) percentile(p int) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[(len(sorted)-1)*p/100].Round(time.Microsecond)
+}
+
+// target is the part of a store the benchmark exercises.
+type target interface {
+	Store(ctx context.Context, person *objectdb.Person) error
+	Get(ctx context.Context, id string) (objectdb.Object, error)
+	List(ctx context.Context) ([]objectdb.Object, error)
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+type dbTarget struct {
+	db objectdb.ObjectDB
+}
+
+func (t dbTarget) Store(ctx context.Context, person *objectdb.Person) error {
+	return t.db.Store(ctx, person)
+}
+
+func (t dbTarget) Get(ctx context.Context, id string) (objectdb.Object, error) {
+	return t.db.GetObjectByID(ctx, id)
+}
+
+func (t dbTarget) List(ctx context.Context) ([]objectdb.Object, error) {
+	return t.db.ListObjects(ctx, kind)
+}
+
+func (t dbTarget) Delete(ctx context.Context, id string) error {
+	return t.db.DeleteObject(ctx, id)
+}
+
+func (t dbTarget) Close() error {
+	return t.db.Close()
+}
+
+type httpTarget struct {
+	client *httpclient.Client
+}
+
+func (t httpTarget) Store(ctx context.Context, person *objectdb.Person) error {
+	return t.client.PutPerson(ctx, person)
+}
+
+func (t httpTarget) Get(ctx context.Context, id string) (objectdb.Object, error) {
+	return t.client.GetPerson(ctx, id)
+}
+
+func (t httpTarget) List(ctx context.Context) ([]objectdb.Object, error) {
+	var all []objectdb.Object
+	cursor := ""
+	for {
+		page, next, err := t.client.ListPage(ctx, "person", cursor)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+func (t httpTarget) Delete(ctx context.Context, id string) error {
+	return t.client.DeletePerson(ctx, id, 0)
+}
+
+func (t httpTarget) Close() error {
+	return nil
+}
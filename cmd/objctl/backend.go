@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectdb/httpclient"
+)
+
+// backend is what the commands need from a store, served either by Redis
+// directly or by an objectserver.
+type backend interface {
+	Get(ctx context.Context, kind, id string) (objectdb.Object, error)
+	List(ctx context.Context, kind, selector string) ([]objectdb.Object, error)
+	Query(ctx context.Context, kind string, filters []objectdb.Filter) ([]objectdb.Object, error)
+	// Create fails if the object already exists; Apply creates or replaces.
+	Create(ctx context.Context, object objectdb.Object) error
+	Apply(ctx context.Context, object objectdb.Object) error
+	Delete(ctx context.Context, kind, id string, policy objectdb.DeletePolicy) error
+	Close() error
+}
+
+var errAlreadyExists = errors.New("already exists")
+
+func openBackend(flags *globalFlags) (backend, error) {
+	if flags.serverURL != "" {
+		return &httpBackend{client: httpclient.New(flags.serverURL, nil)}, nil
+	}
+
+	db, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisBackend{db: db}, nil
+}
+
+type redisBackend struct {
+	db *objectdb.RedisObjectDB
+}
+
+func (b *redisBackend) Get(ctx context.Context, kind, id string) (objectdb.Object, error) {
+	return b.db.GetObject(ctx, kind, id)
+}
+
+func (b *redisBackend) List(ctx context.Context, kind, selector string) ([]objectdb.Object, error) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if selector != "" {
+		sel, err := objectdb.ParseSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		return b.db.ListObjectsByLabels(ctx, object.GetKind(), sel)
+	}
+
+	var objects []objectdb.Object
+	it := b.db.ListObjectsStream(ctx, object.GetKind())
+	for it.Next(ctx) {
+		objects = append(objects, it.Object())
+	}
+
+	return objects, it.Err()
+}
+
+func (b *redisBackend) Query(ctx context.Context, kind string, filters []objectdb.Filter) ([]objectdb.Object, error) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.db.Query(ctx, object.GetKind(), filters...)
+}
+
+func (b *redisBackend) Create(ctx context.Context, object objectdb.Object) error {
+	_, err := b.db.GetObject(ctx, object.GetKind(), object.GetID())
+	if err == nil {
+		return fmt.Errorf("%s '%s' %w", object.GetKind(), object.GetID(), errAlreadyExists)
+	}
+	if !errors.Is(err, objectdb.ErrNotFound) {
+		return err
+	}
+
+	return b.db.Store(ctx, object)
+}
+
+func (b *redisBackend) Apply(ctx context.Context, object objectdb.Object) error {
+	return b.db.Store(ctx, object)
+}
+
+func (b *redisBackend) Delete(ctx context.Context, kind, id string, policy objectdb.DeletePolicy) error {
+	// DeleteObject finds objects by ID alone; reading first makes sure the
+	// ID belongs to kind.
+	if _, err := b.db.GetObject(ctx, kind, id); err != nil {
+		return err
+	}
+
+	var opts []objectdb.DeleteOption
+	if policy != 0 {
+		opts = append(opts, policy)
+	}
+
+	return b.db.DeleteObject(ctx, id, opts...)
+}
+
+func (b *redisBackend) Close() error {
+	return b.db.Close()
+}
+
+type httpBackend struct {
+	client *httpclient.Client
+}
+
+func (b *httpBackend) Get(ctx context.Context, kind, id string) (objectdb.Object, error) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return object, b.client.Get(ctx, kind, id, object)
+}
+
+func (b *httpBackend) List(ctx context.Context, kind, selector string) ([]objectdb.Object, error) {
+	if selector != "" {
+		return b.client.ListBySelector(ctx, kind, selector)
+	}
+
+	var objects []objectdb.Object
+	cursor := ""
+	for {
+		page, next, err := b.client.ListPage(ctx, kind, cursor)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, page...)
+		if next == "" {
+			return objects, nil
+		}
+		cursor = next
+	}
+}
+
+// Query filters on this side, as the server has no query endpoint.
+func (b *httpBackend) Query(ctx context.Context, kind string, filters []objectdb.Filter) ([]objectdb.Object, error) {
+	objects, err := b.List(ctx, kind, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []objectdb.Object
+	for _, object := range objects {
+		ok := true
+		for _, f := range filters {
+			ok = ok && f.Matches(object)
+		}
+		if ok {
+			matched = append(matched, object)
+		}
+	}
+
+	return matched, nil
+}
+
+func (b *httpBackend) Create(ctx context.Context, object objectdb.Object) error {
+	return b.client.Create(ctx, object.GetKind(), object)
+}
+
+func (b *httpBackend) Apply(ctx context.Context, object objectdb.Object) error {
+	return b.client.Put(ctx, object.GetKind(), object)
+}
+
+func (b *httpBackend) Delete(ctx context.Context, kind, id string, policy objectdb.DeletePolicy) error {
+	return b.client.Delete(ctx, kind, id, policy)
+}
+
+func (b *httpBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go-assignment/objectdb"
+)
+
+// withBackend opens the backend for the duration of one command.
+func withBackend(flags *globalFlags, fn func(ctx context.Context, b backend) error) error {
+	if err := validateOutput(flags); err != nil {
+		return err
+	}
+
+	b, err := openBackend(flags)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	return fn(context.Background(), b)
+}
+
+func newGetCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get KIND ID",
+		Short: "Print one object",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withBackend(flags, func(ctx context.Context, b backend) error {
+				object, err := b.Get(ctx, args[0], args[1])
+				if err != nil {
+					return err
+				}
+				return printObjects(cmd.OutOrStdout(), flags.output, []objectdb.Object{object}, true)
+			})
+		},
+	}
+}
+
+func newListCommand(flags *globalFlags) *cobra.Command {
+	var selector string
+
+	cmd := &cobra.Command{
+		Use:   "list KIND",
+		Short: "Print all objects of a kind",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withBackend(flags, func(ctx context.Context, b backend) error {
+				objects, err := b.List(ctx, args[0], selector)
+				if err != nil {
+					return err
+				}
+				sortObjects(objects)
+				return printObjects(cmd.OutOrStdout(), flags.output, objects, false)
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector, e.g. env=prod,tier!=db")
+
+	return cmd
+}
+
+func newCreateCommand(flags *globalFlags) *cobra.Command {
+	return newWriteCommand(flags, "create", "created", "Create the objects in a file, failing on existing IDs", backend.Create)
+}
+
+func newApplyCommand(flags *globalFlags) *cobra.Command {
+	return newWriteCommand(flags, "apply", "applied", "Create or replace the objects in a file", backend.Apply)
+}
+
+// newWriteCommand builds create and apply, which differ only in how they
+// write each object read from -f.
+func newWriteCommand(flags *globalFlags, name, done, short string, write func(backend, context.Context, objectdb.Object) error) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   name + " -f FILE",
+		Short: short,
+		Long: short + `.
+
+FILE holds one JSON object or an array of them, each with a "kind" field
+naming its kind and the object's own fields, e.g.
+
+  {"kind": "person", "id": "123", "name": "John", "last_name": "Doe"}
+
+Use -f - to read standard input.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			objects, err := readObjects(cmd.InOrStdin(), file)
+			if err != nil {
+				return err
+			}
+
+			return withBackend(flags, func(ctx context.Context, b backend) error {
+				for _, object := range objects {
+					if err := write(b, ctx, object); err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s/%s %s\n", kindName(object), object.GetID(), done)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "file to read objects from")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+func newDeleteCommand(flags *globalFlags) *cobra.Command {
+	var policy string
+
+	cmd := &cobra.Command{
+		Use:   "delete KIND ID",
+		Short: "Delete one object",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var p objectdb.DeletePolicy
+			switch policy {
+			case "":
+			case "cascade":
+				p = objectdb.DeleteCascade
+			case "restrict":
+				p = objectdb.DeleteRestrict
+			case "orphan":
+				p = objectdb.DeleteOrphan
+			default:
+				return fmt.Errorf("unknown delete policy '%s'", policy)
+			}
+
+			return withBackend(flags, func(ctx context.Context, b backend) error {
+				if err := b.Delete(ctx, args[0], args[1], p); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s/%s deleted\n", args[0], args[1])
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&policy, "policy", "", "what happens to referencing objects: cascade, restrict or orphan")
+
+	return cmd
+}
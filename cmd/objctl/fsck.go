@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go-assignment/objectdb"
+)
+
+func newFsckCommand(flags *globalFlags) *cobra.Command {
+	var repair bool
+
+	cmd := &cobra.Command{
+		Use:   "fsck [--repair]",
+		Short: "Check stored objects and indexes for inconsistencies",
+		Long: `Check stored objects and indexes for inconsistencies.
+
+Reports values that do not decode or whose ID disagrees with their key,
+broken references, and missing or stale index entries, one per line. With
+--repair, index entries and IDs are fixed; the rest is left to you. Exits
+non-zero while problems remain.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.serverURL != "" {
+				return errors.New("fsck talks to Redis directly and cannot be used with --server")
+			}
+
+			db, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			report, err := db.VerifyConsistency(context.Background(), objectdb.VerifyOptions{Repair: repair})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, problem := range report.Problems {
+				line := fmt.Sprintf("%s %s", problem.Type, problem.Key)
+				if problem.Index != "" {
+					line += " in " + problem.Index
+				}
+				if problem.Detail != "" {
+					line += ": " + problem.Detail
+				}
+				if problem.Repaired {
+					line += " (repaired)"
+				}
+				fmt.Fprintln(out, line)
+			}
+			fmt.Fprintf(out, "%d objects, %d indexes, %d problems\n", report.Objects, report.Indexes, len(report.Problems))
+
+			if n := report.Unrepaired(); n > 0 {
+				return fmt.Errorf("%d problems remain", n)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&repair, "repair", false, "fix index entries and mismatched IDs")
+
+	return cmd
+}
@@ -0,0 +1,78 @@
+// Command objctl reads and writes objects, either directly in Redis or
+// through an objectserver:
+//
+//	objctl list person -l env=prod
+//	objctl get animal 456 -o yaml
+//	objctl apply -f people.json
+//	objctl --server http://localhost:8080 delete person 123 --policy cascade
+//	objctl reconcile --to redis://new-host:6379/0
+//	objctl reindex person animal
+//	objctl fsck --repair
+//	objctl shell
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type globalFlags struct {
+	redisURL  string
+	serverURL string
+	output    string
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	flags := &globalFlags{}
+
+	root := &cobra.Command{
+		Use:          "objctl",
+		Short:        "Inspect and edit objects in the object store",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().StringVar(&flags.redisURL, "redis", envOr("OBJCTL_REDIS", "redis://localhost:6379/0"), "Redis URL to connect to directly ($OBJCTL_REDIS)")
+	root.PersistentFlags().StringVar(&flags.serverURL, "server", os.Getenv("OBJCTL_SERVER"), "objectserver URL; takes precedence over --redis ($OBJCTL_SERVER)")
+	root.PersistentFlags().StringVarP(&flags.output, "output", "o", "table", "output format: table, json or yaml")
+
+	root.AddCommand(
+		newGetCommand(flags),
+		newListCommand(flags),
+		newCreateCommand(flags),
+		newApplyCommand(flags),
+		newDeleteCommand(flags),
+		newReconcileCommand(flags),
+		newReindexCommand(flags),
+		newFsckCommand(flags),
+		newStatsCommand(flags),
+		newMemoryCommand(flags),
+		newShellCommand(flags),
+	)
+
+	return root
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+// validateOutput fails early on an unknown -o, before anything is read.
+func validateOutput(flags *globalFlags) error {
+	switch flags.output {
+	case "table", "json", "yaml":
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format '%s'", flags.output)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"go-assignment/objectdb"
+)
+
+func newMemoryCommand(flags *globalFlags) *cobra.Command {
+	var opts objectdb.MemoryOptions
+
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Estimate the Redis memory each kind takes",
+		Long: `Estimate the Redis memory each kind takes.
+
+A random sample of each kind's keys is measured with MEMORY USAGE and the
+result scaled to the whole kind. The largest sampled objects are listed
+below the totals.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(flags); err != nil {
+				return err
+			}
+			if flags.serverURL != "" {
+				return errors.New("memory talks to Redis directly and cannot be used with --server")
+			}
+
+			db, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			usage, err := db.MemoryUsage(context.Background(), opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch flags.output {
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(usage)
+			case "yaml":
+				enc := yaml.NewEncoder(out)
+				enc.SetIndent(2)
+				defer enc.Close()
+				return enc.Encode(usage)
+			}
+
+			tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "KIND\tOBJECTS\tSAMPLED\tESTIMATED BYTES")
+			for _, m := range usage {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", m.Kind, m.Objects, m.Sampled, m.EstimatedBytes)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out)
+			tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "KEY\tBYTES")
+			for _, m := range usage {
+				for _, key := range m.Largest {
+					fmt.Fprintf(tw, "%s\t%d\n", key.Key, key.Bytes)
+				}
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&opts.Samples, "samples", 0, "keys to measure per kind (default 100)")
+	cmd.Flags().IntVar(&opts.Top, "top", 0, "largest objects to list per kind (default 10)")
+
+	return cmd
+}
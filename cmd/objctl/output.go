@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go-assignment/objectdb"
+)
+
+// kindName is the short kind name objctl shows and accepts, e.g. "person".
+func kindName(object objectdb.Object) string {
+	kind := object.GetKind()
+	if i := strings.LastIndex(kind, "."); i >= 0 {
+		kind = kind[i+1:]
+	}
+
+	return strings.ToLower(strings.TrimPrefix(kind, "*"))
+}
+
+func sortObjects(objects []objectdb.Object) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].GetID() < objects[j].GetID() })
+}
+
+// document is an object's JSON form with its kind added, the shape apply
+// reads back.
+func document(object objectdb.Object) (map[string]interface{}, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc["kind"] = kindName(object)
+
+	return doc, nil
+}
+
+// printObjects writes objects in format. single prints a lone object
+// rather than a list for json and yaml.
+func printObjects(w io.Writer, format string, objects []objectdb.Object, single bool) error {
+	if format == "table" {
+		return printTable(w, objects)
+	}
+
+	docs := make([]map[string]interface{}, 0, len(objects))
+	for _, object := range objects {
+		doc, err := document(object)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	var v interface{} = docs
+	if single && len(docs) == 1 {
+		v = docs[0]
+	}
+
+	if format == "yaml" {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printTable(w io.Writer, objects []objectdb.Object) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tID\tNAME\tVERSION\tLABELS\tUPDATED")
+	for _, object := range objects {
+		meta := object.GetObjectMeta()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			kindName(object), meta.ID, meta.Name, meta.Version, formatLabels(meta.Labels), age(meta.UpdatedAt))
+	}
+
+	return tw.Flush()
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "<none>"
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// age renders how long ago t was, kubectl style.
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// readObjects decodes the objects in file, or standard input for "-".
+func readObjects(stdin io.Reader, file string) ([]objectdb.Object, error) {
+	var r io.Reader = stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &raw)
+	} else {
+		raw = []json.RawMessage{trimmed}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	objects := make([]objectdb.Object, 0, len(raw))
+	for i, data := range raw {
+		var header struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, fmt.Errorf("%s: object %d: %w", file, i, err)
+		}
+		if header.Kind == "" {
+			return nil, fmt.Errorf("%s: object %d has no kind", file, i)
+		}
+
+		object, err := objectdb.NewObject(header.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("%s: object %d: %w", file, i, err)
+		}
+		if err := json.Unmarshal(data, object); err != nil {
+			return nil, fmt.Errorf("%s: object %d: %w", file, i, err)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
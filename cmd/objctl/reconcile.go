@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go-assignment/objectdb"
+)
+
+func newReconcileCommand(flags *globalFlags) *cobra.Command {
+	var to string
+
+	cmd := &cobra.Command{
+		Use:   "reconcile --to URL [KIND...]",
+		Short: "Make a secondary store match the one given by --redis",
+		Long: `Make a secondary store match the one given by --redis.
+
+Objects missing or different on the secondary are written again and objects
+only the secondary has are deleted. Run it after starting dual writes to
+copy existing data, or to repair writes that failed to replicate. Without
+KIND arguments every kind is reconciled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.serverURL != "" {
+				return errors.New("reconcile talks to Redis directly and cannot be used with --server")
+			}
+
+			primary, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+			if err != nil {
+				return err
+			}
+			secondary, err := objectdb.NewRedisObjectDBFromURL(to)
+			if err != nil {
+				primary.Close()
+				return err
+			}
+
+			db := objectdb.NewReplicatingObjectDB(primary, secondary)
+			defer db.Close()
+
+			result, err := db.Reconcile(context.Background(), args...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%d checked, %d stored, %d deleted\n", result.Checked, result.Stored, result.Deleted)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "", "Redis URL of the secondary store")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go-assignment/objectdb"
+)
+
+func newReindexCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex KIND...",
+		Short: "Rebuild the indexes of the given kinds from their objects",
+		Long: `Rebuild the indexes of the given kinds from their objects.
+
+Missing index entries are added and stale ones removed. Run it after index
+corruption, or to index existing objects after upgrading to a version with
+new indexes.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.serverURL != "" {
+				return errors.New("reindex talks to Redis directly and cannot be used with --server")
+			}
+
+			db, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			for _, kind := range args {
+				report, err := db.RebuildIndexes(context.Background(), kind)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %d objects, %d entries added, %d removed\n",
+					kind, report.Objects, report.Added, report.Removed)
+			}
+			return nil
+		},
+	}
+}
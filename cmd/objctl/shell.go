@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+
+	"go-assignment/objectdb"
+)
+
+const shellHelp = `Commands:
+  kinds                          list the registered kinds
+  get KIND ID                    print one object
+  list KIND [SELECTOR]           print the objects of a kind, optionally by labels
+  query KIND FIELD=VALUE...      print the objects matching every filter; <, <=, > and >= work too
+  delete KIND ID [POLICY]        delete an object; POLICY is cascade, restrict or orphan
+  output table|json|yaml         switch the output format
+  help                           show this help
+  exit                           leave the shell
+Tab completes commands, kinds and IDs.`
+
+// idCacheTTL bounds how stale ID completions can get; listing a kind on
+// every tab would be slow on large kinds.
+const idCacheTTL = 10 * time.Second
+
+func newShellCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell",
+		Short: "Explore objects interactively",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withBackend(flags, func(ctx context.Context, b backend) error {
+				s := &shell{ctx: ctx, backend: b, output: flags.output, ids: map[string]idCacheEntry{}}
+				return s.run()
+			})
+		},
+	}
+}
+
+type shell struct {
+	ctx     context.Context
+	backend backend
+	output  string
+
+	mu  sync.Mutex
+	ids map[string]idCacheEntry
+}
+
+type idCacheEntry struct {
+	ids     []string
+	fetched time.Time
+}
+
+func (s *shell) run() error {
+	history := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		history = filepath.Join(home, ".objctl_history")
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "objctl> ",
+		HistoryFile:     history,
+		AutoComplete:    s.completer(),
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	fmt.Fprintln(rl.Stdout(), `Type "help" for commands.`)
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+
+		if err := s.exec(rl.Stdout(), args); err != nil {
+			fmt.Fprintln(rl.Stderr(), "error:", err)
+		}
+	}
+}
+
+func (s *shell) exec(w io.Writer, args []string) error {
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "help":
+		fmt.Fprintln(w, shellHelp)
+
+	case "kinds":
+		for _, kind := range shortKinds() {
+			fmt.Fprintln(w, kind)
+		}
+
+	case "output":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: output table|json|yaml")
+		}
+		if err := validateOutput(&globalFlags{output: rest[0]}); err != nil {
+			return err
+		}
+		s.output = rest[0]
+
+	case "get":
+		if len(rest) != 2 {
+			return fmt.Errorf("usage: get KIND ID")
+		}
+		object, err := s.backend.Get(s.ctx, rest[0], rest[1])
+		if err != nil {
+			return err
+		}
+		return printObjects(w, s.output, []objectdb.Object{object}, true)
+
+	case "list":
+		if len(rest) == 0 {
+			return fmt.Errorf("usage: list KIND [SELECTOR]")
+		}
+		objects, err := s.backend.List(s.ctx, rest[0], strings.Join(rest[1:], " "))
+		if err != nil {
+			return err
+		}
+		sortObjects(objects)
+		return printObjects(w, s.output, objects, false)
+
+	case "query":
+		if len(rest) < 2 {
+			return fmt.Errorf("usage: query KIND FIELD=VALUE...")
+		}
+		var filters []objectdb.Filter
+		for _, arg := range rest[1:] {
+			f, err := objectdb.ParseFilter(arg)
+			if err != nil {
+				return err
+			}
+			filters = append(filters, f)
+		}
+		objects, err := s.backend.Query(s.ctx, rest[0], filters)
+		if err != nil {
+			return err
+		}
+		sortObjects(objects)
+		return printObjects(w, s.output, objects, false)
+
+	case "delete":
+		if len(rest) != 2 && len(rest) != 3 {
+			return fmt.Errorf("usage: delete KIND ID [POLICY]")
+		}
+		policy := map[string]objectdb.DeletePolicy{
+			"cascade":  objectdb.DeleteCascade,
+			"restrict": objectdb.DeleteRestrict,
+			"orphan":   objectdb.DeleteOrphan,
+		}
+		var p objectdb.DeletePolicy
+		if len(rest) == 3 {
+			var ok bool
+			if p, ok = policy[rest[2]]; !ok {
+				return fmt.Errorf("unknown delete policy '%s'", rest[2])
+			}
+		}
+		if err := s.backend.Delete(s.ctx, rest[0], rest[1], p); err != nil {
+			return err
+		}
+		s.forgetIDs(rest[0])
+		fmt.Fprintf(w, "%s/%s deleted\n", rest[0], rest[1])
+
+	default:
+		return fmt.Errorf("unknown command '%s'; try help", cmd)
+	}
+
+	return nil
+}
+
+func (s *shell) completer() readline.AutoCompleter {
+	kinds := readline.PcItemDynamic(func(string) []string { return shortKinds() })
+	kindsAndIDs := readline.PcItemDynamic(func(string) []string { return shortKinds() },
+		readline.PcItemDynamic(s.completeIDs))
+
+	return readline.NewPrefixCompleter(
+		readline.PcItem("kinds"),
+		readline.PcItem("get", kindsAndIDs),
+		readline.PcItem("list", kinds),
+		readline.PcItem("query", kinds),
+		readline.PcItem("delete", readline.PcItemDynamic(func(string) []string { return shortKinds() },
+			readline.PcItemDynamic(s.completeIDs,
+				readline.PcItem("cascade"), readline.PcItem("restrict"), readline.PcItem("orphan")))),
+		readline.PcItem("output", readline.PcItem("table"), readline.PcItem("json"), readline.PcItem("yaml")),
+		readline.PcItem("help"),
+		readline.PcItem("exit"),
+	)
+}
+
+// completeIDs offers the IDs of the kind named by the line's second word.
+func (s *shell) completeIDs(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil
+	}
+	kind := fields[1]
+
+	s.mu.Lock()
+	entry, ok := s.ids[kind]
+	s.mu.Unlock()
+	if ok && time.Since(entry.fetched) < idCacheTTL {
+		return entry.ids
+	}
+
+	objects, err := s.backend.List(s.ctx, kind, "")
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(objects))
+	for _, object := range objects {
+		ids = append(ids, object.GetID())
+	}
+	sort.Strings(ids)
+
+	s.mu.Lock()
+	s.ids[kind] = idCacheEntry{ids: ids, fetched: time.Now()}
+	s.mu.Unlock()
+
+	return ids
+}
+
+func (s *shell) forgetIDs(kind string) {
+	s.mu.Lock()
+	delete(s.ids, kind)
+	s.mu.Unlock()
+}
+
+func shortKinds() []string {
+	var names []string
+	for _, kind := range objectdb.ListKinds() {
+		names = append(names, kind.ShortName)
+	}
+
+	return names
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"go-assignment/objectdb"
+)
+
+func newStatsCommand(flags *globalFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show object counts and sizes per kind",
+		Long: `Show object counts and sizes per kind.
+
+Every stored object is read, so on large stores this takes a while.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(flags); err != nil {
+				return err
+			}
+			if flags.serverURL != "" {
+				return errors.New("stats talks to Redis directly and cannot be used with --server")
+			}
+
+			db, err := objectdb.NewRedisObjectDBFromURL(flags.redisURL)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			stats, err := db.Stats(context.Background())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch flags.output {
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(stats)
+			case "yaml":
+				enc := yaml.NewEncoder(out)
+				enc.SetIndent(2)
+				defer enc.Close()
+				return enc.Encode(stats)
+			}
+
+			tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "KIND\tOBJECTS\tBYTES\tAVERAGE\tLAST WRITE")
+			for _, s := range stats {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", s.Kind, s.Objects, s.Bytes, s.AverageSize, age(s.LastWrite))
+			}
+			return tw.Flush()
+		},
+	}
+}
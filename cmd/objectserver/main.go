@@ -0,0 +1,153 @@
+// Command objectserver serves an object store over HTTP and gRPC, with a
+// GraphQL endpoint at /graphql.
+//
+//	objectserver -addr :8080 -grpc-addr :9090 -redis redis://localhost:6379/0
+//
+// An empty -grpc-addr disables the gRPC server. -tls-cert and -tls-key serve
+// both over TLS, and -client-ca verifies client certificates. With -tokens
+// or -policy every request must authenticate, by bearer token or client
+// certificate, and only gets what its token scopes and the policy grant;
+// token principals are named "token:" followed by the token's name.
+// GraphQL then needs full access. The admin UI, enabled by -admin-password,
+// then acts as the principal "admin:" followed by -admin-user, which the
+// policy must grant what the UI should be able to do.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectdb/graphqlapi"
+	"go-assignment/objectdb/grpcapi"
+	"go-assignment/objectdb/httpserver"
+	"go-assignment/objectpb"
+)
+
+var (
+	addr     = flag.String("addr", ":8080", "HTTP listen address")
+	grpcAddr = flag.String("grpc-addr", ":9090", "gRPC listen address")
+	redisURL = flag.String("redis", "redis://localhost:6379/0", "Redis URL")
+	timeout  = flag.Duration("timeout", 10*time.Second, "deadline for each store call; 0 waits indefinitely")
+
+	pageTokenKey = flag.String("page-token-key", os.Getenv("OBJECTSERVER_PAGE_TOKEN_KEY"), "key list cursors are signed with, shared by every replica; random if empty ($OBJECTSERVER_PAGE_TOKEN_KEY)")
+
+	adminUser     = flag.String("admin-user", "admin", "admin UI user name")
+	adminPassword = flag.String("admin-password", os.Getenv("OBJECTSERVER_ADMIN_PASSWORD"), "admin UI password; the UI at /admin/ is off without one ($OBJECTSERVER_ADMIN_PASSWORD)")
+
+	tokensFile = flag.String("tokens", "", "JSON file of API tokens and their scopes")
+	policyFile = flag.String("policy", "", "JSON access policy, e.g. for client certificate principals")
+	tlsCert    = flag.String("tls-cert", "", "TLS certificate file")
+	tlsKey     = flag.String("tls-key", "", "TLS private key file")
+	clientCA   = flag.String("client-ca", "", "CA file to verify client certificates with")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("objectserver: ")
+	flag.Parse()
+
+	dbOpts := []objectdb.Option{objectdb.WithChangeFeed(0), objectdb.WithTimeout(*timeout)}
+	if *pageTokenKey != "" {
+		dbOpts = append(dbOpts, objectdb.WithPageTokens([]byte(*pageTokenKey), 0))
+	}
+	db, err := objectdb.NewRedisObjectDBFromURL(*redisURL, dbOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	access, err := loadAccess()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsConfig, err := loadTLS()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if access.policy != nil && tlsConfig == nil {
+		log.Print("warning: tokens are accepted over plain text; use -tls-cert and -tls-key")
+	}
+
+	gql, err := graphqlapi.NewHandler(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", access.requireFullAccess(gql))
+	var opts []httpserver.Option
+	if *adminPassword != "" {
+		opts = append(opts, httpserver.WithAdminUI(*adminUser, *adminPassword))
+	}
+	var grpcOpts []grpcapi.ServerOption
+	if access.policy != nil {
+		opts = append(opts, httpserver.WithAccessPolicy(access.policy, access.http))
+		grpcOpts = append(grpcOpts, grpcapi.WithAccessPolicy(access.policy, access.grpc))
+	}
+	mux.Handle("/", httpserver.New(db, opts...))
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         tlsConfig,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var serverOpts []grpc.ServerOption
+		if tlsConfig != nil {
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer := grpc.NewServer(serverOpts...)
+		objectpb.RegisterObjectServiceServer(grpcServer, grpcapi.NewServer(db, grpcOpts...))
+		go func() {
+			<-ctx.Done()
+			// Watch streams only end when their client goes away, so a
+			// graceful stop could wait forever.
+			grpcServer.Stop()
+		}()
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Print(err)
+			}
+		}()
+		log.Printf("serving gRPC on %s", *grpcAddr)
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("listening on %s", *addr)
+	if tlsConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Print(err)
+	}
+}
@@ -2,197 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"reflect"
-	"time"
 
 	"github.com/go-redis/redis/v8"
-)
-
-type Object interface {
-	GetKind() string
-	GetID() string
-	GetName() string
-	SetID(string)
-	SetName(string)
-}
-
-type Person struct {
-	Name      string    `json:"name"`
-	ID        string    `json:"id"`
-	LastName  string    `json:"last_name"`
-	Birthday  string    `json:"birthday"`
-	BirthDate time.Time `json:"birth_date"`
-}
-
-func (p *Person) GetKind() string {
-	return reflect.TypeOf(p).String()
-}
-
-func (p *Person) GetID() string {
-	return p.ID
-}
-
-func (p *Person) GetName() string {
-	return p.Name
-}
-
-func (p *Person) SetID(s string) {
-	p.ID = s
-}
-
-func (p *Person) SetName(s string) {
-	p.Name = s
-}
-
-type Animal struct {
-	Name    string `json:"name"`
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	OwnerID string `json:"owner_id"`
-}
-
-func (a *Animal) GetKind() string {
-	return reflect.TypeOf(a).String()
-}
-
-func (a *Animal) GetID() string {
-	return a.ID
-}
-
-func (a *Animal) GetName() string {
-	return a.Name
-}
-
-func (a *Animal) SetID(s string) {
-	a.ID = s
-}
-
-func (a *Animal) SetName(s string) {
-	a.Name = s
-}
-
-type ObjectDB interface {
-	Store(ctx context.Context, object Object) error
-	GetObjectByID(ctx context.Context, id string) (Object, error)
-	GetObjectByName(ctx context.Context, name string) (Object, error)
-	ListObjects(ctx context.Context, kind string) ([]Object, error)
-	DeleteObject(ctx context.Context, id string) error
-}
-
-type RedisObjectDB struct {
-	redisClient *redis.Client
-}
-
-func NewRedisObjectDB(client *redis.Client) *RedisObjectDB {
-	return &RedisObjectDB{
-		redisClient: client,
-	}
-}
-
-func (db *RedisObjectDB) Store(ctx context.Context, object Object) error {
-	objectBytes, err := json.Marshal(object)
-	if err != nil {
-		return err
-	}
-
-	key := fmt.Sprintf("%s:%s", object.GetKind(), object.GetID())
-	err = db.redisClient.Set(ctx, key, objectBytes, 0).Err()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (db *RedisObjectDB) GetObjectByID(ctx context.Context, id string) (Object, error) {
-	objects, err := db.getObjectsByField(ctx, "ID", id)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(objects) == 0 {
-		return nil, fmt.Errorf("object with ID '%s' not found", id)
-	}
-
-	return objects[0], nil
-}
-
-func (db *RedisObjectDB) GetObjectByName(ctx context.Context, name string) (Object, error) {
-	objects, err := db.getObjectsByField(ctx, "Name", name)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(objects) == 0 {
-		return nil, fmt.Errorf("object with name '%s' not found", name)
-	}
-
-	return objects[0], nil
-}
-
-func (db *RedisObjectDB) ListObjects(ctx context.Context, kind string) ([]Object, error) {
-	iter := db.redisClient.Scan(ctx, 0, fmt.Sprintf("%s:*", kind), 0).Iterator()
-
-	var objects []Object
-	for iter.Next(ctx) {
-		val, err := db.redisClient.Get(ctx, iter.Val()).Bytes()
-		if err != nil {
-			return nil, err
-		}
-
-		var object Object
-		err = json.Unmarshal(val, &object)
-		if err != nil {
-			return nil, err
-		}
 
-		objects = append(objects, object)
-	}
-
-	return objects, nil
-}
-
-func (db *RedisObjectDB) DeleteObject(ctx context.Context, id string) error {
-	object, err := db.GetObjectByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	key := fmt.Sprintf("%s:%s", object.GetKind(), object.GetID())
-	err = db.redisClient.Del(ctx, key).Err()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (db *RedisObjectDB) getObjectsByField(ctx context.Context, field string, value string) ([]Object, error) {
-	iter := db.redisClient.Scan(ctx, 0, "*", 0).Iterator()
-
-	var objects []Object
-	for iter.Next(ctx) {
-		val, err := db.redisClient.Get(ctx, iter.Val()).Bytes()
-		if err != nil {
-			return nil, err
-		}
-
-		var object Object
-		err = json.Unmarshal(val, &object)
-		if err != nil {
-			return nil, err
-		}
-
-		objectValue := reflect.ValueOf(object).Elem().FieldByName(field).String()
-		if objectValue == value {
-			objects = append(objects, object)
-		}
-	}
-
-	return objects, nil
-}
+	"go-assignment/objectdb"
+)
 
 func main() {
 	redisClient := redis.NewClient(&redis.Options{
@@ -201,12 +16,15 @@ func main() {
 		DB:       0,
 	})
 
-	objectDB := NewRedisObjectDB(redisClient)
+	objectDB := objectdb.NewRedisObjectDB(redisClient)
+	defer objectDB.Close()
 
 	// Testing the implementation
-	person := &Person{
-		Name:     "John Doe",
-		ID:       "123",
+	person := &objectdb.Person{
+		ObjectMeta: objectdb.ObjectMeta{
+			Name: "John Doe",
+			ID:   "123",
+		},
 		LastName: "Doe",
 		Birthday: "01-01-1990",
 	}
@@ -225,9 +43,11 @@ func main() {
 
 	fmt.Println("Retrieved person:", retrievedPerson)
 
-	animal := &Animal{
-		Name:    "Rex",
-		ID:      "456",
+	animal := &objectdb.Animal{
+		ObjectMeta: objectdb.ObjectMeta{
+			Name: "Rex",
+			ID:   "456",
+		},
 		Type:    "Dog",
 		OwnerID: "123",
 	}
@@ -246,7 +66,7 @@ func main() {
 
 	fmt.Println("Retrieved animal:", retrievedAnimal)
 
-	objects, err := objectDB.ListObjects(context.Background(), reflect.TypeOf(person).String())
+	objects, err := objectDB.ListObjects(context.Background(), person.GetKind())
 	if err != nil {
 		fmt.Println("Error listing objects:", err)
 		return
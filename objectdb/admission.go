@@ -0,0 +1,93 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDenied is wrapped by the DenialError Store returns for writes an
+// admission rule rejects.
+var ErrDenied = errors.New("denied by policy")
+
+// AdmissionRule is one policy every write of its kind must satisfy. A rule
+// may give Require, Check or both; the write is denied by the first part
+// that fails.
+type AdmissionRule struct {
+	// Name identifies the rule in denials.
+	Name string
+	// Kind limits the rule to one kind, by registered or short name;
+	// empty applies it to every kind.
+	Kind string
+	// Require lists filters the object must match, as parsed by
+	// ParseFilter, e.g. "labels.team=payments" or "version<=100".
+	Require []Filter
+	// Check returns why object may not replace previous, which is nil for
+	// creates, or nil to admit it.
+	Check func(ctx context.Context, object, previous Object) error
+}
+
+// DenialError explains which admission rule rejected a write and why.
+type DenialError struct {
+	Rule   string `json:"rule"`
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func (e *DenialError) Error() string {
+	return fmt.Sprintf("%s '%s' denied by policy '%s': %s", e.Kind, e.ID, e.Rule, e.Reason)
+}
+
+func (e *DenialError) Unwrap() error {
+	return ErrDenied
+}
+
+// WithAdmissionRules makes Store, and every other write, check objects
+// against rules, in order, once they have passed validation. Rules for
+// kinds that are not registered are ignored.
+func WithAdmissionRules(rules ...AdmissionRule) Option {
+	return func(db *RedisObjectDB) {
+		for _, rule := range rules {
+			if rule.Kind != "" {
+				kind, ok := lookupKind(rule.Kind)
+				if !ok {
+					continue
+				}
+				rule.Kind = kind
+			}
+			db.admissionRules = append(db.admissionRules, rule)
+		}
+	}
+}
+
+// admit returns a DenialError for the first rule object breaks.
+func (db *RedisObjectDB) admit(ctx context.Context, object, previous Object) error {
+	for _, rule := range db.admissionRules {
+		if rule.Kind != "" && rule.Kind != object.GetKind() {
+			continue
+		}
+
+		reason := ""
+		for _, f := range rule.Require {
+			if !f.Matches(object) {
+				op := f.Op
+				if op == "" {
+					op = OpEq
+				}
+				reason = fmt.Sprintf("requires %s %s %v", f.Field, op, f.Value)
+				break
+			}
+		}
+		if reason == "" && rule.Check != nil {
+			if err := rule.Check(ctx, object, previous); err != nil {
+				reason = err.Error()
+			}
+		}
+		if reason != "" {
+			return &DenialError{Rule: rule.Name, Kind: object.GetKind(), ID: object.GetID(), Reason: reason}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Aggregate is a root object composed with related objects, such as a
+// person with their animals.
+type Aggregate struct {
+	Root Object `json:"root"`
+	// Related holds the included objects by short kind name, e.g.
+	// "animal", with an empty list for included kinds nothing relates to.
+	Related map[string][]Object `json:"related"`
+}
+
+// GetAggregate returns the object with rootID together with the objects of
+// the included kinds, by registered or short name, that it references or
+// that reference it, in either case through `ref`-tagged fields:
+//
+//	GetAggregate(ctx, personID, "animal")
+//
+// returns a person and the animals they own. Unlike chaining
+// GetObjectByID and ListReferrers it takes two round trips however many
+// kinds are included: one pipeline finds the root and its referrers, one
+// MGET reads everything related. If several kinds have an object with
+// rootID, the first in Kinds order is the root.
+func (db *RedisObjectDB) GetAggregate(ctx context.Context, rootID string, includes ...string) (*Aggregate, error) {
+	ctx, cancel := db.withTimeout(ctx, "GetAggregate")
+	defer cancel()
+
+	included := map[string]string{}
+	for _, include := range includes {
+		kind, ok := lookupKind(include)
+		if !ok {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, include)
+		}
+		included[kind] = shortKindName(kind)
+	}
+
+	searched := Kinds()
+	pipe := db.redisClient.Pipeline()
+	values := make([]*redis.StringCmd, len(searched))
+	referrers := make([]*redis.StringSliceCmd, len(searched))
+	for i, kind := range searched {
+		values[i] = pipe.Get(ctx, objectKey(kind, rootID))
+		referrers[i] = pipe.SMembers(ctx, refIndexKey(kind, rootID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var root Object
+	var keys []string
+	for i, kind := range searched {
+		data, err := values[i].Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if root, err = db.decode(kind, data); err != nil {
+			return nil, err
+		}
+
+		for _, key := range referrers[i].Val() {
+			if k, _, ok := parseObjectKey(key); ok && included[k] != "" {
+				keys = append(keys, key)
+			}
+		}
+		break
+	}
+	if root == nil {
+		return nil, fmt.Errorf("object with ID '%s' %w", rootID, ErrNotFound)
+	}
+
+	aggregate := &Aggregate{Root: root, Related: map[string][]Object{}}
+	for _, short := range included {
+		aggregate.Related[short] = []Object{}
+	}
+
+	for _, ref := range objectReferences(root) {
+		if included[ref.Kind] != "" {
+			keys = append(keys, objectKey(ref.Kind, ref.ID))
+		}
+	}
+	err := db.fetchObjects(ctx, dedupe(keys), func(object Object) error {
+		if relates(root, object) {
+			short := included[object.GetKind()]
+			aggregate.Related[short] = append(aggregate.Related[short], object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregate, nil
+}
+
+// relates reports whether either of a and b references the other; the ref
+// index may lag behind the objects themselves.
+func relates(a, b Object) bool {
+	for _, pair := range [][2]Object{{a, b}, {b, a}} {
+		for _, ref := range objectReferences(pair[0]) {
+			if ref.Kind == pair[1].GetKind() && ref.ID == pair[1].GetID() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func dedupe(keys []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	return unique
+}
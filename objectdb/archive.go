@@ -0,0 +1,533 @@
+package objectdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ArchiveBackend holds archived values outside Redis, e.g. in a directory,
+// see FileArchive, or an S3 bucket. Keys are slash-separated paths made of
+// URL path-escaped segments, so they suit object stores and file systems
+// alike.
+type ArchiveBackend interface {
+	Put(ctx context.Context, key string, value []byte) error
+	// Get fails with an error wrapping os.ErrNotExist for unknown keys.
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Archived values are replaced in Redis by a stub framed as 0x00,
+// formatArchived, the SHA-256 of the archived value and its key in the
+// backend. Stubs are signed like any value when the store has an
+// integrity key, and the archived value is the stored value as it was,
+// so reads decode it the usual way once fetched.
+const formatArchived byte = 0x30
+
+// archiveIndexKey maps the keys of archived objects to their values' keys
+// in the backend, so values that are no longer referenced can be pruned.
+const archiveIndexKey = "archive:objects"
+
+// ArchivalRule moves the objects of a kind out of Redis once they have
+// gone After without an update.
+type ArchivalRule struct {
+	// Kind is the kind the rule applies to, by registered or short name.
+	Kind  string
+	After time.Duration
+	// Match, if given, limits the rule to objects matching every filter,
+	// as parsed by ParseFilter.
+	Match []Filter
+}
+
+// ArchivalReport describes one pass of RunArchival.
+type ArchivalReport struct {
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+	// Kinds has one entry per rule, in the order the rules were given.
+	Kinds []KindArchival `json:"kinds"`
+	// Archived is the number of objects the pass moved to the backend and
+	// Bytes the size of their values.
+	Archived int64 `json:"archived"`
+	Bytes    int64 `json:"bytes"`
+	// Pruned archived values belonged to objects that have since been
+	// written to or deleted, and were removed from the backend.
+	Pruned int64 `json:"pruned"`
+}
+
+// KindArchival is what one archival rule did in a pass.
+type KindArchival struct {
+	Kind string `json:"kind"`
+	// Checked objects were last updated before Cutoff according to the
+	// updated_at index, archived or not.
+	Cutoff   time.Time `json:"cutoff"`
+	Checked  int64     `json:"checked"`
+	Archived int64     `json:"archived"`
+	// Skipped objects were written to while being archived, and are left
+	// for the next pass.
+	Skipped int64 `json:"skipped"`
+}
+
+type archivalState struct {
+	mu   sync.Mutex
+	last ArchivalReport
+}
+
+// WithArchive moves objects matching rules to backend every interval in
+// the background until Close, leaving stubs in their place. Archived
+// objects keep their index entries and read like any other, their value
+// fetched from backend. The first GetObjectByID, GetObject or
+// GetObjectByName also stores it in Redis again, as does writing the
+// object; listings and Healthy leave the stub in place. Rules go
+// by updates, not reads, so the next pass archives an object that was
+// only read again. Rules for kinds that are not registered, or without an
+// After, are ignored. An interval of 0 only archives when RunArchival is
+// called, but reads of archived objects still need the backend.
+func WithArchive(backend ArchiveBackend, interval time.Duration, rules ...ArchivalRule) Option {
+	return func(db *RedisObjectDB) {
+		db.archive = backend
+		for _, rule := range rules {
+			kind, ok := lookupKind(rule.Kind)
+			if !ok || rule.After <= 0 {
+				continue
+			}
+			rule.Kind = kind
+			db.archivalRules = append(db.archivalRules, rule)
+		}
+		if interval > 0 {
+			db.archivalInterval = interval
+		}
+	}
+}
+
+func (db *RedisObjectDB) startArchival() {
+	db.goBackground(func(ctx context.Context) {
+		ticker := time.NewTicker(db.archivalInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				db.RunArchival(ctx)
+			}
+		}
+	})
+}
+
+// LastArchival returns the report of the most recent RunArchival pass,
+// finished or not.
+func (db *RedisObjectDB) LastArchival() ArchivalReport {
+	db.archival.mu.Lock()
+	defer db.archival.mu.Unlock()
+
+	return db.archival.last
+}
+
+// RunArchival archives the objects the WithArchive rules have gone cold,
+// then prunes archived values no object refers to anymore. Candidates are
+// read from each kind's updated_at range index, oldest first, and each
+// batch is archived under WATCH, so an object written in the meantime
+// stays in Redis.
+func (db *RedisObjectDB) RunArchival(ctx context.Context) (ArchivalReport, error) {
+	if db.archive == nil {
+		return ArchivalReport{}, errors.New("archival is not enabled")
+	}
+
+	report := ArchivalReport{Started: time.Now()}
+	publish := func() {
+		report.Elapsed = time.Since(report.Started)
+		db.archival.mu.Lock()
+		db.archival.last = report
+		db.archival.mu.Unlock()
+	}
+	defer publish()
+
+	for _, rule := range db.archivalRules {
+		report.Kinds = append(report.Kinds, KindArchival{Kind: rule.Kind, Cutoff: report.Started.Add(-rule.After)})
+		if err := db.archiveKind(ctx, rule, &report); err != nil {
+			return report, err
+		}
+		publish()
+	}
+
+	return report, db.pruneArchive(ctx, &report)
+}
+
+func (db *RedisObjectDB) archiveKind(ctx context.Context, rule ArchivalRule, report *ArchivalReport) error {
+	ka := &report.Kinds[len(report.Kinds)-1]
+	max := strconv.FormatInt(ka.Cutoff.UnixMicro(), 10)
+
+	// Archived objects stay in the index, so pages simply move on.
+	for offset := int64(0); ; offset += int64(db.fetchBatchSize) {
+		keys, err := db.redisClient.ZRangeByScore(ctx, rangeIndexKey(rule.Kind, "updated_at"), &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    "(" + max,
+			Offset: offset,
+			Count:  int64(db.fetchBatchSize),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		if err := db.archiveBatch(ctx, rule, keys, report); err != nil {
+			return err
+		}
+	}
+}
+
+func (db *RedisObjectDB) archiveBatch(ctx context.Context, rule ArchivalRule, keys []string, report *ArchivalReport) error {
+	ka := &report.Kinds[len(report.Kinds)-1]
+	ka.Checked += int64(len(keys))
+
+	var put, replaced []string
+	var archived, size int64
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		replaced = nil
+		vals, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		stubs := map[string][]byte{}
+		backendKeys := map[string]string{}
+		namespaces := map[string]string{}
+		for i, val := range vals {
+			data, ok := val.(string)
+			if !ok || db.isArchived([]byte(data)) {
+				continue
+			}
+			object, err := db.decode(rule.Kind, []byte(data))
+			if err != nil || !object.GetObjectMeta().UpdatedAt.Before(ka.Cutoff) || !matches(object, rule.Match) {
+				continue
+			}
+
+			key, stub, err := db.archiveStub(object, []byte(data))
+			if err != nil {
+				return err
+			}
+			if err := db.archive.Put(ctx, key, []byte(data)); err != nil {
+				return fmt.Errorf("archive %s: %w", keys[i], err)
+			}
+			put = append(put, key)
+			stubs[keys[i]] = stub
+			backendKeys[keys[i]] = key
+			namespaces[keys[i]] = objectNamespace(object)
+			size += int64(len(data))
+		}
+		if len(stubs) == 0 {
+			return nil
+		}
+
+		// Objects archived before and since written back to Redis, e.g. by
+		// Migrate or a read, still have their old value in the backend,
+		// which the new index entries would leave behind.
+		fields := make([]string, 0, len(stubs))
+		for key := range stubs {
+			fields = append(fields, key)
+		}
+		old, err := tx.HMGet(ctx, archiveIndexKey, fields...).Result()
+		if err != nil {
+			return err
+		}
+		for _, v := range old {
+			if key, ok := v.(string); ok {
+				replaced = append(replaced, key)
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for key, stub := range stubs {
+				queueSet(ctx, pipe, key, namespaces[key], stub)
+				pipe.HSet(ctx, archiveIndexKey, key, backendKeys[key])
+			}
+			return nil
+		})
+		if err == nil {
+			archived = int64(len(stubs))
+		}
+		return err
+	}, keys...)
+	if err != nil {
+		// Nothing refers to the values just put.
+		for _, key := range put {
+			db.archive.Delete(ctx, key)
+		}
+	}
+	if errors.Is(err, redis.TxFailedErr) {
+		ka.Skipped += int64(len(keys))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, key := range replaced {
+		if err := db.archive.Delete(ctx, key); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("prune archived value '%s': %w", key, err)
+		}
+		report.Pruned++
+	}
+
+	ka.Archived += archived
+	report.Archived += archived
+	report.Bytes += size
+	return nil
+}
+
+// archiveStub returns a new backend key to archive value of object under
+// and the stub that replaces it in Redis. Keys are random, so neither a
+// newer version of the object nor another store archiving it at the same
+// time ever overwrites a value a stub refers to.
+func (db *RedisObjectDB) archiveStub(object Object, value []byte) (string, []byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	key := fmt.Sprintf("%s/%s/%s", url.PathEscape(object.GetKind()), url.PathEscape(object.GetID()), hex.EncodeToString(id))
+
+	sum := sha256.Sum256(value)
+	stub := append([]byte{valueMarker, formatArchived}, sum[:]...)
+	return key, db.sign(append(stub, key...)), nil
+}
+
+func isArchiveStub(data []byte) bool {
+	return len(data) > 2+sha256.Size && data[0] == valueMarker && data[1] == formatArchived
+}
+
+func archiveStubKey(stub []byte) string {
+	return string(stub[2+sha256.Size:])
+}
+
+// isArchived reports whether the stored value data is an archive stub.
+func (db *RedisObjectDB) isArchived(data []byte) bool {
+	inner, err := db.verify(data)
+	return err == nil && isArchiveStub(inner)
+}
+
+// unarchive fetches the value stub stands for from the backend. Like the
+// namespace keys, values are fetched under the store's lifetime, decoding
+// having no context of its own.
+func (db *RedisObjectDB) unarchive(stub []byte) ([]byte, error) {
+	key := archiveStubKey(stub)
+	if db.archive == nil {
+		return nil, fmt.Errorf("value is archived as '%s' but archival is not enabled", key)
+	}
+
+	ctx, cancel := db.withTimeout(db.lifetime, "Unarchive")
+	defer cancel()
+
+	value, err := db.archive.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("fetch archived value '%s': %w", key, err)
+	}
+	sum := sha256.Sum256(value)
+	if !bytes.Equal(sum[:], stub[2:2+sha256.Size]) {
+		return nil, fmt.Errorf("archived value '%s' does not match its stub: %w", key, ErrIntegrity)
+	}
+	if isArchiveStub(value) {
+		return nil, fmt.Errorf("archived value '%s' is itself a stub", key)
+	}
+
+	return value, nil
+}
+
+// verifyValue is verify for stored values that may be archive stubs, whose
+// archived value it fetches and verifies in turn.
+func (db *RedisObjectDB) verifyValue(data []byte) ([]byte, error) {
+	data, err := db.verify(data)
+	if err != nil || !isArchiveStub(data) {
+		return data, err
+	}
+	if data, err = db.unarchive(data); err != nil {
+		return nil, err
+	}
+
+	return db.verify(data)
+}
+
+// rehydrate replaces the stub of object in Redis with its archived value,
+// and restores its index entries, unless the stub was replaced in the
+// meantime. The backend copy is left for pruneArchive. It is best effort:
+// a read that fails to rehydrate still returns the object, and the next
+// one tries again.
+func (db *RedisObjectDB) rehydrate(object Object, stub, value []byte) {
+	ctx, cancel := db.withTimeout(db.lifetime, "Rehydrate")
+	defer cancel()
+
+	key := objectKey(object.GetKind(), object.GetID())
+	db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Bytes()
+		if err != nil || !bytes.Equal(current, stub) {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			queueSet(ctx, pipe, key, objectNamespace(object), value)
+			updateIndexes(ctx, pipe, nil, object)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// pruneArchive deletes archived values whose objects have since been
+// written to, and so hold their value in Redis again, or deleted.
+func (db *RedisObjectDB) pruneArchive(ctx context.Context, report *ArchivalReport) error {
+	it := db.redisClient.HScan(ctx, archiveIndexKey, 0, "", int64(db.fetchBatchSize)).Iterator()
+
+	archived := map[string]string{}
+	for it.Next(ctx) {
+		objectKey := it.Val()
+		if !it.Next(ctx) {
+			break
+		}
+		archived[objectKey] = it.Val()
+		if len(archived) < db.fetchBatchSize {
+			continue
+		}
+		if err := db.pruneBatch(ctx, archived, report); err != nil {
+			return err
+		}
+		archived = map[string]string{}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return db.pruneBatch(ctx, archived, report)
+}
+
+func (db *RedisObjectDB) pruneBatch(ctx context.Context, archived map[string]string, report *ArchivalReport) error {
+	keys := make([]string, 0, len(archived))
+	for key := range archived {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var stale []string
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		vals, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		var fields []string
+		for i, val := range vals {
+			data, _ := val.(string)
+			inner, err := db.verify([]byte(data))
+			if err == nil && isArchiveStub(inner) && archiveStubKey(inner) == archived[keys[i]] {
+				continue
+			}
+			fields = append(fields, keys[i])
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, archiveIndexKey, fields...)
+			return nil
+		})
+		if err == nil {
+			for _, key := range fields {
+				stale = append(stale, archived[key])
+			}
+		}
+		return err
+	}, keys...)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		if err := db.archive.Delete(ctx, key); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("prune archived value '%s': %w", key, err)
+		}
+		report.Pruned++
+	}
+	return nil
+}
+
+// FileArchive is an ArchiveBackend keeping values as files below a
+// directory.
+type FileArchive struct {
+	Dir string
+}
+
+func (a FileArchive) path(key string) (string, error) {
+	path := filepath.FromSlash(key)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("invalid archive key '%s'", key)
+	}
+
+	return filepath.Join(a.Dir, path), nil
+}
+
+// Put writes value to a temporary file first, so a crash never leaves a
+// partial value behind.
+func (a FileArchive) Put(ctx context.Context, key string, value []byte) error {
+	path, err := a.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (a FileArchive) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := a.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+func (a FileArchive) Delete(ctx context.Context, key string) error {
+	path, err := a.path(key)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,86 @@
+package objectdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// archivedPerson stores a person and archives it, returning its key.
+func archivedPerson(t *testing.T, db *RedisObjectDB) string {
+	t.Helper()
+	ctx := context.Background()
+
+	person := &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Doe"}
+	if err := db.Store(ctx, person); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	report, err := db.RunArchival(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Archived != 1 {
+		t.Fatalf("archived %d objects, want 1", report.Archived)
+	}
+
+	return objectKey(person.GetKind(), person.ID)
+}
+
+func newArchiveDB(t *testing.T) (*RedisObjectDB, func(key string) bool) {
+	t.Helper()
+
+	db, mr := newTestDB(t, WithArchive(FileArchive{Dir: t.TempDir()}, 0, ArchivalRule{Kind: "person", After: time.Nanosecond}))
+	stubbed := func(key string) bool {
+		value, err := mr.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db.isArchived([]byte(value))
+	}
+
+	return db, stubbed
+}
+
+func TestReadsOnlyRehydrateOnGet(t *testing.T) {
+	db, stubbed := newArchiveDB(t)
+	ctx := context.Background()
+	key := archivedPerson(t, db)
+
+	if err := db.Healthy(ctx); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := db.ListObjects(ctx, (&Person{}).GetKind())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].GetName() != "John" {
+		t.Fatalf("ListObjects() = %v, want the archived person", objects)
+	}
+	if !stubbed(key) {
+		t.Fatal("Healthy or ListObjects stored the archived value in Redis again")
+	}
+
+	object, err := db.GetObjectByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.GetName() != "John" {
+		t.Fatalf("GetObjectByID() = %v, want the archived person", object)
+	}
+	if stubbed(key) {
+		t.Fatal("GetObjectByID left the stub in place")
+	}
+}
+
+func TestGetObjectRehydrates(t *testing.T) {
+	db, stubbed := newArchiveDB(t)
+	key := archivedPerson(t, db)
+
+	if _, err := db.GetObject(context.Background(), "person", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if stubbed(key) {
+		t.Fatal("GetObject left the stub in place")
+	}
+}
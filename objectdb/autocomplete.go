@@ -0,0 +1,94 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The name index of a kind is a sorted set with every score 0, so Redis
+// orders it by member. Each member is the name as folded by foldName, the
+// name as written and the object key, separated by NUL bytes; a prefix of
+// folded names is then one ZRANGEBYLEX away, and the names come back
+// without reading any object.
+
+// defaultAutocompleteLimit is the limit of AutocompleteName left at zero.
+const defaultAutocompleteLimit = 10
+
+func nameIndexKey(kind string) string {
+	return fmt.Sprintf("idx:name:%s", kind)
+}
+
+func nameMember(name, key string) string {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "\x00", "")
+	return foldName(name) + "\x00" + name + "\x00" + key
+}
+
+func parseNameMember(member string) (name, key string, ok bool) {
+	parts := strings.SplitN(member, "\x00", 3)
+	if len(parts) != 3 {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func nameIndexEntries(object Object) []indexEntry {
+	if strings.TrimSpace(object.GetName()) == "" {
+		return nil
+	}
+	key := objectKey(object.GetKind(), object.GetID())
+
+	return []indexEntry{{key: nameIndexKey(object.GetKind()), member: nameMember(object.GetName(), key), sorted: true}}
+}
+
+// AutocompleteName returns up to limit distinct names of objects of kind
+// starting with prefix, compared as by foldName, in alphabetical order. A
+// zero limit means 10.
+func (db *RedisObjectDB) AutocompleteName(ctx context.Context, kind, prefix string, limit int) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx, "AutocompleteName")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	if limit <= 0 {
+		limit = defaultAutocompleteLimit
+	}
+	// A trailing blank is kept so "john " only completes names with more
+	// words.
+	folded := foldName(prefix)
+	if folded != "" && strings.TrimRightFunc(prefix, unicode.IsSpace) != prefix {
+		folded += " "
+	}
+	prefix = folded
+
+	var names []string
+	seen := map[string]bool{}
+	by := redis.ZRangeBy{Min: "[" + prefix, Max: "[" + prefix + "\xff", Count: int64(limit)}
+	for len(names) < limit {
+		members, err := db.redisClient.ZRangeByLex(ctx, nameIndexKey(name), &by).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			n, _, ok := parseNameMember(member)
+			if ok && !seen[n] && len(names) < limit {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+		// Objects sharing a name take a member each, so a full page may
+		// still hold fewer than limit names.
+		if len(members) < limit {
+			break
+		}
+		by.Offset += int64(len(members))
+	}
+
+	return names, nil
+}
@@ -0,0 +1,160 @@
+package objectdb_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectdb/testsupport"
+)
+
+// The benchmarks run every combination of codec and compression, and for
+// ListObjects of fetch batch size, against one Redis started by
+// testsupport, flushed between configurations:
+//
+//	go test ./objectdb -run '^$' -bench . -benchmem
+//	go test ./objectdb -run '^$' -bench 'BenchmarkListObjects/codec=msgpack/'
+//
+// They are skipped when Docker is not available.
+
+const benchObjects = 1000
+
+var benchCodecs = []struct {
+	name string
+	opt  objectdb.Option
+}{
+	{"json", objectdb.WithCodec(objectdb.JSONCodec{})},
+	{"msgpack", objectdb.WithMsgpack()},
+	{"cbor", objectdb.WithCBOR()},
+	{"protobuf", objectdb.WithProtobuf()},
+}
+
+var benchCompressions = []struct {
+	name string
+	c    objectdb.Compression
+}{
+	{"none", objectdb.CompressionNone},
+	{"gzip", objectdb.CompressionGzip},
+	{"zstd", objectdb.CompressionZstd},
+}
+
+var benchBatchSizes = []int{10, 100, 1000}
+
+type benchConfig struct {
+	name string
+	opts []objectdb.Option
+}
+
+func benchConfigs(batchSizes []int) []benchConfig {
+	var configs []benchConfig
+	for _, codec := range benchCodecs {
+		for _, compression := range benchCompressions {
+			for _, size := range batchSizes {
+				// A threshold of 1 compresses every value, the persons
+				// stored being well under the default 1 KiB.
+				opts := []objectdb.Option{codec.opt, objectdb.WithCompression(compression.c, 1), objectdb.WithFetchBatchSize(size)}
+				name := fmt.Sprintf("codec=%s/compression=%s", codec.name, compression.name)
+				if len(batchSizes) > 1 {
+					name += fmt.Sprintf("/batch=%d", size)
+				}
+				configs = append(configs, benchConfig{name: name, opts: opts})
+			}
+		}
+	}
+
+	return configs
+}
+
+// runBench runs bench as a sub-benchmark per configuration, each on an
+// empty database.
+func runBench(b *testing.B, configs []benchConfig, bench func(b *testing.B, db *objectdb.RedisObjectDB)) {
+	url := testsupport.StartRedis(b)
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	client := redis.NewClient(options)
+	defer client.Close()
+
+	for _, config := range configs {
+		b.Run(config.name, func(b *testing.B) {
+			if err := client.FlushDB(context.Background()).Err(); err != nil {
+				b.Fatal(err)
+			}
+			db, err := objectdb.NewRedisObjectDBFromURL(url, config.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+
+			bench(b, db)
+		})
+	}
+}
+
+func benchPerson(i int) *objectdb.Person {
+	return &objectdb.Person{
+		ObjectMeta: objectdb.ObjectMeta{
+			ID:     fmt.Sprintf("bench-%d", i),
+			Name:   fmt.Sprintf("Person %d", i),
+			Labels: map[string]string{"team": "benchmarks", "tier": fmt.Sprint(i % 3)},
+		},
+		LastName: strings.Repeat("Doe", 10),
+	}
+}
+
+func seedBench(b *testing.B, db *objectdb.RedisObjectDB) {
+	b.Helper()
+
+	objects := make([]objectdb.Object, benchObjects)
+	for i := range objects {
+		objects[i] = benchPerson(i)
+	}
+	testsupport.Seed(b, db, objects...)
+	b.ResetTimer()
+}
+
+func BenchmarkStore(b *testing.B) {
+	runBench(b, benchConfigs([]int{100}), func(b *testing.B, db *objectdb.RedisObjectDB) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if err := db.Store(ctx, benchPerson(i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetObjectByID(b *testing.B) {
+	runBench(b, benchConfigs([]int{100}), func(b *testing.B, db *objectdb.RedisObjectDB) {
+		seedBench(b, db)
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := db.GetObjectByID(ctx, benchPerson(i%benchObjects).ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkListObjects(b *testing.B) {
+	runBench(b, benchConfigs(benchBatchSizes), func(b *testing.B, db *objectdb.RedisObjectDB) {
+		seedBench(b, db)
+		ctx := context.Background()
+		kind := (&objectdb.Person{}).GetKind()
+		for i := 0; i < b.N; i++ {
+			objects, err := db.ListObjects(ctx, kind)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(objects) != benchObjects {
+				b.Fatalf("listed %d objects, want %d", len(objects), benchObjects)
+			}
+		}
+		b.ReportMetric(float64(benchObjects), "objects/op")
+	})
+}
@@ -0,0 +1,160 @@
+package objectdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the canonical form of Person.Birthday, an RFC 3339
+// full-date.
+const dateLayout = "2006-01-02"
+
+// BirthdayOptions configures WithBirthdays.
+type BirthdayOptions struct {
+	// Layouts are the time.Parse layouts tried in order on Birthday. The
+	// default accepts RFC 3339 dates and timestamps only, as day-first and
+	// month-first dates cannot be told apart.
+	Layouts []string
+	// Location is the zone of birthdays given without an offset, and the
+	// zone BirthDate is set to midnight in. Timestamps with an offset are
+	// converted to it before their date is taken. Defaults to UTC.
+	Location *time.Location
+}
+
+// WithBirthdays makes Store normalize Person birthdays: Birthday is parsed
+// with opts and rewritten as an RFC 3339 date, and BirthDate set to match,
+// or Birthday derived from BirthDate when only that is given. A Birthday no
+// layout accepts, or one BirthDate disagrees with, fails with a
+// *ValidationError.
+//
+// With field encryption, see WithFieldEncryption and WithNamespaceKeys,
+// Birthday is encrypted but BirthDate could not be, so BirthDate is cleared
+// instead of set and stays out of the birth_date range index; Age and
+// NextBirthday then work from Birthday.
+func WithBirthdays(opts BirthdayOptions) Option {
+	return func(db *RedisObjectDB) {
+		if len(opts.Layouts) == 0 {
+			opts.Layouts = []string{dateLayout, time.RFC3339}
+		}
+		if opts.Location == nil {
+			opts.Location = time.UTC
+		}
+		db.birthdays = &opts
+	}
+}
+
+func (db *RedisObjectDB) normalizeBirthday(object Object) error {
+	p, ok := object.(*Person)
+	if !ok || db.birthdays == nil {
+		return nil
+	}
+	loc := db.birthdays.Location
+
+	if p.Birthday == "" {
+		if !p.BirthDate.IsZero() {
+			p.BirthDate = midnight(p.BirthDate.In(loc))
+			p.Birthday = p.BirthDate.Format(dateLayout)
+		}
+		if db.encryptsFields() {
+			p.BirthDate = time.Time{}
+		}
+		return nil
+	}
+
+	date, ok := db.parseBirthday(p.Birthday)
+	if !ok {
+		return &ValidationError{Kind: p.GetKind(), ID: p.ID, Violations: []Violation{{
+			Field:   "birthday",
+			Message: fmt.Sprintf("'%s' is not a date in an accepted layout", p.Birthday),
+		}}}
+	}
+	if !p.BirthDate.IsZero() && !midnight(p.BirthDate.In(loc)).Equal(date) {
+		return &ValidationError{Kind: p.GetKind(), ID: p.ID, Violations: []Violation{{
+			Field:   "birth_date",
+			Message: fmt.Sprintf("%s disagrees with birthday '%s'", p.BirthDate.Format(dateLayout), p.Birthday),
+		}}}
+	}
+
+	p.Birthday = date.Format(dateLayout)
+	if !db.encryptsFields() {
+		p.BirthDate = date
+		return nil
+	}
+
+	// Person.Validate cannot see the date once BirthDate is cleared.
+	if date.After(time.Now()) {
+		return &ValidationError{Kind: p.GetKind(), ID: p.ID, Violations: []Violation{{
+			Field:   "birthday",
+			Message: fmt.Sprintf("'%s' is in the future", p.Birthday),
+		}}}
+	}
+	p.BirthDate = time.Time{}
+	return nil
+}
+
+// encryptsFields reports whether pii fields are stored encrypted.
+func (db *RedisObjectDB) encryptsFields() bool {
+	return db.piiAEAD != nil || db.namespaceKeys != nil
+}
+
+func (db *RedisObjectDB) parseBirthday(s string) (time.Time, bool) {
+	for _, layout := range db.birthdays.Layouts {
+		t, err := time.ParseInLocation(layout, s, db.birthdays.Location)
+		if err == nil {
+			return midnight(t.In(db.birthdays.Location)), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// birthDate returns BirthDate, or else Birthday as a date in UTC if it is
+// one in the canonical layout, as stores with field encryption leave it.
+func (p *Person) birthDate() (time.Time, bool) {
+	if !p.BirthDate.IsZero() {
+		return p.BirthDate, true
+	}
+
+	t, err := time.Parse(dateLayout, p.Birthday)
+	return t, err == nil
+}
+
+// Age returns p's age in whole years on the date of at, in the zone of
+// BirthDate, and false if p has no birth date.
+func (p *Person) Age(at time.Time) (int, bool) {
+	born, ok := p.birthDate()
+	if !ok {
+		return 0, false
+	}
+
+	at = at.In(born.Location())
+	years := at.Year() - born.Year()
+	if at.Month() < born.Month() || at.Month() == born.Month() && at.Day() < born.Day() {
+		years--
+	}
+
+	return years, true
+}
+
+// NextBirthday returns the first birthday of p on or after the date of at,
+// and false if p has no birth date. Birthdays on 29 February fall on 1
+// March in common years.
+func (p *Person) NextBirthday(at time.Time) (time.Time, bool) {
+	born, ok := p.birthDate()
+	if !ok {
+		return time.Time{}, false
+	}
+
+	loc := born.Location()
+	today := midnight(at.In(loc))
+	next := time.Date(today.Year(), born.Month(), born.Day(), 0, 0, 0, 0, loc)
+	if next.Before(today) {
+		next = time.Date(today.Year()+1, born.Month(), born.Day(), 0, 0, 0, 0, loc)
+	}
+
+	return next, true
+}
@@ -0,0 +1,174 @@
+package objectdb
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// storeBatch stores objects the way Store does, but reads their previous
+// versions with one MGET and writes all valid objects in one MULTI. errs[i]
+// is the validation error for objects[i]; err is set when the batch as a
+// whole could not be read or written. An object appearing twice in a batch
+// replaces its earlier copy, as two Store calls would.
+//
+// With tx set, objects may reference each other and nothing is written
+// unless every object is valid.
+func (db *RedisObjectDB) storeBatch(ctx context.Context, objects []Object, tx bool) (errs []error, err error) {
+	errs = make([]error, len(objects))
+	if len(objects) == 0 {
+		return errs, nil
+	}
+
+	var pending map[string]bool
+	if tx {
+		pending = make(map[string]bool, len(objects))
+		for _, object := range objects {
+			pending[objectKey(object.GetKind(), object.GetID())] = true
+		}
+	}
+
+	previous, err := db.previousObjects(ctx, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := db.redisClient.TxPipeline()
+	queued := map[string]Object{}
+	failed := false
+	for i, object := range objects {
+		errs[i] = db.validateObject(ctx, object, pending)
+		if errs[i] != nil {
+			failed = true
+			continue
+		}
+
+		key := objectKey(object.GetKind(), object.GetID())
+		if earlier, ok := queued[key]; ok {
+			previous[i] = earlier
+		}
+
+		value, err := db.prepareWrite(ctx, object, previous[i])
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+
+		db.queueWrite(ctx, pipe, object, previous[i], value)
+		queued[key] = object
+	}
+	if len(queued) == 0 || (tx && failed) {
+		return errs, nil
+	}
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// previousObjects is previousObject for many objects at once.
+func (db *RedisObjectDB) previousObjects(ctx context.Context, objects []Object) ([]Object, error) {
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = objectKey(object.GetKind(), object.GetID())
+	}
+
+	vals, err := db.redisClient.MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	previous := make([]Object, len(objects))
+	for i, val := range vals {
+		data, ok := val.(string)
+		if !ok {
+			continue
+		}
+		// As in previousObject, a value that no longer decodes is
+		// treated as absent.
+		previous[i], _ = db.decode(objects[i].GetKind(), []byte(data))
+	}
+
+	return previous, nil
+}
+
+// batchImporter collects parsed rows for ImportCSV and ImportNDJSON and
+// stores them fetchBatchSize at a time, recording per-row failures in
+// result. With dryRun set, rows are only validated.
+type batchImporter struct {
+	db     *RedisObjectDB
+	dryRun bool
+	result ImportResult
+
+	objects []Object
+	rows    []int
+}
+
+// fail records a row that was rejected before reaching the store.
+func (b *batchImporter) fail(row int, err error) {
+	b.result.Errors = append(b.result.Errors, RowError{Row: row, Err: err})
+}
+
+// add queues object, parsed from row, flushing once a batch is full.
+func (b *batchImporter) add(ctx context.Context, row int, object Object) error {
+	b.objects = append(b.objects, object)
+	b.rows = append(b.rows, row)
+	if len(b.objects) < b.db.fetchBatchSize {
+		return nil
+	}
+
+	return b.flush(ctx)
+}
+
+func (b *batchImporter) flush(ctx context.Context) error {
+	if len(b.objects) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if b.dryRun {
+		errs = make([]error, len(b.objects))
+		for i, object := range b.objects {
+			errs[i] = b.db.validateObject(ctx, object, nil)
+			if errs[i] == nil {
+				errs[i] = validateJSONSchema(object)
+			}
+		}
+	} else {
+		var err error
+		errs, err = b.db.storeBatch(ctx, b.objects, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			b.fail(b.rows[i], err)
+		} else {
+			b.result.Imported++
+		}
+	}
+	b.objects, b.rows = b.objects[:0], b.rows[:0]
+
+	return nil
+}
+
+// ensureID gives object a generated ID if it has none.
+func ensureID(object Object) error {
+	if object.GetID() != "" {
+		return nil
+	}
+
+	id, err := newObjectID()
+	if err != nil {
+		return err
+	}
+	object.SetID(id)
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package objectdb
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CachedObjectDB fronts another ObjectDB with an in-process LRU cache for
+// GetObjectByID and GetObjectByName. Writes made through it invalidate the
+// entries they affect; writes made elsewhere are only picked up once an
+// entry's TTL runs out, unless the writer shares WithCacheInvalidation.
+type CachedObjectDB struct {
+	db ObjectDB
+
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	// keys maps an object ID to the cache keys holding that object, so a
+	// write can drop its name lookups too.
+	keys map[string]map[string]bool
+	// generation counts invalidations, so a load that raced with a write
+	// does not cache what it read before the write.
+	generation uint64
+
+	invalidation *cacheInvalidation
+}
+
+type cacheEntry struct {
+	key     string
+	object  Object
+	expires time.Time
+}
+
+// CacheOption configures a CachedObjectDB at construction time.
+type CacheOption func(*CachedObjectDB)
+
+const (
+	defaultCacheSize = 1000
+	defaultCacheTTL  = time.Minute
+)
+
+// WithCacheSize bounds how many lookups are cached.
+func WithCacheSize(n int) CacheOption {
+	return func(db *CachedObjectDB) {
+		if n > 0 {
+			db.size = n
+		}
+	}
+}
+
+// WithCacheTTL bounds how long a cached object is served without going back
+// to the store.
+func WithCacheTTL(d time.Duration) CacheOption {
+	return func(db *CachedObjectDB) {
+		if d > 0 {
+			db.ttl = d
+		}
+	}
+}
+
+func NewCachedObjectDB(db ObjectDB, opts ...CacheOption) *CachedObjectDB {
+	c := &CachedObjectDB{
+		db:      db,
+		size:    defaultCacheSize,
+		ttl:     defaultCacheTTL,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		keys:    map[string]map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.invalidation != nil {
+		c.subscribe()
+	}
+
+	return c
+}
+
+func (c *CachedObjectDB) Store(ctx context.Context, object Object, opts ...StoreOption) error {
+	err := c.db.Store(ctx, object, opts...)
+	c.invalidate(ctx, object.GetID(), object.GetName())
+
+	return err
+}
+
+func (c *CachedObjectDB) GetObjectByID(ctx context.Context, id string) (Object, error) {
+	return c.lookup("id:"+id, func() (Object, error) {
+		return c.db.GetObjectByID(ctx, id)
+	})
+}
+
+func (c *CachedObjectDB) GetObjectByName(ctx context.Context, name string) (Object, error) {
+	return c.lookup("name:"+name, func() (Object, error) {
+		return c.db.GetObjectByName(ctx, name)
+	})
+}
+
+func (c *CachedObjectDB) ListObjects(ctx context.Context, kind string) ([]Object, error) {
+	return c.db.ListObjects(ctx, kind)
+}
+
+// DeleteObject invalidates id. A delete policy can change other objects
+// too, so deleting with one clears the whole cache.
+func (c *CachedObjectDB) DeleteObject(ctx context.Context, id string, opts ...DeleteOption) error {
+	err := c.db.DeleteObject(ctx, id, opts...)
+	if len(opts) > 0 {
+		c.Purge(ctx)
+	} else {
+		c.invalidate(ctx, id, "")
+	}
+
+	return err
+}
+
+func (c *CachedObjectDB) Close() error {
+	c.unsubscribe()
+	return c.db.Close()
+}
+
+// Purge drops every cached object, on other instances too when
+// WithCacheInvalidation is used.
+func (c *CachedObjectDB) Purge(ctx context.Context) {
+	c.purgeLocal()
+	c.publish(ctx, invalidationMessage{Purge: true})
+}
+
+func (c *CachedObjectDB) purgeLocal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	c.keys = map[string]map[string]bool{}
+}
+
+// lookup serves key from the cache or loads it. Callers get their own copy
+// so changing it cannot corrupt the cache.
+func (c *CachedObjectDB) lookup(key string, load func() (Object, error)) (Object, error) {
+	c.mu.Lock()
+	object, ok := c.get(key)
+	generation := c.generation
+	c.mu.Unlock()
+	if ok {
+		return cloneObject(object)
+	}
+
+	object, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := cloneObject(object)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.generation == generation {
+		c.put(key, cached)
+	}
+	c.mu.Unlock()
+
+	return object, nil
+}
+
+func (c *CachedObjectDB) get(key string) (Object, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+
+	return entry.object, true
+}
+
+func (c *CachedObjectDB) put(key string, object Object) {
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	entry := &cacheEntry{key: key, object: object, expires: time.Now().Add(c.ttl)}
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.keys[object.GetID()] == nil {
+		c.keys[object.GetID()] = map[string]bool{}
+	}
+	c.keys[object.GetID()][key] = true
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *CachedObjectDB) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+
+	id := entry.object.GetID()
+	delete(c.keys[id], entry.key)
+	if len(c.keys[id]) == 0 {
+		delete(c.keys, id)
+	}
+}
+
+// invalidate drops the entries a write to id affects, here and, with
+// WithCacheInvalidation, on the other instances.
+func (c *CachedObjectDB) invalidate(ctx context.Context, id, name string) {
+	c.invalidateLocal(id, name)
+	c.publish(ctx, invalidationMessage{ID: id, Name: name})
+}
+
+// invalidateLocal drops every entry holding object id, and the lookup of
+// name, which a write may have made resolve to a different object.
+func (c *CachedObjectDB) invalidateLocal(id, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key := range c.keys[id] {
+		c.remove(c.entries[key])
+	}
+	if el, ok := c.entries["name:"+name]; ok && name != "" {
+		c.remove(el)
+	}
+}
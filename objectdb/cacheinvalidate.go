@@ -0,0 +1,140 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultInvalidationChannel is the Redis channel WithCacheInvalidation
+// publishes on when given an empty channel name.
+const DefaultInvalidationChannel = "objectdb:cache:invalidate"
+
+// Failed receives are retried after a delay doubling from
+// minReceiveBackoff up to maxReceiveBackoff, so an unreachable Redis does
+// not keep the subscriber spinning.
+const (
+	minReceiveBackoff = 50 * time.Millisecond
+	maxReceiveBackoff = 5 * time.Second
+)
+
+type cacheInvalidation struct {
+	client  *redis.Client
+	channel string
+	origin  string
+	pubsub  *redis.PubSub
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type invalidationMessage struct {
+	Origin string `json:"origin"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Purge  bool   `json:"purge,omitempty"`
+}
+
+// WithCacheInvalidation shares invalidations between every CachedObjectDB
+// using channel on client: each write made through one instance is
+// published, and the others drop their copies as soon as they hear of it.
+// A lost message or a dropped subscription only leaves entries stale until
+// their TTL; on resubscribing the cache is purged to be safe. While Redis
+// is unreachable the subscriber retries with backoff, until Close.
+func WithCacheInvalidation(client *redis.Client, channel string) CacheOption {
+	return func(c *CachedObjectDB) {
+		if channel == "" {
+			channel = DefaultInvalidationChannel
+		}
+
+		origin := make([]byte, 8)
+		rand.Read(origin)
+		c.invalidation = &cacheInvalidation{
+			client:  client,
+			channel: channel,
+			origin:  hex.EncodeToString(origin),
+		}
+	}
+}
+
+// subscribe starts listening for other instances' invalidations.
+func (c *CachedObjectDB) subscribe() {
+	inv := c.invalidation
+	ctx, cancel := context.WithCancel(context.Background())
+	inv.pubsub = inv.client.Subscribe(ctx, inv.channel)
+	inv.cancel = cancel
+	inv.done = make(chan struct{})
+
+	go func() {
+		defer close(inv.done)
+		backoff := minReceiveBackoff
+		for {
+			// Receive reconnects by itself and returns an error once
+			// the subscription is closed.
+			msg, err := inv.pubsub.Receive(ctx)
+			if err == redis.ErrClosed || ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+				if backoff *= 2; backoff > maxReceiveBackoff {
+					backoff = maxReceiveBackoff
+				}
+				continue
+			}
+			backoff = minReceiveBackoff
+
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// Anything published while we were reconnecting
+				// has been missed.
+				if msg.Kind == "subscribe" {
+					c.purgeLocal()
+				}
+			case *redis.Message:
+				var m invalidationMessage
+				if json.Unmarshal([]byte(msg.Payload), &m) != nil || m.Origin == inv.origin {
+					continue
+				}
+				if m.Purge {
+					c.purgeLocal()
+				} else {
+					c.invalidateLocal(m.ID, m.Name)
+				}
+			}
+		}
+	}()
+}
+
+// publish tells the other instances about a local invalidation. Failures
+// are ignored: the write itself succeeded and the TTL bounds staleness.
+func (c *CachedObjectDB) publish(ctx context.Context, m invalidationMessage) {
+	inv := c.invalidation
+	if inv == nil {
+		return
+	}
+
+	m.Origin = inv.origin
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	inv.client.Publish(ctx, inv.channel, payload)
+}
+
+func (c *CachedObjectDB) unsubscribe() {
+	if c.invalidation == nil {
+		return
+	}
+
+	c.invalidation.cancel()
+	c.invalidation.pubsub.Close()
+	<-c.invalidation.done
+}
@@ -0,0 +1,70 @@
+package objectdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCacheInvalidationReachesOtherInstances(t *testing.T) {
+	db, mr := newTestDB(t)
+	ctx := context.Background()
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "Ann"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	a := NewCachedObjectDB(db, WithCacheInvalidation(client, ""))
+	b := NewCachedObjectDB(db, WithCacheInvalidation(client, ""))
+	defer a.unsubscribe()
+	defer b.unsubscribe()
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := b.GetObjectByID(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "Bob"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		object, err := b.GetObjectByID(ctx, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if object.GetName() == "Bob" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the other instance kept serving its stale copy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCacheInvalidationStopsWhileRedisIsDown(t *testing.T) {
+	db, mr := newTestDB(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	c := NewCachedObjectDB(db, WithCacheInvalidation(client, ""))
+	time.Sleep(50 * time.Millisecond)
+
+	// Failing receives back off instead of spinning, so shutting down
+	// while Redis is unreachable returns promptly.
+	mr.Close()
+	time.Sleep(200 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		c.unsubscribe()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the subscriber did not stop")
+	}
+}
@@ -0,0 +1,221 @@
+package objectdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Ops of a ChangeEvent, as Debezium names them.
+const (
+	ChangeOpCreate = "c"
+	ChangeOpUpdate = "u"
+	ChangeOpDelete = "d"
+)
+
+// ChangeEvent is a change feed entry in the shape of a Debezium change
+// event, for publishing to message brokers.
+type ChangeEvent struct {
+	// Before is always null, as the feed does not keep the versions writes
+	// replaced.
+	Before Object `json:"before"`
+	// After is the object as written, or null for deletes. Its pii fields
+	// are as stored, i.e. encrypted when the store encrypts them, unless
+	// the publisher decrypts them, see PublishOptions.DecryptPII.
+	After  Object       `json:"after"`
+	Source ChangeSource `json:"source"`
+	// Op is ChangeOpCreate for the first version of an object,
+	// ChangeOpUpdate for later ones and ChangeOpDelete for deletes.
+	Op string `json:"op"`
+	// TsMs is when the event was made, in Unix milliseconds.
+	TsMs int64 `json:"ts_ms"`
+}
+
+// ChangeSource says where a ChangeEvent comes from.
+type ChangeSource struct {
+	Connector string `json:"connector"`
+	// Name is that of the publisher.
+	Name string `json:"name"`
+	// TsMs is when the change was committed, in Unix milliseconds.
+	TsMs int64  `json:"ts_ms"`
+	Kind string `json:"kind"`
+	// Table is the short kind name, e.g. "animal".
+	Table  string `json:"table"`
+	ID     string `json:"id"`
+	Cursor string `json:"cursor"`
+}
+
+// Key returns the key to publish e under, {"id":"..."}, so that brokers
+// keep the events of one object in order.
+func (e ChangeEvent) Key() []byte {
+	key, _ := json.Marshal(map[string]string{"id": e.Source.ID})
+	return key
+}
+
+// ChangeSink delivers change events to a message broker.
+type ChangeSink interface {
+	// Publish delivers events, which are in feed order, and returns once
+	// the broker has acknowledged all of them.
+	Publish(ctx context.Context, events []ChangeEvent) error
+}
+
+type PublishOptions struct {
+	// Name identifies the publisher. Its position in the feed is saved in
+	// Redis under it.
+	Name string
+	// Kind limits publishing to one kind, by registered or short name;
+	// empty means every kind.
+	Kind string
+	// OnProgress is called after each batch the sink acknowledged.
+	OnProgress func(PublishProgress)
+	// DecryptPII publishes pii fields decrypted, as reads return them.
+	// Events leave the store, and brokers keep them, so by default those
+	// fields are published as stored, encrypted with the store's field
+	// encryption or namespace keys, and only consumers holding the keys
+	// can read them. Set it only for brokers trusted with the plaintext.
+	DecryptPII bool
+}
+
+type PublishProgress struct {
+	Name string `json:"name"`
+	// Cursor is that of the last change published.
+	Cursor    string `json:"cursor"`
+	Published int64  `json:"published"`
+	// DeadLettered counts the entries that could not be decoded and went
+	// to the dead-letter stream instead, see PublishChanges and RelayOutbox.
+	DeadLettered int64 `json:"dead_lettered,omitempty"`
+}
+
+func publisherCursorKey(name string) string {
+	return fmt.Sprintf("publisher:%s", name)
+}
+
+// publisherDeadLetterKey is the stream the publisher name moves changes it
+// cannot decode to, with the fields they had in the feed plus "change_id",
+// their cursor, and "error".
+func publisherDeadLetterKey(name string) string {
+	return fmt.Sprintf("dead:publisher:%s", name)
+}
+
+// PublishChanges follows the change feed until ctx ends or sink fails,
+// handing the changes to sink as ChangeEvents in batches of up to 100. The
+// position of the last batch sink acknowledged is saved in Redis, so
+// running PublishChanges again under the same name resumes after it:
+// every change is delivered at least once, and those of a batch that was
+// published but not saved are delivered again. A new publisher starts with
+// the next change. Changes that fall out of the feed, see WithChangeFeed,
+// while the publisher is stopped are lost. Changes that cannot be decoded,
+// e.g. signed with another integrity key, would stop every restart at the
+// same place; they are copied to the stream "dead:publisher:<name>"
+// instead, counted in PublishProgress.DeadLettered, and the saved position
+// moves past them.
+func (db *RedisObjectDB) PublishChanges(ctx context.Context, sink ChangeSink, opts PublishOptions) error {
+	if opts.Name == "" {
+		return errors.New("publisher needs a name")
+	}
+	kind := opts.Kind
+	if kind != "" {
+		name, ok := lookupKind(kind)
+		if !ok {
+			return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+		}
+		kind = name
+	}
+
+	key := publisherCursorKey(opts.Name)
+	cursor, err := db.redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// Saved right away, so changes made before the first batch are not
+		// skipped by a restart.
+		if cursor, err = db.lastChange(ctx); err == nil {
+			err = db.redisClient.Set(ctx, key, cursor, 0).Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	progress := PublishProgress{Name: opts.Name, Cursor: cursor}
+	it := db.Watch(ctx, kind, cursor)
+	it.sealed = !opts.DecryptPII
+	it.skipUndecodable = true
+	for it.Next(ctx) {
+		var batch []ChangeEvent
+		var dead []Change
+		for n := 0; ; n++ {
+			if change := it.Change(); change.undecodable != nil {
+				dead = append(dead, change)
+			} else {
+				batch = append(batch, changeEvent(opts.Name, change))
+			}
+			if len(it.buf) == 0 || n+1 >= watchBatchSize {
+				break
+			}
+			it.Next(ctx)
+		}
+		last := it.Change().Cursor
+
+		if len(dead) > 0 {
+			_, err := db.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, change := range dead {
+					queueDeadLetter(ctx, pipe, publisherDeadLetterKey(opts.Name), "change_id", change.raw, change.undecodable)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			progress.DeadLettered += int64(len(dead))
+		}
+		if len(batch) > 0 {
+			if err := sink.Publish(ctx, batch); err != nil {
+				return err
+			}
+			progress.Published += int64(len(batch))
+		}
+		progress.Cursor = last
+		if err := db.redisClient.Set(ctx, key, progress.Cursor, 0).Err(); err != nil {
+			return err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	return it.Err()
+}
+
+func changeEvent(name string, change Change) ChangeEvent {
+	// Stream IDs start with the commit time in milliseconds.
+	committed, _, _ := strings.Cut(change.Cursor, "-")
+	ts, _ := strconv.ParseInt(committed, 10, 64)
+
+	event := ChangeEvent{
+		After: change.Object,
+		Source: ChangeSource{
+			Connector: "objectdb",
+			Name:      name,
+			TsMs:      ts,
+			Kind:      change.Kind,
+			Table:     shortKindName(change.Kind),
+			ID:        change.ID,
+			Cursor:    change.Cursor,
+		},
+		Op:   ChangeOpUpdate,
+		TsMs: time.Now().UnixMilli(),
+	}
+	switch {
+	case change.Type == ChangeDelete:
+		event.Op = ChangeOpDelete
+	case change.Object != nil && change.Object.GetObjectMeta().Version <= 1:
+		event.Op = ChangeOpCreate
+	}
+
+	return event
+}
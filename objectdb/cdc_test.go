@@ -0,0 +1,96 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type recordingSink struct {
+	events []ChangeEvent
+}
+
+func (s *recordingSink) Publish(ctx context.Context, events []ChangeEvent) error {
+	s.events = append(s.events, events...)
+	return nil
+}
+
+// publishFor runs PublishChanges for d and returns what it published.
+func publishFor(t *testing.T, db *RedisObjectDB, name string, d time.Duration) (*recordingSink, PublishProgress) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	sink := &recordingSink{}
+	var progress PublishProgress
+	err := db.PublishChanges(ctx, sink, PublishOptions{Name: name, OnProgress: func(p PublishProgress) { progress = p }})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("PublishChanges: %v", err)
+	}
+
+	return sink, progress
+}
+
+func TestPublishChangesDeadLettersUndecodableChanges(t *testing.T) {
+	db, _ := newTestDB(t, WithChangeFeed(0))
+	ctx := context.Background()
+
+	// Registers the publisher, so it starts with the changes below.
+	publishFor(t, db, "test", 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: fmt.Sprint(i), Name: fmt.Sprint("p", i)}}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			bad := map[string]interface{}{"type": string(ChangePut), "kind": (&Person{}).GetKind(), "id": "bad", "value": "\x00\x7f"}
+			if err := db.redisClient.XAdd(ctx, &redis.XAddArgs{Stream: changeStreamKey, Values: bad}).Err(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	sink, progress := publishFor(t, db, "test", 200*time.Millisecond)
+	if len(sink.events) != 3 || progress.Published != 3 || progress.DeadLettered != 1 {
+		t.Fatalf("published %d events, progress %+v; want 3 published and 1 dead-lettered", len(sink.events), progress)
+	}
+	dead, err := db.redisClient.XRange(ctx, publisherDeadLetterKey("test"), "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Values["id"] != "bad" || dead[0].Values["error"] == "" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	// The saved cursor is past the bad change, so a restart neither stops
+	// at it nor dead-letters it again.
+	sink, progress = publishFor(t, db, "test", 200*time.Millisecond)
+	if len(sink.events) != 0 || progress.DeadLettered != 0 {
+		t.Fatalf("restart published %d events, progress %+v", len(sink.events), progress)
+	}
+}
+
+func TestPublishChangesSavesCursorPastTrailingDeadLetter(t *testing.T) {
+	db, _ := newTestDB(t, WithChangeFeed(0))
+	ctx := context.Background()
+	publishFor(t, db, "test", 50*time.Millisecond)
+
+	bad := map[string]interface{}{"type": string(ChangePut), "kind": (&Person{}).GetKind(), "id": "bad", "value": "\x00\x7f"}
+	id, err := db.redisClient.XAdd(ctx, &redis.XAddArgs{Stream: changeStreamKey, Values: bad}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, progress := publishFor(t, db, "test", 200*time.Millisecond)
+	if progress.DeadLettered != 1 || progress.Cursor != id {
+		t.Fatalf("progress = %+v, want 1 dead-lettered and cursor %s", progress, id)
+	}
+	saved, err := db.redisClient.Get(ctx, publisherCursorKey("test")).Result()
+	if err != nil || saved != id {
+		t.Fatalf("saved cursor = %q, %v; want %s", saved, err, id)
+	}
+}
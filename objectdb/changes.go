@@ -0,0 +1,217 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The change feed is a Redis stream that every Store and delete appends to
+// in the same MULTI as the write itself, so watchers see exactly the writes
+// that happened, in commit order.
+const changeStreamKey = "changes"
+
+const (
+	defaultChangeFeedMaxLen = 10000
+	watchPollInterval       = time.Second
+	watchBatchSize          = 100
+)
+
+type ChangeType string
+
+const (
+	ChangePut    ChangeType = "put"
+	ChangeDelete ChangeType = "delete"
+)
+
+// Change is one entry of the change feed.
+type Change struct {
+	// Cursor identifies the entry; Watch resumes right after it.
+	Cursor string
+	Type   ChangeType
+	Kind   string
+	ID     string
+	// Object is the object as written, or nil for deletes.
+	Object Object
+
+	// undecodable is why the entry failed to decode, and raw the entry,
+	// for iterators with skipUndecodable.
+	undecodable error
+	raw         redis.XMessage
+}
+
+// WithChangeFeed records every write in the change feed read by Watch. The
+// feed keeps roughly the last maxLen changes; 0 keeps 10000.
+func WithChangeFeed(maxLen int64) Option {
+	return func(db *RedisObjectDB) {
+		if maxLen <= 0 {
+			maxLen = defaultChangeFeedMaxLen
+		}
+		db.changeFeedMaxLen = maxLen
+	}
+}
+
+// recordChange queues a change feed entry, an outbox entry with WithOutbox
+// and a history entry with WithHistory on pipe. value is the stored value,
+// or nil for deletes.
+func (db *RedisObjectDB) recordChange(ctx context.Context, pipe redis.Pipeliner, typ ChangeType, kind, id string, value []byte) {
+	if db.changeFeedMaxLen == 0 && !db.outbox && !db.history {
+		return
+	}
+	if db.history {
+		db.queueHistory(ctx, pipe, typ, kind, id, value)
+	}
+
+	values := map[string]interface{}{"type": string(typ), "kind": kind, "id": id}
+	if value != nil {
+		values["value"] = value
+	}
+	if db.changeFeedMaxLen > 0 {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: changeStreamKey,
+			MaxLen: db.changeFeedMaxLen,
+			Approx: true,
+			Values: values,
+		})
+	}
+	if db.outbox {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: outboxStreamKey, Values: values})
+	}
+}
+
+// ChangeIterator follows the change feed. Next blocks until a change
+// arrives or ctx is done:
+//
+//	it := db.Watch(ctx, "", "")
+//	for it.Next(ctx) {
+//		handle(it.Change())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ChangeIterator struct {
+	db     *RedisObjectDB
+	kind   string
+	cursor string
+	// sealed leaves pii fields encrypted, see decodeChange.
+	sealed bool
+	// skipUndecodable hands out entries that fail to decode as changes
+	// with undecodable set, instead of stopping with an error.
+	skipUndecodable bool
+
+	buf []Change
+	cur Change
+	err error
+}
+
+// Watch follows changes to objects of kind, or to every kind if kind is
+// empty. It starts after cursor, a Change.Cursor from an earlier watch, or
+// with the next change if cursor is empty. Changes older than the feed's
+// length are gone; resuming from such a cursor starts at the oldest
+// change still kept.
+func (db *RedisObjectDB) Watch(ctx context.Context, kind, cursor string) *ChangeIterator {
+	return &ChangeIterator{db: db, kind: kind, cursor: cursor}
+}
+
+func (it *ChangeIterator) Next(ctx context.Context) bool {
+	if it.err == nil && it.cursor == "" {
+		it.cursor, it.err = it.db.lastChange(ctx)
+	}
+
+	for len(it.buf) == 0 {
+		if it.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		streams, err := it.db.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{changeStreamKey, it.cursor},
+			Count:   watchBatchSize,
+			Block:   watchPollInterval,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				it.cursor = msg.ID
+				change, err := it.db.decodeChange(msg, it.sealed)
+				if err != nil && !it.skipUndecodable {
+					it.err = fmt.Errorf("change %s: %w", msg.ID, err)
+					break
+				}
+				if err != nil {
+					change.undecodable, change.raw = fmt.Errorf("change %s: %w", msg.ID, err), msg
+				}
+				if it.kind == "" || change.Kind == it.kind {
+					it.buf = append(it.buf, change)
+				}
+			}
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Change returns the change Next moved to.
+func (it *ChangeIterator) Change() Change {
+	return it.cur
+}
+
+func (it *ChangeIterator) Err() error {
+	return it.err
+}
+
+// lastChange returns the ID of the newest change, or "0-0" for an empty
+// feed, so that a watch without a cursor starts with the next change.
+func (db *RedisObjectDB) lastChange(ctx context.Context) (string, error) {
+	msgs, err := db.redisClient.XRevRangeN(ctx, changeStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0-0", nil
+	}
+
+	return msgs[0].ID, nil
+}
+
+// decodeChange decodes a change feed or outbox entry. With sealed, pii
+// fields are left as stored, encrypted when the store encrypts them, for
+// changes that leave the process. Entries whose value fails to decode come
+// back without their object, next to the error.
+func (db *RedisObjectDB) decodeChange(msg redis.XMessage, sealed bool) (Change, error) {
+	field := func(name string) string {
+		s, _ := msg.Values[name].(string)
+		return s
+	}
+
+	change := Change{
+		Cursor: msg.ID,
+		Type:   ChangeType(field("type")),
+		Kind:   field("kind"),
+		ID:     field("id"),
+	}
+	if value, ok := msg.Values["value"].(string); ok && change.Type == ChangePut {
+		decode := db.decode
+		if sealed {
+			decode = db.decodeSealed
+		}
+		object, err := decode(change.Kind, []byte(value))
+		if err != nil {
+			return change, err
+		}
+		change.Object = object
+	}
+
+	return change, nil
+}
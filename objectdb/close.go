@@ -0,0 +1,29 @@
+package objectdb
+
+import "context"
+
+// goBackground runs fn on its own goroutine with a context that Close
+// cancels, and makes Close wait for fn to return.
+func (db *RedisObjectDB) goBackground(fn func(ctx context.Context)) {
+	db.workers.Add(1)
+	go func() {
+		defer db.workers.Done()
+		fn(db.lifetime)
+	}()
+}
+
+// Close stops background goroutines, waits for them to exit and then closes
+// the Redis client. In write-behind mode the buffer is written out first.
+// It is safe to call more than once.
+func (db *RedisObjectDB) Close() error {
+	db.closeOnce.Do(func() {
+		if db.writeBehind != nil {
+			db.stopWriteBehind()
+		}
+		db.cancel()
+		db.workers.Wait()
+		db.closeErr = db.redisClient.Close()
+	})
+
+	return db.closeErr
+}
@@ -0,0 +1,218 @@
+package objectdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec turns objects into the bytes stored in Redis and back. Values are
+// written as a 0x00 marker, the codec's Format byte and the payload, so a
+// store reads values written by any registered codec regardless of the one
+// it writes with.
+type Codec interface {
+	// Format identifies the codec in stored values. It must be unique and
+	// non-zero.
+	Format() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+const (
+	FormatJSON     byte = 0x01
+	FormatMsgpack  byte = 0x02
+	FormatCBOR     byte = 0x03
+	FormatProtobuf byte = 0x04
+)
+
+// valueMarker starts every framed value. No codec's own output begins with
+// 0x00: JSON starts with '{', MessagePack and CBOR maps with bytes of 0x80
+// and above, and protobuf never uses field number 0.
+const valueMarker = 0x00
+
+var codecs = map[byte]Codec{}
+
+func init() {
+	for _, c := range []Codec{JSONCodec{}, MsgpackCodec{}, CBORCodec{}, ProtobufCodec{}} {
+		if err := RegisterCodec(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterCodec makes values written by c readable by every store.
+func RegisterCodec(c Codec) error {
+	if c.Format() == valueMarker || c.Format() == formatHMAC || c.Format() == formatArchived || isCompression(c.Format()) {
+		return fmt.Errorf("codec %T uses reserved format 0x%02x", c, c.Format())
+	}
+	if existing, ok := codecs[c.Format()]; ok {
+		return fmt.Errorf("codec format 0x%02x already registered by %T", c.Format(), existing)
+	}
+
+	codecs[c.Format()] = c
+	return nil
+}
+
+func encodeValue(c Codec, v interface{}) ([]byte, error) {
+	payload, err := c.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{valueMarker, c.Format()}, payload...), nil
+}
+
+// decodeValue splits a stored value into its codec and payload. Values
+// written before framing was introduced carry no header; their codec is
+// guessed from the first byte by legacyCodecFor.
+func decodeValue(data []byte) (Codec, []byte, error) {
+	if len(data) == 0 || data[0] != valueMarker {
+		return legacyCodecFor(data), data, nil
+	}
+	if isSigned(data) {
+		data = data[2+sha256.Size:]
+	} else if len(data) >= 2 && data[1] == formatHMAC {
+		return nil, nil, fmt.Errorf("truncated value signature")
+	}
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("truncated value header")
+	}
+	if data[1] == formatArchived {
+		return nil, nil, fmt.Errorf("value is archived")
+	}
+
+	if isCompression(data[1]) {
+		inner, err := decompress(Compression(data[1]), data[2:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("decompress value: %w", err)
+		}
+		if len(inner) > 1 && inner[0] == valueMarker && isCompression(inner[1]) {
+			return nil, nil, fmt.Errorf("value is compressed twice")
+		}
+		data = inner
+		if len(data) < 2 || data[0] != valueMarker {
+			return nil, nil, fmt.Errorf("compressed value has no header")
+		}
+	}
+
+	c, ok := codecs[data[1]]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown codec format 0x%02x", data[1])
+	}
+
+	return c, data[2:], nil
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Format() byte {
+	return FormatJSON
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec reads the json struct tags, so field names are the same in
+// both formats and one set of tags serves both.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Format() byte {
+	return FormatMsgpack
+}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// CBORCodec writes time.Time as tagged RFC 3339 strings with nanoseconds
+// and the original offset, so BirthDate and the ObjectMeta timestamps come
+// back exactly as they were stored.
+type CBORCodec struct{}
+
+func (CBORCodec) Format() byte {
+	return FormatCBOR
+}
+
+var (
+	cborEnc = mustCBOREncMode(cbor.EncOptions{
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	})
+	cborDec = mustCBORDecMode(cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+	})
+)
+
+func (CBORCodec) Marshal(v interface{}) ([]byte, error) {
+	return cborEnc.Marshal(v)
+}
+
+func (CBORCodec) Unmarshal(data []byte, v interface{}) error {
+	return cborDec.Unmarshal(data, v)
+}
+
+func mustCBOREncMode(opts cbor.EncOptions) cbor.EncMode {
+	mode, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return mode
+}
+
+func mustCBORDecMode(opts cbor.DecOptions) cbor.DecMode {
+	mode, err := opts.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return mode
+}
+
+// legacyCodecFor picks the codec an unframed value was written with from
+// its first byte: '{' for JSON, a fixmap, map16 or map32 marker for MessagePack, a
+// major type 5 (map) header for CBOR, and otherwise a protobuf field tag,
+// all of which are below 0x80 for the low-numbered fields our messages
+// start with.
+func legacyCodecFor(data []byte) Codec {
+	if len(data) == 0 {
+		return JSONCodec{}
+	}
+
+	switch b := data[0]; {
+	case b == '{':
+		return JSONCodec{}
+	case b >= 0x80 && b <= 0x8f, b == 0xde, b == 0xdf:
+		return MsgpackCodec{}
+	case b >= 0xa0 && b <= 0xbf:
+		return CBORCodec{}
+	case b < 0x80:
+		return ProtobufCodec{}
+	}
+
+	return JSONCodec{}
+}
@@ -0,0 +1,109 @@
+package objectdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression byte
+
+// Compressed values are framed like codec output, with the compression in
+// the format byte and a complete framed value as the payload, so reads
+// detect compression the same way they detect codecs.
+const (
+	CompressionNone Compression = 0
+	CompressionGzip Compression = 0x10
+	CompressionZstd Compression = 0x11
+)
+
+const defaultCompressionThreshold = 1024
+
+// maxDecompressedSize bounds what one stored value may inflate to, so a
+// corrupt or hostile value cannot exhaust memory on read.
+const maxDecompressedSize = 64 << 20
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+)
+
+// WithCompression compresses values whose encoded size is at least
+// threshold bytes; smaller values rarely shrink enough to be worth it. A
+// threshold of 0 uses 1 KiB.
+func WithCompression(c Compression, threshold int) Option {
+	return func(db *RedisObjectDB) {
+		if threshold <= 0 {
+			threshold = defaultCompressionThreshold
+		}
+		db.compression = c
+		db.compressionThreshold = threshold
+	}
+}
+
+// encode frames v with c, compresses the result if the store is configured
+// to and v is large enough, and signs it if the store has an integrity key.
+func (db *RedisObjectDB) encode(c Codec, v interface{}) ([]byte, error) {
+	value, err := encodeValue(c, v)
+	if err != nil {
+		return nil, err
+	}
+	if db.compression == CompressionNone || len(value) < db.compressionThreshold {
+		return db.sign(value), nil
+	}
+
+	compressed, err := compress(db.compression, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.sign(append([]byte{valueMarker, byte(db.compression)}, compressed...)), nil
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+
+	return nil, fmt.Errorf("unknown compression 0x%02x", byte(c))
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+		if err == nil && len(data) > maxDecompressedSize {
+			return nil, fmt.Errorf("value inflates to more than %d bytes", maxDecompressedSize)
+		}
+		return data, err
+
+	case CompressionZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression 0x%02x", byte(c))
+}
+
+func isCompression(format byte) bool {
+	return format == byte(CompressionGzip) || format == byte(CompressionZstd)
+}
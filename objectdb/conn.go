@@ -0,0 +1,55 @@
+package objectdb
+
+import (
+	"crypto/tls"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ConnConfig describes a connection to a standalone Redis server. Managed
+// offerings (Elasticache, Azure Cache, Upstash) usually need TLS and either a
+// password or an ACL username/password pair.
+type ConnConfig struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+
+	// TLS enables TLS with sane defaults when TLSConfig is nil.
+	TLS       bool
+	TLSConfig *tls.Config
+}
+
+func NewRedisObjectDBFromConfig(cfg ConnConfig, opts ...Option) *RedisObjectDB {
+	return NewRedisObjectDB(redis.NewClient(&redis.Options{
+		Addr:      cfg.Addr,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		DB:        cfg.DB,
+		TLSConfig: tlsConfig(cfg.TLS, cfg.TLSConfig),
+	}), opts...)
+}
+
+// NewRedisObjectDBFromURL accepts redis:// and rediss:// URLs, which is the
+// form most hosted providers hand out, including credentials.
+func NewRedisObjectDBFromURL(url string, opts ...Option) (*RedisObjectDB, error) {
+	clientOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedisObjectDB(redis.NewClient(clientOpts), opts...), nil
+}
+
+// tlsConfig leaves ServerName empty so it is taken from whichever address
+// is being dialed; with Sentinel that is not known up front.
+func tlsConfig(enabled bool, cfg *tls.Config) *tls.Config {
+	if cfg != nil {
+		return cfg
+	}
+	if !enabled {
+		return nil
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
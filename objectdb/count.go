@@ -0,0 +1,190 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// countInterScript counts the members the sets in KEYS have in common
+// without sending them back.
+var countInterScript = redis.NewScript(`return #redis.call("SINTER", unpack(KEYS))`)
+
+// Count returns how many objects of kind match all filters, like
+// len(Query(...)) but answered from indexes where it can be:
+//
+//   - equality on a label, e.g. labels.tier=db, or on a `ref`-tagged field,
+//     e.g. owner_id=42, intersects the label and ref indexes;
+//   - a single comparison on a range-indexed field, e.g. version>=2, is a
+//     ZCOUNT. Times only count exactly with >= or < and whole microseconds,
+//     as the index truncates them.
+//
+// Objects are only read for whatever filters are left, and then just the
+// candidates filterCandidates finds.
+func (db *RedisObjectDB) Count(ctx context.Context, kind string, filters ...Filter) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx, "Count")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	if err := checkFilters(kind, filters); err != nil {
+		return 0, err
+	}
+
+	sets := []string{kindIndexKey(kind)}
+	var rest []Filter
+	for _, f := range filters {
+		if key, ok := setIndexFor(kind, f); ok {
+			sets = append(sets, key)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+
+	switch {
+	case len(rest) == 0 && len(sets) == 1:
+		return db.redisClient.SCard(ctx, sets[0]).Result()
+	case len(rest) == 0:
+		return countInterScript.Run(ctx, db.redisClient, sets).Int64()
+	case len(rest) == 1 && len(sets) == 1:
+		if key, min, max, ok := rangeCountFor(kind, rest[0]); ok {
+			return db.redisClient.ZCount(ctx, key, min, max).Result()
+		}
+	}
+
+	keys, err := db.filterCandidates(ctx, kind, filters)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for len(keys) > 0 {
+		batch := len(keys)
+		if batch > db.fetchBatchSize {
+			batch = db.fetchBatchSize
+		}
+		err := db.fetchObjects(ctx, keys[:batch], func(object Object) error {
+			if matches(object, filters) {
+				n++
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		keys = keys[batch:]
+	}
+
+	return n, nil
+}
+
+// filterCandidates returns the keys of the objects of kind that may match
+// filters: those in every set index an equality filter has, or else in the
+// range some filters constrain, or else every object in the kind index.
+// They still have to be filtered.
+func (db *RedisObjectDB) filterCandidates(ctx context.Context, kind string, filters []Filter) ([]string, error) {
+	sets := []string{kindIndexKey(kind)}
+	for _, f := range filters {
+		if key, ok := setIndexFor(kind, f); ok {
+			sets = append(sets, key)
+		}
+	}
+	if len(sets) > 1 {
+		return db.redisClient.SInter(ctx, sets...).Result()
+	}
+
+	keys, ok, err := db.rangeCandidates(ctx, kind, filters)
+	if ok || err != nil {
+		return keys, err
+	}
+
+	return db.redisClient.SMembers(ctx, kindIndexKey(kind)).Result()
+}
+
+// setIndexFor returns the set index holding exactly the objects of kind
+// matching f, if there is one.
+func setIndexFor(kind string, f Filter) (string, bool) {
+	if f.Op != "" && f.Op != OpEq {
+		return "", false
+	}
+	info := kinds[kind]
+	value := fmt.Sprint(f.Value)
+
+	if prefix, key, ok := strings.Cut(f.Field, "."); ok {
+		labels, found := info.fields["labels"]
+		field, isMap := info.fields[prefix]
+		if found && isMap && reflect.DeepEqual(labels.index, field.index) {
+			return labelIndexKey(kind, key, value), true
+		}
+		return "", false
+	}
+
+	field, ok := info.fields[f.Field]
+	if !ok || len(field.index) != 1 || field.typ.Kind() != reflect.String || value == "" {
+		return "", false
+	}
+	t := reflect.TypeOf(info.newObject())
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	tag, ok := t.Field(field.index[0]).Tag.Lookup("ref")
+	if !ok {
+		return "", false
+	}
+	target, ok := lookupKind(tag)
+	if !ok {
+		return "", false
+	}
+
+	return refIndexKey(target, value), true
+}
+
+// rangeCountFor returns the range index and ZCOUNT bounds counting exactly
+// the objects of kind matching f, if f allows that.
+func rangeCountFor(kind string, f Filter) (key, min, max string, ok bool) {
+	info := kinds[kind]
+	field, found := info.fields[f.Field]
+	if !found {
+		return "", "", "", false
+	}
+	r, found := info.rangeIndexOn(field)
+	if !found {
+		return "", "", "", false
+	}
+	score, valid := rangeScore(r.typ, f.Value)
+	if !valid {
+		return "", "", "", false
+	}
+	if r.typ == timeType {
+		t, _ := toTime(f.Value)
+		if t.Nanosecond()%1000 != 0 || (f.Op != OpGte && f.Op != OpLt) {
+			return "", "", "", false
+		}
+	}
+
+	s := strconv.FormatFloat(score, 'g', -1, 64)
+	min, max = "-inf", "+inf"
+	switch f.Op {
+	case "", OpEq:
+		min, max = s, s
+	case OpLt:
+		max = "(" + s
+	case OpLte:
+		max = s
+	case OpGt:
+		min = "(" + s
+	case OpGte:
+		min = s
+	}
+
+	return rangeIndexKey(kind, r.name), min, max, true
+}
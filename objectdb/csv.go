@@ -0,0 +1,124 @@
+package objectdb
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ColumnMap maps CSV header names to the field paths they fill, such as
+// "Birthday" to "birth_date" or "Env" to "labels.env". A nil ColumnMap maps
+// every column to the field of the same name.
+type ColumnMap map[string]string
+
+// ImportResult reports how an ImportCSV or ImportNDJSON run went. Rows that
+// failed to parse or validate are listed in Errors and were not stored.
+type ImportResult struct {
+	Imported int
+	Errors   []RowError
+}
+
+// RowError is the reason a single row was rejected. Row counts data rows
+// from 1: CSV rows after the header, or NDJSON lines.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// ImportCSV reads objects of kind from r, one per row after the header row,
+// and stores them in batches of WithFetchBatchSize rows with one round trip
+// each. Columns mapping to no field are an error before anything is
+// stored; empty cells leave their field unset, and rows without an ID get a
+// generated one. Bad rows are reported in the result and do not stop the
+// import; the returned error is only set when reading or writing failed.
+func (db *RedisObjectDB) ImportCSV(ctx context.Context, kind string, r io.Reader, mapping ColumnMap) (ImportResult, error) {
+	var result ImportResult
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return result, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	paths := make([]string, len(header))
+	for i, column := range header {
+		path := column
+		if mapping != nil {
+			path, ok = mapping[column]
+			if !ok {
+				continue
+			}
+		}
+		if !hasField(kind, path) {
+			return result, fmt.Errorf("column '%s': %s has no field '%s'", column, kind, path)
+		}
+		paths[i] = path
+	}
+
+	b := &batchImporter{db: db}
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			b.fail(row, err)
+			continue
+		}
+		if err != nil {
+			return b.result, err
+		}
+
+		object, err := objectFromRecord(kind, paths, record)
+		if err != nil {
+			b.fail(row, err)
+			continue
+		}
+
+		if err := b.add(ctx, row, object); err != nil {
+			return b.result, err
+		}
+	}
+
+	err = b.flush(ctx)
+	return b.result, err
+}
+
+func objectFromRecord(kind string, paths, record []string) (Object, error) {
+	object := kinds[kind].newObject()
+	for i, value := range record {
+		if i >= len(paths) || paths[i] == "" || value == "" {
+			continue
+		}
+		if err := setFieldString(object, paths[i], value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ensureID(object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
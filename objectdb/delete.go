@@ -0,0 +1,112 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrRestricted is returned by DeleteObject under DeleteRestrict when other
+// objects still reference the one being deleted.
+var ErrRestricted = errors.New("object is still referenced")
+
+type DeleteOption interface {
+	applyDelete(*deleteOptions)
+}
+
+type deleteOptions struct {
+	policy DeletePolicy
+	dryRun *DryRunResult
+	access *AccessPolicy
+}
+
+// DeletePolicy decides what happens to objects whose `ref` fields point at
+// a deleted object, much like ON DELETE for foreign keys. Without a policy
+// DeleteObject leaves dependents alone.
+type DeletePolicy int
+
+const (
+	// DeleteCascade deletes dependents as well, recursively.
+	DeleteCascade DeletePolicy = iota + 1
+	// DeleteRestrict refuses the delete with ErrRestricted.
+	DeleteRestrict
+	// DeleteOrphan keeps dependents but clears their reference.
+	DeleteOrphan
+)
+
+func (p DeletePolicy) applyDelete(o *deleteOptions) {
+	o.policy = p
+}
+
+// handleDependents applies the delete policy to everything referencing
+// object. seen guards against reference cycles while cascading.
+func (db *RedisObjectDB) handleDependents(ctx context.Context, object Object, options deleteOptions, seen map[string]bool) error {
+	if options.policy == 0 {
+		return nil
+	}
+	seen[objectKey(object.GetKind(), object.GetID())] = true
+
+	dependents, err := db.dependents(ctx, object.GetKind(), object.GetID())
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range dependents {
+		key := objectKey(dep.object.GetKind(), dep.object.GetID())
+		if seen[key] {
+			continue
+		}
+
+		switch options.policy {
+		case DeleteRestrict:
+			return fmt.Errorf("%s '%s' is referenced by %s '%s': %w",
+				object.GetKind(), object.GetID(), dep.object.GetKind(), dep.object.GetID(), ErrRestricted)
+
+		case DeleteCascade:
+			err = db.handleDependents(ctx, dep.object, options, seen)
+			if err != nil {
+				return err
+			}
+			err = db.deleteOrPlan(ctx, dep.object, options)
+
+		case DeleteOrphan:
+			reflect.ValueOf(dep.object).Elem().FieldByName(dep.field).SetString("")
+			if options.dryRun != nil {
+				err = db.Store(ctx, dep.object, DryRun(options.dryRun))
+			} else {
+				err = db.Store(ctx, dep.object)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type dependent struct {
+	object Object
+	field  string
+}
+
+// dependents finds the objects holding a reference to kind/id through the
+// reverse reference index.
+func (db *RedisObjectDB) dependents(ctx context.Context, kind, id string) ([]dependent, error) {
+	referrers, err := db.ListReferrers(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []dependent
+	for _, object := range referrers {
+		for _, ref := range objectReferences(object) {
+			if ref.Kind == kind && ref.ID == id {
+				deps = append(deps, dependent{object: object, field: ref.Field})
+			}
+		}
+	}
+
+	return deps, nil
+}
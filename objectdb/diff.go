@@ -0,0 +1,101 @@
+package objectdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Ops of a FieldChange.
+const (
+	FieldAdded   = "added"
+	FieldRemoved = "removed"
+	FieldChanged = "changed"
+)
+
+// FieldChange is one field that differs between two revisions of an object.
+type FieldChange struct {
+	// Path is the JSON path of the field, with nested objects and map
+	// entries joined by dots as in filters, e.g. "labels.env".
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	// Before and After are the JSON values of the field, nil where it is
+	// absent.
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Diff returns the fields that differ from a to b, ordered by path. Either
+// may be nil, for an object being created or deleted; otherwise both must
+// be of the same kind. Objects are compared as they encode to JSON, down to
+// the leaves of nested objects and maps, while lists are compared whole.
+// Metadata is compared like any other field, so two revisions differ at
+// least in "version" and "updated_at".
+func Diff(a, b Object) ([]FieldChange, error) {
+	if a != nil && b != nil && a.GetKind() != b.GetKind() {
+		return nil, fmt.Errorf("cannot diff %s '%s' against %s '%s'", a.GetKind(), a.GetID(), b.GetKind(), b.GetID())
+	}
+
+	before, err := diffFields(a)
+	if err != nil {
+		return nil, err
+	}
+	after, err := diffFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FieldChange
+	diffMaps("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes, nil
+}
+
+// diffFields returns the JSON fields of object, keeping numbers as written.
+func diffFields(object Object) (map[string]interface{}, error) {
+	if object == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func diffMaps(prefix string, before, after map[string]interface{}, changes *[]FieldChange) {
+	for key, old := range before {
+		path := prefix + key
+		new, ok := after[key]
+		if !ok {
+			*changes = append(*changes, FieldChange{Path: path, Op: FieldRemoved, Before: old})
+			continue
+		}
+
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := new.(map[string]interface{})
+		switch {
+		case oldIsMap && newIsMap:
+			diffMaps(path+".", oldMap, newMap, changes)
+		case !reflect.DeepEqual(old, new):
+			*changes = append(*changes, FieldChange{Path: path, Op: FieldChanged, Before: old, After: new})
+		}
+	}
+
+	for key, new := range after {
+		if _, ok := before[key]; !ok {
+			*changes = append(*changes, FieldChange{Path: prefix + key, Op: FieldAdded, After: new})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package objectdb
+
+import "context"
+
+// DryRunResult lists the changes a dry-run Store or DeleteObject would
+// have made, in the order it would have made them.
+type DryRunResult struct {
+	Changes []PlannedChange `json:"changes"`
+}
+
+// PlannedChange is one write a dry run skipped. For puts, Created tells a
+// new object from an update, and Version and Size are those of the value
+// that would have been stored.
+type PlannedChange struct {
+	Type    ChangeType `json:"type"`
+	Kind    string     `json:"kind"`
+	ID      string     `json:"id"`
+	Created bool       `json:"created,omitempty"`
+	Version int64      `json:"version,omitempty"`
+	Size    int        `json:"size,omitempty"`
+}
+
+type dryRun struct {
+	result *DryRunResult
+}
+
+func (d dryRun) applyStore(o *storeOptions) {
+	o.dryRun = d.result
+}
+
+func (d dryRun) applyDelete(o *deleteOptions) {
+	o.dryRun = d.result
+}
+
+// DryRun makes Store or DeleteObject run every check and encode every
+// value, including delete policies and the writes they cause, without
+// writing anything. The changes that would have been made are appended to
+// result. The object passed to Store is left as it was.
+func DryRun(result *DryRunResult) interface {
+	StoreOption
+	DeleteOption
+} {
+	return dryRun{result: result}
+}
+
+// planStore records what Store would do with an already validated object.
+func (db *RedisObjectDB) planStore(ctx context.Context, object Object, result *DryRunResult) error {
+	previous, err := db.previousObject(ctx, object.GetKind(), object.GetID())
+	if err != nil {
+		return err
+	}
+
+	clone, err := cloneObject(object)
+	if err != nil {
+		return err
+	}
+	value, err := db.prepareWrite(ctx, clone, previous)
+	if err != nil {
+		return err
+	}
+
+	result.Changes = append(result.Changes, PlannedChange{
+		Type:    ChangePut,
+		Kind:    object.GetKind(),
+		ID:      object.GetID(),
+		Created: previous == nil,
+		Version: clone.GetObjectMeta().Version,
+		Size:    len(value),
+	})
+	return nil
+}
+
+// deleteOrPlan removes object, or records its removal in a dry run.
+func (db *RedisObjectDB) deleteOrPlan(ctx context.Context, object Object, options deleteOptions) error {
+	if options.dryRun == nil {
+		return db.removeObject(ctx, object)
+	}
+
+	options.dryRun.Changes = append(options.dryRun.Changes, PlannedChange{
+		Type: ChangeDelete,
+		Kind: object.GetKind(),
+		ID:   object.GetID(),
+	})
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package election picks one leader among instances sharing an object
+// store, for singleton background jobs such as index rebuilds:
+//
+//	e := election.New(db, "reindex", hostname,
+//		election.OnElected(func(ctx context.Context) { rebuild(ctx) }))
+//	go e.Campaign(ctx)
+//
+// Leadership is a lock in the store held with a TTL and renewed while the
+// leader is alive; if it dies, another candidate takes over once the TTL
+// runs out.
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go-assignment/objectdb"
+)
+
+const defaultTTL = 15 * time.Second
+
+// Election is one candidate's view of a named election.
+type Election struct {
+	db        *objectdb.RedisObjectDB
+	name      string
+	candidate string
+	ttl       time.Duration
+
+	onElected      func(ctx context.Context)
+	onLeaderChange func(leader string)
+
+	mu     sync.Mutex
+	lease  *objectdb.Lease
+	leader string
+}
+
+// Option configures an Election at construction time.
+type Option func(*Election)
+
+// WithTTL sets how long leadership outlives a leader that stopped renewing
+// it. Leaders renew every third of the TTL.
+func WithTTL(d time.Duration) Option {
+	return func(e *Election) {
+		if d > 0 {
+			e.ttl = d
+		}
+	}
+}
+
+// OnElected sets a function run while this candidate leads. Its context is
+// cancelled when leadership is lost or Campaign returns, and the candidate
+// does not campaign again until fn has returned.
+func OnElected(fn func(ctx context.Context)) Option {
+	return func(e *Election) {
+		e.onElected = fn
+	}
+}
+
+// OnLeaderChange sets a function called whenever this candidate sees a new
+// leader, or "" when there is none.
+func OnLeaderChange(fn func(leader string)) Option {
+	return func(e *Election) {
+		e.onLeaderChange = fn
+	}
+}
+
+func New(db *objectdb.RedisObjectDB, name, candidate string, opts ...Option) *Election {
+	e := &Election{db: db, name: name, candidate: candidate, ttl: defaultTTL}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// lockID is the object ID the election's lock is keyed by.
+func (e *Election) lockID() string {
+	return "election:" + e.name
+}
+
+// IsLeader reports whether this candidate currently leads.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lease != nil
+}
+
+// Leader returns the last leader this candidate saw, or "".
+func (e *Election) Leader() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.leader
+}
+
+// Campaign takes part in the election until ctx ends, then steps down if
+// leading. Store errors are retried on the next round rather than
+// returned.
+func (e *Election) Campaign(ctx context.Context) error {
+	interval := e.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var stopJob func()
+	defer func() {
+		if stopJob != nil {
+			stopJob()
+		}
+		e.resign()
+	}()
+
+	for {
+		elected, lost := e.round(ctx)
+		if elected && e.onElected != nil {
+			stopJob = e.runJob(ctx)
+		}
+		if lost && stopJob != nil {
+			stopJob()
+			stopJob = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// round renews or tries to take leadership once, and reports whether this
+// candidate just became leader or just lost it.
+func (e *Election) round(ctx context.Context) (elected, lost bool) {
+	e.mu.Lock()
+	lease := e.lease
+	e.mu.Unlock()
+
+	if lease != nil {
+		err := lease.Refresh(ctx, e.ttl)
+		if err == nil {
+			return false, false
+		}
+		// A failed refresh may still have kept the lock; only give up
+		// once the lease can no longer be valid.
+		if !errors.Is(err, objectdb.ErrLeaseLost) && time.Now().Before(lease.Expires()) {
+			return false, false
+		}
+
+		e.mu.Lock()
+		e.lease = nil
+		e.mu.Unlock()
+		lost = true
+	} else {
+		lease, err := e.db.TryLockAs(ctx, e.lockID(), e.candidate, e.ttl)
+		if err == nil {
+			e.mu.Lock()
+			e.lease = lease
+			e.mu.Unlock()
+			e.observe(e.candidate)
+			return true, false
+		}
+	}
+
+	leader, err := e.db.LockHolder(ctx, e.lockID())
+	if err == nil {
+		e.observe(leader)
+	}
+
+	return false, lost
+}
+
+// runJob starts onElected and returns a function that stops it and waits.
+func (e *Election) runJob(ctx context.Context) func() {
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.onElected(jobCtx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (e *Election) observe(leader string) {
+	e.mu.Lock()
+	changed := leader != e.leader
+	e.leader = leader
+	e.mu.Unlock()
+
+	if changed && e.onLeaderChange != nil {
+		e.onLeaderChange(leader)
+	}
+}
+
+// resign releases leadership so the next candidate need not wait out the
+// TTL.
+func (e *Election) resign() {
+	e.mu.Lock()
+	lease := e.lease
+	e.lease = nil
+	e.mu.Unlock()
+	if lease == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lease.Release(ctx)
+	e.observe("")
+}
@@ -0,0 +1,316 @@
+package objectdb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldInfo locates a field inside a kind's struct for reflect.FieldByIndex.
+type fieldInfo struct {
+	index []int
+	typ   reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// buildFieldIndex maps every exported field of t to its location. Fields are
+// reachable by their JSON path ("owner_id", "birth_date") and by their Go
+// path ("OwnerID"); fields of nested structs are joined with dots, while
+// embedded structs are flattened the same way encoding/json flattens them.
+func buildFieldIndex(t reflect.Type) map[string]fieldInfo {
+	fields := map[string]fieldInfo{}
+
+	var walk func(t reflect.Type, index []int, jsonPrefix, goPrefix string)
+	walk = func(t reflect.Type, index []int, jsonPrefix, goPrefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+
+			idx := append(append([]int{}, index...), i)
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, idx, jsonPrefix, goPrefix)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			info := fieldInfo{index: idx, typ: f.Type}
+			fields[jsonPrefix+name] = info
+			fields[goPrefix+f.Name] = info
+
+			if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+				walk(f.Type, idx, jsonPrefix+name+".", goPrefix+f.Name+".")
+			}
+		}
+	}
+	walk(t, nil, "", "")
+
+	return fields
+}
+
+// fieldValue reads path from object. A path whose prefix names a map with
+// string keys, such as "labels.env", reads that map entry; other paths of
+// an Unstructured are read from its Fields.
+func fieldValue(object Object, path string) (reflect.Value, bool) {
+	info, ok := kinds[object.GetKind()]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+
+	if f, ok := info.fields[path]; ok {
+		fv, err := v.FieldByIndexErr(f.index)
+		return fv, err == nil
+	}
+
+	for i := strings.LastIndex(path, "."); i > 0; i = strings.LastIndex(path[:i], ".") {
+		f, ok := info.fields[path[:i]]
+		if !ok || f.typ.Kind() != reflect.Map || f.typ.Key().Kind() != reflect.String {
+			continue
+		}
+
+		m, err := v.FieldByIndexErr(f.index)
+		if err != nil || m.IsNil() {
+			return reflect.Value{}, false
+		}
+
+		entry := m.MapIndex(reflect.ValueOf(path[i+1:]).Convert(f.typ.Key()))
+		return entry, entry.IsValid()
+	}
+
+	if u, ok := object.(*Unstructured); ok {
+		if field, ok := u.Get(path); ok && field != nil {
+			return reflect.ValueOf(field), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// hasField reports whether kind has a field or map at path. Unstructured
+// kinds may have any field.
+func hasField(kind, path string) bool {
+	info, ok := kinds[kind]
+	if !ok {
+		return false
+	}
+	if _, ok := info.fields[path]; ok || info.unstructured {
+		return true
+	}
+	if i := strings.Index(path, "."); i > 0 {
+		f, ok := info.fields[path[:i]]
+		return ok && f.typ.Kind() == reflect.Map
+	}
+
+	return false
+}
+
+// compareValues orders a field value against want, converting want to the
+// field's type first. Strings are parsed, so "3" compares with an int field
+// and an RFC 3339 timestamp with a time.Time field. ok is false when the
+// two cannot be compared.
+func compareValues(field reflect.Value, want interface{}) (cmp int, ok bool) {
+	for field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return 0, false
+		}
+		field = field.Elem()
+	}
+
+	if field.Type() == timeType {
+		t, ok := toTime(want)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(field.Interface().(time.Time).UnixNano(), t.UnixNano()), true
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return strings.Compare(field.String(), fmt.Sprint(want)), true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		w, ok := toFloat(want)
+		if !ok {
+			return 0, false
+		}
+		f, _ := toFloat(field.Interface())
+		return compareOrdered(f, w), true
+
+	case reflect.Bool:
+		w, ok := want.(bool)
+		if s, isString := want.(string); isString {
+			parsed, err := strconv.ParseBool(s)
+			w, ok = parsed, err == nil
+		}
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(boolToInt(field.Bool()), boolToInt(w)), true
+	}
+
+	if reflect.DeepEqual(field.Interface(), want) {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func compareOrdered[T int | int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if s, ok := v.(string); ok {
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", t)
+		}
+		return parsed, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// setFieldString parses s into the field at path. A path into a map with
+// string keys, such as "labels.env", sets that entry, creating the map if
+// needed. Times are RFC 3339 timestamps or dates like 2006-01-02.
+func setFieldString(object Object, path, s string) error {
+	info, ok := kinds[object.GetKind()]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, object.GetKind())
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+
+	if f, ok := info.fields[path]; ok {
+		parsed, err := parseString(f.typ, s)
+		if err != nil {
+			return fmt.Errorf("field '%s': %w", path, err)
+		}
+		v.FieldByIndex(f.index).Set(parsed)
+		return nil
+	}
+
+	if i := strings.Index(path, "."); i > 0 {
+		f, ok := info.fields[path[:i]]
+		if ok && f.typ.Kind() == reflect.Map && f.typ.Key().Kind() == reflect.String {
+			parsed, err := parseString(f.typ.Elem(), s)
+			if err != nil {
+				return fmt.Errorf("field '%s': %w", path, err)
+			}
+
+			m := v.FieldByIndex(f.index)
+			if m.IsNil() {
+				m.Set(reflect.MakeMap(f.typ))
+			}
+			m.SetMapIndex(reflect.ValueOf(path[i+1:]).Convert(f.typ.Key()), parsed)
+			return nil
+		}
+	}
+
+	if u, ok := object.(*Unstructured); ok {
+		u.Set(path, s)
+		return nil
+	}
+
+	return fmt.Errorf("%s has no field '%s'", object.GetKind(), path)
+}
+
+func parseString(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t == timeType {
+		parsed, ok := toTime(s)
+		if !ok {
+			var err error
+			parsed, err = time.Parse("2006-01-02", s)
+			if err != nil {
+				return v, fmt.Errorf("'%s' is not a timestamp or date", s)
+			}
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, fmt.Errorf("'%s' is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("'%s' is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("'%s' is not an unsigned integer", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("'%s' is not a number", s)
+		}
+		v.SetFloat(f)
+	default:
+		return v, fmt.Errorf("cannot set a %s from text", t)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,164 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type ForEachOptions struct {
+	// Concurrency is how many calls of fn run at once; zero means one.
+	Concurrency int
+	// Checkpoint names the job. With a name, progress is saved in Redis
+	// after each batch, so calling ForEachObject again with the same kind
+	// and name after a crash, error or cancellation resumes where it
+	// stopped instead of starting over.
+	Checkpoint string
+	// OnProgress is called after each batch.
+	OnProgress func(ForEachProgress)
+}
+
+type ForEachProgress struct {
+	Kind string `json:"kind"`
+	// Position is the key of the last object of the last finished batch.
+	Position  string `json:"position"`
+	Processed int64  `json:"processed"`
+	Done      bool   `json:"done"`
+}
+
+func forEachProgressKey(kind, checkpoint string) string {
+	return fmt.Sprintf("foreach:%s:%s", kind, checkpoint)
+}
+
+// ForEachObject calls fn for every object of kind, in batches of
+// fetchBatchSize read in ID order from the ID index, with up to
+// opts.Concurrency calls in flight. It stops at the first error fn
+// returns, once the calls already started have returned. A batch only
+// counts as finished when fn succeeded for all of it, so a resumed job
+// sees each object at least once and some of the last batch twice; fn
+// should be safe to repeat. Objects created after the job passed their ID
+// are not visited.
+func (db *RedisObjectDB) ForEachObject(ctx context.Context, kind string, fn func(Object) error, opts ForEachOptions) (ForEachProgress, error) {
+	name, ok := lookupKind(kind)
+	if !ok {
+		return ForEachProgress{}, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var progressKey string
+	progress := ForEachProgress{Kind: kind}
+	if opts.Checkpoint != "" {
+		progressKey = forEachProgressKey(kind, opts.Checkpoint)
+		values, err := db.redisClient.HGetAll(ctx, progressKey).Result()
+		if err != nil {
+			return progress, err
+		}
+		progress.Position = values["position"]
+		progress.Processed, _ = strconv.ParseInt(values["processed"], 10, 64)
+	}
+
+	for !progress.Done {
+		min := "-"
+		if progress.Position != "" {
+			min = "(" + progress.Position
+		}
+		keys, err := db.redisClient.ZRangeByLex(ctx, idIndexKey(kind), &redis.ZRangeBy{
+			Min:   min,
+			Max:   "+",
+			Count: int64(db.fetchBatchSize),
+		}).Result()
+		if err != nil {
+			return progress, err
+		}
+
+		var batch []Object
+		err = db.fetchObjects(ctx, keys, func(object Object) error {
+			batch = append(batch, object)
+			return nil
+		})
+		if err != nil {
+			return progress, err
+		}
+		if err := forEachBatch(ctx, batch, fn, concurrency); err != nil {
+			return progress, err
+		}
+
+		progress.Processed += int64(len(batch))
+		if len(keys) > 0 {
+			progress.Position = keys[len(keys)-1]
+		}
+		progress.Done = len(keys) < db.fetchBatchSize
+
+		if progressKey != "" {
+			err := db.redisClient.HSet(ctx, progressKey,
+				"position", progress.Position,
+				"processed", progress.Processed,
+			).Err()
+			if err != nil {
+				return progress, err
+			}
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	if progressKey == "" {
+		return progress, nil
+	}
+	return progress, db.redisClient.Del(ctx, progressKey).Err()
+}
+
+// forEachBatch runs fn over batch, concurrency calls at a time, and returns
+// the first error; no call starts after one failed.
+func forEachBatch(ctx context.Context, batch []Object, fn func(Object) error, concurrency int) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return first != nil
+	}
+
+	slots := make(chan struct{}, concurrency)
+	for _, object := range batch {
+		if err := ctx.Err(); err != nil {
+			wg.Wait()
+			return err
+		}
+		slots <- struct{}{}
+		if failed() {
+			break
+		}
+
+		wg.Add(1)
+		go func(object Object) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := fn(object); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = fmt.Errorf("%s '%s': %w", object.GetKind(), object.GetID(), err)
+				}
+				mu.Unlock()
+			}
+		}(object)
+	}
+	wg.Wait()
+
+	if first == nil {
+		return ctx.Err()
+	}
+	return first
+}
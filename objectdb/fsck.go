@@ -0,0 +1,278 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ProblemType classifies what VerifyConsistency found wrong.
+type ProblemType string
+
+const (
+	// ProblemUndecodable values cannot be decoded as the kind their key
+	// names.
+	ProblemUndecodable ProblemType = "undecodable"
+	// ProblemIDMismatch values carry a different ID than their key. Values
+	// do not record their kind, so a kind mismatch only shows up as an
+	// undecodable value.
+	ProblemIDMismatch ProblemType = "id_mismatch"
+	// ProblemBrokenReference objects have a `ref` field pointing at an
+	// object that does not exist.
+	ProblemBrokenReference ProblemType = "broken_reference"
+	// ProblemMissingIndex objects lack an entry in one of their indexes.
+	ProblemMissingIndex ProblemType = "missing_index"
+	// ProblemStaleIndex entries name an object that is gone or no longer
+	// belongs in the index.
+	ProblemStaleIndex ProblemType = "stale_index"
+)
+
+// Problem is one inconsistency found by VerifyConsistency.
+type Problem struct {
+	Type ProblemType `json:"type"`
+	// Key is the object key concerned, and Index the index set for index
+	// problems.
+	Key    string `json:"key"`
+	Index  string `json:"index,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Repaired is set when VerifyOptions.Repair fixed the problem.
+	Repaired bool `json:"repaired,omitempty"`
+}
+
+// ConsistencyReport is the result of VerifyConsistency.
+type ConsistencyReport struct {
+	Objects  int64     `json:"objects"`
+	Indexes  int64     `json:"indexes"`
+	Problems []Problem `json:"problems"`
+}
+
+// Unrepaired returns the number of problems still present.
+func (r ConsistencyReport) Unrepaired() int {
+	n := 0
+	for _, problem := range r.Problems {
+		if !problem.Repaired {
+			n++
+		}
+	}
+
+	return n
+}
+
+// VerifyOptions changes what VerifyConsistency does besides reporting.
+type VerifyOptions struct {
+	// Repair adds missing index entries, removes stale ones and rewrites
+	// values whose ID disagrees with their key. Undecodable values and
+	// broken references need a decision and are only reported.
+	Repair bool
+}
+
+// VerifyConsistency checks every stored object and index entry: values must
+// decode, agree with their key and have their references and index entries
+// in place, and every index entry must name an object that belongs in it.
+// Objects written during the check may be reported wrongly; run it again
+// to confirm.
+func (db *RedisObjectDB) VerifyConsistency(ctx context.Context, opts VerifyOptions) (ConsistencyReport, error) {
+	var report ConsistencyReport
+
+	it := db.redisClient.Scan(ctx, 0, "*", db.scanCount).Iterator()
+	var keys []string
+	for it.Next(ctx) {
+		if _, _, ok := parseObjectKey(it.Val()); !ok {
+			continue
+		}
+		keys = append(keys, it.Val())
+		if len(keys) >= db.fetchBatchSize {
+			if err := db.verifyObjects(ctx, keys, opts, &report); err != nil {
+				return report, err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := it.Err(); err != nil {
+		return report, err
+	}
+	if err := db.verifyObjects(ctx, keys, opts, &report); err != nil {
+		return report, err
+	}
+
+	indexes := db.redisClient.Scan(ctx, 0, "idx:*", db.scanCount).Iterator()
+	for indexes.Next(ctx) {
+		if err := db.verifyIndex(ctx, indexes.Val(), opts, &report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, indexes.Err()
+}
+
+func (db *RedisObjectDB) verifyObjects(ctx context.Context, keys []string, opts VerifyOptions, report *ConsistencyReport) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	vals, err := db.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+
+	var objects []Object
+	for i, key := range keys {
+		data, ok := vals[i].(string)
+		if !ok {
+			continue
+		}
+		report.Objects++
+
+		kind, id, _ := parseObjectKey(key)
+		object, err := db.decode(kind, []byte(data))
+		if err != nil {
+			report.Problems = append(report.Problems, Problem{Type: ProblemUndecodable, Key: key, Detail: err.Error()})
+			continue
+		}
+		if object.GetID() != id {
+			problem := Problem{Type: ProblemIDMismatch, Key: key, Detail: fmt.Sprintf("value has ID '%s'", object.GetID())}
+			if opts.Repair {
+				problem.Repaired, problem.Detail = db.repairID(ctx, object, id, problem.Detail)
+			}
+			report.Problems = append(report.Problems, problem)
+			if !problem.Repaired {
+				continue
+			}
+		}
+		objects = append(objects, object)
+	}
+
+	pipe, repair := db.redisClient.Pipeline(), db.redisClient.Pipeline()
+	var refChecks, indexChecks []func() *Problem
+	for _, object := range objects {
+		key := objectKey(object.GetKind(), object.GetID())
+		for _, ref := range objectReferences(object) {
+			ref := ref
+			if ref.Kind == "" {
+				refChecks = append(refChecks, func() *Problem {
+					return &Problem{Type: ProblemBrokenReference, Key: key,
+						Detail: fmt.Sprintf("field '%s' references unknown kind '%s'", ref.Field, ref.Tag)}
+				})
+				continue
+			}
+			exists := pipe.Exists(ctx, objectKey(ref.Kind, ref.ID))
+			refChecks = append(refChecks, func() *Problem {
+				if exists.Val() > 0 {
+					return nil
+				}
+				return &Problem{Type: ProblemBrokenReference, Key: key,
+					Detail: fmt.Sprintf("field '%s' points at missing %s '%s'", ref.Field, ref.Kind, ref.ID)}
+			})
+		}
+		for _, entry := range objectIndexEntries(object) {
+			entry := entry
+			if entry.sorted {
+				score := pipe.ZScore(ctx, entry.key, entry.member)
+				indexChecks = append(indexChecks, func() *Problem {
+					if score.Err() == nil && score.Val() == entry.score {
+						return nil
+					}
+					queueIndexAdd(ctx, repair, entry)
+					return &Problem{Type: ProblemMissingIndex, Key: key, Index: entry.key}
+				})
+				continue
+			}
+			member := pipe.SIsMember(ctx, entry.key, entry.member)
+			indexChecks = append(indexChecks, func() *Problem {
+				if member.Val() {
+					return nil
+				}
+				queueIndexAdd(ctx, repair, entry)
+				return &Problem{Type: ProblemMissingIndex, Key: key, Index: entry.key}
+			})
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	for _, check := range refChecks {
+		if problem := check(); problem != nil {
+			report.Problems = append(report.Problems, *problem)
+		}
+	}
+	var missing []*Problem
+	for _, check := range indexChecks {
+		if problem := check(); problem != nil {
+			missing = append(missing, problem)
+		}
+	}
+	if opts.Repair && len(missing) > 0 {
+		if _, err := repair.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	for _, problem := range missing {
+		problem.Repaired = opts.Repair
+		report.Problems = append(report.Problems, *problem)
+	}
+
+	return nil
+}
+
+// repairID rewrites object under the ID of its key and reports whether that
+// worked, along with the problem's updated detail. The rewrite drops the
+// index entries for the ID in the value, so it is not done while an object
+// with that ID exists.
+func (db *RedisObjectDB) repairID(ctx context.Context, object Object, id, detail string) (bool, string) {
+	n, err := db.redisClient.Exists(ctx, objectKey(object.GetKind(), object.GetID())).Result()
+	if err != nil {
+		return false, fmt.Sprintf("%s; checking it failed: %v", detail, err)
+	}
+	if n > 0 {
+		return false, fmt.Sprintf("%s, which another object has", detail)
+	}
+
+	object.SetID(id)
+	if err := db.Store(ctx, object); err != nil {
+		return false, fmt.Sprintf("%s; rewriting it failed: %v", detail, err)
+	}
+
+	return true, detail
+}
+
+func (db *RedisObjectDB) verifyIndex(ctx context.Context, index string, opts VerifyOptions, report *ConsistencyReport) error {
+	report.Indexes++
+
+	return db.scanIndex(ctx, index, func(members []string) error {
+		return db.verifyIndexBatch(ctx, index, members, opts, report)
+	})
+}
+
+func (db *RedisObjectDB) verifyIndexBatch(ctx context.Context, index string, members []string, opts VerifyOptions, report *ConsistencyReport) error {
+	if len(members) == 0 {
+		return nil
+	}
+	vals, err := db.redisClient.MGet(ctx, indexObjectKeys(index, members)...).Result()
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for i, member := range members {
+		if !db.belongsInIndex(index, member, vals[i]) {
+			stale = append(stale, member)
+		}
+	}
+
+	// Repairs go through the GC, which checks the entries again under
+	// WATCH and leaves them alone if they are being written to.
+	repaired := false
+	if opts.Repair && len(stale) > 0 {
+		var gc GCReport
+		if err := db.gcIndexBatch(ctx, index, stale, &gc); err != nil {
+			return err
+		}
+		repaired = gc.Removed == int64(len(stale))
+	}
+	for _, member := range stale {
+		report.Problems = append(report.Problems, Problem{Type: ProblemStaleIndex, Key: indexObjectKey(index, member), Index: index, Repaired: repaired})
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package objectdb
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+// fuzzSeedValues returns a stored value for every format byte reads
+// dispatch on: each codec, each compression, a signature and an archive
+// stub, plus unframed and truncated values.
+func fuzzSeedValues(f *testing.F) [][]byte {
+	person := &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John", Labels: map[string]string{"env": "dev"}}, LastName: "Doe"}
+
+	var values [][]byte
+	for _, c := range []Codec{JSONCodec{}, MsgpackCodec{}, CBORCodec{}, ProtobufCodec{}} {
+		value, err := encodeValue(c, person)
+		if err != nil {
+			f.Fatalf("encode with %T: %v", c, err)
+		}
+		payload := value[2:]
+		// Unframed values predate framing and are told apart by their
+		// first byte.
+		values = append(values, value, payload, value[:2], value[:len(value)/2])
+	}
+
+	for _, c := range []Compression{CompressionGzip, CompressionZstd} {
+		value, err := NewRedisObjectDB(nil, WithCompression(c, 1)).encode(JSONCodec{}, person)
+		if err != nil {
+			f.Fatalf("compress with 0x%02x: %v", byte(c), err)
+		}
+		values = append(values, value, value[:len(value)/2])
+	}
+
+	signer := NewRedisObjectDB(nil, WithIntegrityKey([]byte("fuzz")), WithCompression(CompressionZstd, 1))
+	signed, err := signer.encode(MsgpackCodec{}, person)
+	if err != nil {
+		f.Fatalf("sign: %v", err)
+	}
+	values = append(values, signed, signed[:2+sha256.Size], signed[:2+sha256.Size/2])
+
+	_, stub, err := NewRedisObjectDB(nil).archiveStub(person, []byte("value"))
+	if err != nil {
+		f.Fatalf("archive stub: %v", err)
+	}
+	_, signedStub, err := signer.archiveStub(person, []byte("value"))
+	if err != nil {
+		f.Fatalf("archive stub: %v", err)
+	}
+	values = append(values, stub, signedStub, stub[:2])
+
+	return append(values, nil, []byte{valueMarker}, []byte{valueMarker, 0x7f, '{', '}'})
+}
+
+func FuzzDecodeValue(f *testing.F) {
+	for _, value := range fuzzSeedValues(f) {
+		f.Add(value)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, _, err := decodeValue(data)
+		if err != nil {
+			return
+		}
+		if c == nil {
+			t.Fatalf("decodeValue(%q) returned no codec and no error", data)
+		}
+
+		// Decoding the payload may fail, but must not panic past the
+		// recover in decodeObject.
+		kind := (&Person{}).GetKind()
+		if object, err := decodeObject(kind, data); err == nil && object == nil {
+			t.Fatalf("decodeObject(%q) returned no object and no error", data)
+		}
+	})
+}
+
+func FuzzParseObjectKey(f *testing.F) {
+	for _, kind := range Kinds() {
+		f.Add(objectKey(kind, "1"))
+		f.Add(objectKey(kind, "a:b"))
+		f.Add(objectKey(kind, ""))
+		f.Add(kindIndexKey(kind))
+		f.Add(historyKey(kind, "1"))
+	}
+	f.Add("")
+	f.Add(":")
+	f.Add("person:1")
+	f.Add("*main.Unknown:1")
+
+	f.Fuzz(func(t *testing.T, key string) {
+		kind, id, ok := parseObjectKey(key)
+		if !ok {
+			if kind != "" || id != "" {
+				t.Fatalf("parseObjectKey(%q) = %q, %q, false", key, kind, id)
+			}
+			return
+		}
+		if _, registered := kinds[kind]; !registered {
+			t.Fatalf("parseObjectKey(%q) returned unregistered kind %q", key, kind)
+		}
+		if id == "" || strings.Contains(kind, ":") {
+			t.Fatalf("parseObjectKey(%q) = %q, %q", key, kind, id)
+		}
+		if objectKey(kind, id) != key {
+			t.Fatalf("objectKey(parseObjectKey(%q)) = %q", key, objectKey(kind, id))
+		}
+	})
+}
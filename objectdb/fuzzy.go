@@ -0,0 +1,157 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Names are also indexed by trigram: each word of a name, lower-cased and
+// padded as "  john ", is cut into overlapping three-letter pieces, and
+// each piece has a set of the keys of objects whose name contains it. Two
+// names are as similar as the share of trigrams they have in common, as in
+// PostgreSQL's pg_trgm, so "Jon Doe" and "John Doe" score 0.55.
+
+// defaultSimilarity is the threshold of FuzzyOptions left at zero.
+const defaultSimilarity = 0.3
+
+// FuzzyOptions configures FindObjectsByName.
+type FuzzyOptions struct {
+	// Kind limits the lookup to one kind; empty searches every kind.
+	Kind string
+	// Threshold is the least similarity, between 0 and 1, a name needs to
+	// match. Zero means 0.3; 1 only matches names with the same words,
+	// whatever their case and punctuation.
+	Threshold float64
+	// Limit caps the number of matches; zero returns all of them.
+	Limit int
+}
+
+// NameMatch is one result of FindObjectsByName.
+type NameMatch struct {
+	Object     Object  `json:"object"`
+	Similarity float64 `json:"similarity"`
+}
+
+func trigramIndexKey(kind, trigram string) string {
+	return fmt.Sprintf("idx:trigram:%s:%s", kind, trigram)
+}
+
+// trigrams returns the distinct trigrams of the words of s.
+func trigrams(s string) map[string]bool {
+	grams := map[string]bool{}
+	for _, word := range tokenize(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			grams[string(padded[i:i+3])] = true
+		}
+	}
+
+	return grams
+}
+
+// similarity is the number of trigrams a and b share over the number in
+// either.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for gram := range a {
+		if b[gram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func trigramIndexEntries(object Object) []indexEntry {
+	member := objectKey(object.GetKind(), object.GetID())
+
+	var entries []indexEntry
+	for gram := range trigrams(object.GetName()) {
+		entries = append(entries, indexEntry{key: trigramIndexKey(object.GetKind(), gram), member: member})
+	}
+
+	return entries
+}
+
+// FindObjectsByName is a typo-tolerant GetObjectByName: it returns the
+// objects whose name is at least opts.Threshold similar to name, most
+// similar first, ties broken by kind and ID.
+func (db *RedisObjectDB) FindObjectsByName(ctx context.Context, name string, opts FuzzyOptions) ([]NameMatch, error) {
+	ctx, cancel := db.withTimeout(ctx, "FindObjectsByName")
+	defer cancel()
+
+	if opts.Threshold <= 0 {
+		opts.Threshold = defaultSimilarity
+	}
+	searched := Kinds()
+	if opts.Kind != "" {
+		kind, ok := lookupKind(opts.Kind)
+		if !ok {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, opts.Kind)
+		}
+		searched = []string{kind}
+	}
+	want := trigrams(name)
+	if len(want) == 0 {
+		return nil, fmt.Errorf("name '%s' has no letters or digits to match", name)
+	}
+
+	pipe := db.redisClient.Pipeline()
+	var cmds []*redis.StringSliceCmd
+	for _, kind := range searched {
+		for gram := range want {
+			cmds = append(cmds, pipe.SMembers(ctx, trigramIndexKey(kind, gram)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	// A name sharing n of the wanted trigrams is at most n/len(want)
+	// similar, so only names sharing enough of them are read.
+	shared := map[string]int{}
+	for _, cmd := range cmds {
+		for _, key := range cmd.Val() {
+			shared[key]++
+		}
+	}
+	var keys []string
+	for key, n := range shared {
+		if float64(n) >= opts.Threshold*float64(len(want)) {
+			keys = append(keys, key)
+		}
+	}
+	objects, err := db.fetchKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []NameMatch
+	for _, object := range objects {
+		if s := similarity(want, trigrams(object.GetName())); s >= opts.Threshold {
+			matches = append(matches, NameMatch{Object: object, Similarity: s})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if a.Object.GetKind() != b.Object.GetKind() {
+			return a.Object.GetKind() < b.Object.GetKind()
+		}
+		return a.Object.GetID() < b.Object.GetID()
+	})
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+
+	return matches, nil
+}
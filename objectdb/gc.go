@@ -0,0 +1,185 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// GCReport describes one pass of RunGC.
+type GCReport struct {
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+	// Indexes is the number of index sets scanned and Checked the number
+	// of entries looked at in them.
+	Indexes int64 `json:"indexes"`
+	Checked int64 `json:"checked"`
+	// Removed entries pointed at objects that no longer exist or no
+	// longer belong in the index.
+	Removed int64 `json:"removed"`
+	// Skipped entries were written to while being checked, and are left
+	// for the next pass.
+	Skipped int64 `json:"skipped"`
+}
+
+type gcState struct {
+	mu   sync.Mutex
+	last GCReport
+}
+
+// WithIndexGC runs RunGC every interval in the background until Close.
+// Errors end a pass early; the next one starts over.
+func WithIndexGC(interval time.Duration) Option {
+	return func(db *RedisObjectDB) {
+		if interval > 0 {
+			db.gcInterval = interval
+		}
+	}
+}
+
+func (db *RedisObjectDB) startIndexGC() {
+	db.goBackground(func(ctx context.Context) {
+		ticker := time.NewTicker(db.gcInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				db.RunGC(ctx)
+			}
+		}
+	})
+}
+
+// LastGC returns the report of the most recent RunGC pass, finished or
+// not.
+func (db *RedisObjectDB) LastGC() GCReport {
+	db.gc.mu.Lock()
+	defer db.gc.mu.Unlock()
+
+	return db.gc.last
+}
+
+// RunGC removes index entries left behind by interrupted writes. Every
+// member of every index set is checked against the object it names: the
+// object must exist and, decoded, must still produce that index entry.
+// Values that no longer decode are left alone. Each batch of members is
+// checked under WATCH, so an entry being rewritten concurrently is never
+// removed.
+func (db *RedisObjectDB) RunGC(ctx context.Context) (GCReport, error) {
+	report := GCReport{Started: time.Now()}
+	publish := func() {
+		report.Elapsed = time.Since(report.Started)
+		db.gc.mu.Lock()
+		db.gc.last = report
+		db.gc.mu.Unlock()
+	}
+	defer publish()
+
+	it := db.redisClient.Scan(ctx, 0, "idx:*", db.scanCount).Iterator()
+	for it.Next(ctx) {
+		if err := db.gcIndex(ctx, it.Val(), nil, &report); err != nil {
+			return report, err
+		}
+		publish()
+	}
+
+	return report, it.Err()
+}
+
+// gcIndex checks the members of one index set, or only those keep accepts
+// if it is not nil.
+func (db *RedisObjectDB) gcIndex(ctx context.Context, index string, keep func(member string) bool, report *GCReport) error {
+	report.Indexes++
+
+	return db.scanIndex(ctx, index, func(members []string) error {
+		var batch []string
+		for _, member := range members {
+			if keep == nil || keep(member) {
+				batch = append(batch, member)
+			}
+		}
+		return db.gcIndexBatch(ctx, index, batch, report)
+	})
+}
+
+func (db *RedisObjectDB) gcIndexBatch(ctx context.Context, index string, members []string, report *GCReport) error {
+	if len(members) == 0 {
+		return nil
+	}
+	report.Checked += int64(len(members))
+
+	keys := indexObjectKeys(index, members)
+	var removed int
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		vals, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		var orphans []interface{}
+		for i, member := range members {
+			if !db.belongsInIndex(index, member, vals[i]) {
+				orphans = append(orphans, member)
+			}
+		}
+		if len(orphans) == 0 {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			queueIndexRem(ctx, pipe, index, orphans...)
+			return nil
+		})
+		if err == nil {
+			removed = len(orphans)
+		}
+		return err
+	}, keys...)
+	if errors.Is(err, redis.TxFailedErr) {
+		report.Skipped += int64(len(members))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	report.Removed += int64(removed)
+	return nil
+}
+
+// belongsInIndex reports whether the object member of index stands for,
+// whose value is val, still has that entry in index. Anything that cannot
+// be judged is kept.
+func (db *RedisObjectDB) belongsInIndex(index, member string, val interface{}) bool {
+	kind, _, ok := parseObjectKey(indexObjectKey(index, member))
+	if !ok {
+		return true
+	}
+
+	data, ok := val.(string)
+	if !ok {
+		return false
+	}
+	// Archived objects have not been written to since they were indexed.
+	if db.isArchived([]byte(data)) {
+		return true
+	}
+	object, err := db.decode(kind, []byte(data))
+	if err != nil {
+		return true
+	}
+
+	for _, entry := range objectIndexEntries(object) {
+		if entry.key == index && entry.member == member {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrNoGeoIndex is returned by FindNearby for kinds without RegisterGeoIndex.
+var ErrNoGeoIndex = errors.New("no geo index")
+
+// A geo index is the Redis GEO sorted set of a kind, one member per object
+// with a location. Scores are computed here, the way GEOADD computes
+// them, so the entries are written and checked like any other sorted
+// index and GEORADIUS can query them.
+
+// The latitudes Redis accepts; the poles cannot be projected.
+const (
+	geoLatMax = 85.05112878
+	geoLonMax = 180
+	geoStep   = 26
+)
+
+type geoField struct {
+	lat, lon fieldInfo
+}
+
+// RegisterGeoIndex indexes the objects of kind by the location in their
+// numeric latField and lonField, in degrees, for FindNearby. A kind has at
+// most one location. Objects at exactly 0,0 are taken to have none, so
+// kinds whose location is optional need not use pointers; objects outside
+// the latitudes Redis supports, ±85.05°, are not indexed either.
+func RegisterGeoIndex(kind, latField, lonField string) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	var geo geoField
+	for _, f := range []struct {
+		name string
+		info *fieldInfo
+	}{{latField, &geo.lat}, {lonField, &geo.lon}} {
+		fi, ok := info.fields[f.name]
+		if !ok {
+			return fmt.Errorf("%s has no field '%s'", kind, f.name)
+		}
+		if fi.typ == timeType || !rangeable(fi.typ) {
+			return fmt.Errorf("field '%s' of %s is not a number", f.name, kind)
+		}
+		*f.info = fi
+	}
+
+	if info.geo != nil && !reflect.DeepEqual(*info.geo, geo) {
+		return fmt.Errorf("%s already has a geo index", kind)
+	}
+	info.geo = &geo
+	return nil
+}
+
+func geoIndexKey(kind string) string {
+	return fmt.Sprintf("idx:geo:%s", kind)
+}
+
+// geoScore is the 52-bit geohash Redis uses as the score of lat, lon.
+func geoScore(lat, lon float64) float64 {
+	latBits := uint32((lat + geoLatMax) / (2 * geoLatMax) * (1 << geoStep))
+	lonBits := uint32((lon + geoLonMax) / (2 * geoLonMax) * (1 << geoStep))
+
+	return float64(spreadBits(latBits) | spreadBits(lonBits)<<1)
+}
+
+// spreadBits moves bit i of x to bit 2i.
+func spreadBits(x uint32) uint64 {
+	v := uint64(x)
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+
+	return v
+}
+
+func geoIndexEntries(object Object) []indexEntry {
+	info, ok := kinds[object.GetKind()]
+	if !ok || info.geo == nil {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+
+	lat, latOK := geoCoordinate(v, info.geo.lat)
+	lon, lonOK := geoCoordinate(v, info.geo.lon)
+	if !latOK || !lonOK || lat == 0 && lon == 0 {
+		return nil
+	}
+	if lat < -geoLatMax || lat > geoLatMax || lon < -geoLonMax || lon > geoLonMax {
+		return nil
+	}
+
+	return []indexEntry{{
+		key:    geoIndexKey(object.GetKind()),
+		member: objectKey(object.GetKind(), object.GetID()),
+		sorted: true,
+		score:  geoScore(lat, lon),
+	}}
+}
+
+func geoCoordinate(v reflect.Value, f fieldInfo) (float64, bool) {
+	fv, err := v.FieldByIndexErr(f.index)
+	if err != nil {
+		return 0, false
+	}
+
+	return toFloat(fv.Interface())
+}
+
+// NearbyMatch is one result of FindNearby; Distance is in meters.
+type NearbyMatch struct {
+	Object   Object  `json:"object"`
+	Distance float64 `json:"distance"`
+}
+
+// FindNearby returns the objects of kind within radius meters of lat, lon,
+// nearest first. The kind needs a geo index, see RegisterGeoIndex.
+func (db *RedisObjectDB) FindNearby(ctx context.Context, kind string, lat, lon, radius float64) ([]NearbyMatch, error) {
+	ctx, cancel := db.withTimeout(ctx, "FindNearby")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	if kinds[name].geo == nil {
+		return nil, fmt.Errorf("%s has %w", name, ErrNoGeoIndex)
+	}
+
+	locations, err := db.redisClient.GeoRadius(ctx, geoIndexKey(name), lon, lat, &redis.GeoRadiusQuery{
+		Radius:   radius,
+		Unit:     "m",
+		WithDist: true,
+		Sort:     "ASC",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(locations))
+	distances := make(map[string]float64, len(locations))
+	for i, location := range locations {
+		keys[i] = location.Name
+		distances[location.Name] = location.Dist
+	}
+	objects, err := db.fetchKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]NearbyMatch, len(objects))
+	for i, object := range objects {
+		matches[i] = NearbyMatch{Object: object, Distance: distances[objectKey(name, object.GetID())]}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,322 @@
+// Package graphqlapi exposes Person and Animal over GraphQL. References are
+// resolved on demand, so a query can walk from an animal to its owner and
+// from a person to their animals:
+//
+//	{
+//	  persons(filter: [{field: "last_name", value: "Doe"}], first: 10) {
+//	    items { id name animals { name owner { name } } }
+//	    endCursor
+//	    hasNextPage
+//	  }
+//	}
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"go-assignment/objectdb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var labelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Label",
+	Fields: graphql.Fields{
+		"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var filterOpType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "FilterOp",
+	Values: graphql.EnumValueConfigMap{
+		"EQ":  &graphql.EnumValueConfig{Value: string(objectdb.OpEq)},
+		"LT":  &graphql.EnumValueConfig{Value: string(objectdb.OpLt)},
+		"LTE": &graphql.EnumValueConfig{Value: string(objectdb.OpLte)},
+		"GT":  &graphql.EnumValueConfig{Value: string(objectdb.OpGt)},
+		"GTE": &graphql.EnumValueConfig{Value: string(objectdb.OpGte)},
+	},
+})
+
+var filterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "Filter",
+	Description: "Matches objects whose field, a JSON field path such as last_name or labels.env, compares with value as op says; op defaults to equality.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"op":    &graphql.InputObjectFieldConfig{Type: filterOpType, DefaultValue: string(objectdb.OpEq)},
+		"value": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+type label struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// page is one page of a connection. Objects are ordered by ID and
+// endCursor is the ID of the last one, so pages stay stable while objects
+// are added elsewhere in the list.
+type page struct {
+	Items       []objectdb.Object `json:"items"`
+	EndCursor   string            `json:"endCursor"`
+	HasNextPage bool              `json:"hasNextPage"`
+}
+
+// refLoaderKey holds the RefLoader of a request, shared by its resolvers
+// so animals with the same owner fetch them once.
+type refLoaderKey struct{}
+
+func getOwner(ctx context.Context, db *objectdb.RedisObjectDB, animal *objectdb.Animal) (*objectdb.Person, error) {
+	loader, ok := ctx.Value(refLoaderKey{}).(*objectdb.RefLoader)
+	if !ok {
+		return db.GetOwner(ctx, animal)
+	}
+
+	object, err := loader.Refs(animal)["OwnerID"].Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owner, ok := object.(*objectdb.Person)
+	if !ok {
+		return nil, fmt.Errorf("owner of animal '%s' is a %s", animal.ID, object.GetKind())
+	}
+	return owner, nil
+}
+
+// NewSchema builds the GraphQL schema served against db.
+func NewSchema(db *objectdb.RedisObjectDB) (graphql.Schema, error) {
+	var personType, animalType *graphql.Object
+
+	personType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Person",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := metaFields()
+			fields["lastName"] = &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*objectdb.Person).LastName, nil
+				},
+			}
+			fields["birthday"] = &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*objectdb.Person).Birthday, nil
+				},
+			}
+			fields["birthDate"] = &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optionalTime(p.Source.(*objectdb.Person).BirthDate), nil
+				},
+			}
+			fields["animals"] = &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(animalType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return db.ListAnimalsByOwner(p.Context, p.Source.(*objectdb.Person).ID)
+				},
+			}
+			return fields
+		}),
+	})
+
+	animalType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Animal",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := metaFields()
+			fields["type"] = &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*objectdb.Animal).Type, nil
+				},
+			}
+			fields["ownerId"] = &graphql.Field{
+				Type: graphql.ID,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*objectdb.Animal).OwnerID, nil
+				},
+			}
+			fields["owner"] = &graphql.Field{
+				Type:        personType,
+				Description: "The person OwnerID refers to; null when unset or dangling.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					animal := p.Source.(*objectdb.Animal)
+					if animal.OwnerID == "" {
+						return nil, nil
+					}
+
+					owner, err := getOwner(p.Context, db, animal)
+					if errors.Is(err, objectdb.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return owner, nil
+				},
+			}
+			return fields
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"person":  getField(db, personType, (&objectdb.Person{}).GetKind()),
+			"animal":  getField(db, animalType, (&objectdb.Animal{}).GetKind()),
+			"persons": listField(db, personType, (&objectdb.Person{}).GetKind()),
+			"animals": listField(db, animalType, (&objectdb.Animal{}).GetKind()),
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// metaFields are the ObjectMeta fields every kind shares.
+func metaFields() graphql.Fields {
+	meta := func(p graphql.ResolveParams) *objectdb.ObjectMeta {
+		return p.Source.(objectdb.Object).GetObjectMeta()
+	}
+
+	return graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return meta(p).ID, nil
+			},
+		},
+		"name": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return meta(p).Name, nil
+			},
+		},
+		"labels": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(labelType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				labels := []label{}
+				for k, v := range meta(p).Labels {
+					labels = append(labels, label{Key: k, Value: v})
+				}
+				sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+				return labels, nil
+			},
+		},
+		"createdAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalTime(meta(p).CreatedAt), nil
+			},
+		},
+		"updatedAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalTime(meta(p).UpdatedAt), nil
+			},
+		},
+		"version": &graphql.Field{
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return meta(p).Version, nil
+			},
+		},
+	}
+}
+
+func getField(db *objectdb.RedisObjectDB, typ *graphql.Object, kind string) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			object, err := db.GetObject(p.Context, kind, p.Args["id"].(string))
+			if errors.Is(err, objectdb.ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return object, nil
+		},
+	}
+}
+
+func listField(db *objectdb.RedisObjectDB, typ *graphql.Object, kind string) *graphql.Field {
+	connection := graphql.NewObject(graphql.ObjectConfig{
+		Name: typ.Name() + "Connection",
+		Fields: graphql.Fields{
+			"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(typ)))},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	return &graphql.Field{
+		Type: graphql.NewNonNull(connection),
+		Args: graphql.FieldConfigArgument{
+			"filter": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(filterType))},
+			"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+			"after":  &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var filters []objectdb.Filter
+			raw, _ := p.Args["filter"].([]interface{})
+			for _, f := range raw {
+				f := f.(map[string]interface{})
+				op, _ := f["op"].(string)
+				filters = append(filters, objectdb.Filter{Field: f["field"].(string), Op: objectdb.FilterOp(op), Value: f["value"]})
+			}
+
+			objects, err := db.Query(p.Context, kind, filters...)
+			if err != nil {
+				return nil, err
+			}
+
+			first, _ := p.Args["first"].(int)
+			after, _ := p.Args["after"].(string)
+			return paginate(objects, first, after), nil
+		},
+	}
+}
+
+// paginate returns up to first objects with an ID greater than after.
+func paginate(objects []objectdb.Object, first int, after string) page {
+	if first <= 0 {
+		first = defaultPageSize
+	}
+	if first > maxPageSize {
+		first = maxPageSize
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].GetID() < objects[j].GetID() })
+	start := sort.Search(len(objects), func(i int) bool { return objects[i].GetID() > after })
+	objects = objects[start:]
+
+	p := page{Items: objects}
+	if len(objects) > first {
+		p.Items, p.HasNextPage = objects[:first], true
+	}
+	if len(p.Items) > 0 {
+		p.EndCursor = p.Items[len(p.Items)-1].GetID()
+	}
+
+	return p
+}
+
+// optionalTime keeps unset times null instead of year 1.
+func optionalTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
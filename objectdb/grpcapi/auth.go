@@ -0,0 +1,74 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"go-assignment/objectdb"
+)
+
+// BearerTokens authenticates calls by their "authorization: Bearer"
+// metadata, as sent by TokenCredentials.
+func BearerTokens(tokens *objectdb.TokenSet) func(context.Context) (objectdb.Principal, bool) {
+	return func(ctx context.Context) (objectdb.Principal, bool) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			scheme, token, ok := strings.Cut(v, " ")
+			if ok && strings.EqualFold(scheme, "Bearer") {
+				return tokens.Authenticate(strings.TrimSpace(token))
+			}
+		}
+
+		return objectdb.Principal{}, false
+	}
+}
+
+// ClientCertificates authenticates calls by their verified TLS client
+// certificate, see objectdb.PrincipalFromCertificate. The server's TLS
+// credentials must verify client certificates for there to be any.
+func ClientCertificates(ctx context.Context) (objectdb.Principal, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return objectdb.Principal{}, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return objectdb.Principal{}, false
+	}
+
+	return objectdb.PrincipalFromCertificate(info.State.VerifiedChains[0][0])
+}
+
+// FirstOf authenticates calls with the first of authenticators that
+// recognises them.
+func FirstOf(authenticators ...func(context.Context) (objectdb.Principal, bool)) func(context.Context) (objectdb.Principal, bool) {
+	return func(ctx context.Context) (objectdb.Principal, bool) {
+		for _, authenticate := range authenticators {
+			if p, ok := authenticate(ctx); ok {
+				return p, true
+			}
+		}
+
+		return objectdb.Principal{}, false
+	}
+}
+
+// TokenCredentials sends Token with every call, for use with
+// grpc.WithPerRPCCredentials. Tokens are only sent over TLS unless
+// Insecure is set, which is meant for local testing.
+type TokenCredentials struct {
+	Token    string
+	Insecure bool
+}
+
+func (c TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.Token}, nil
+}
+
+func (c TokenCredentials) RequireTransportSecurity() bool {
+	return !c.Insecure
+}
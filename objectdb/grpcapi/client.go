@@ -0,0 +1,164 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectpb"
+)
+
+// Client talks to an ObjectService. Its methods mirror RedisObjectDB's, and
+// its errors unwrap to the same objectdb errors a local store returns.
+type Client struct {
+	rpc objectpb.ObjectServiceClient
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: objectpb.NewObjectServiceClient(conn)}
+}
+
+// Store writes object and, like RedisObjectDB.Store, updates its metadata
+// with what the server stamped.
+func (c *Client) Store(ctx context.Context, object objectdb.Object) error {
+	stored, err := toStoredObject(object)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.rpc.Store(ctx, &objectpb.StoreRequest{Object: stored})
+	if err != nil {
+		return fromStatus(err)
+	}
+
+	written, err := fromStoredObject(resp.GetObject())
+	if err != nil {
+		return err
+	}
+	copyInto(object, written)
+
+	return nil
+}
+
+func (c *Client) GetObject(ctx context.Context, kind, id string) (objectdb.Object, error) {
+	resp, err := c.rpc.Get(ctx, &objectpb.GetRequest{Kind: kind, Id: id})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	return fromStoredObject(resp.GetObject())
+}
+
+// ListPage lists one page as RedisObjectDB.ListPage does.
+func (c *Client) ListPage(ctx context.Context, page objectdb.PageRequest) (objectdb.Page, error) {
+	req := &objectpb.ListRequest{
+		Kind:      page.Kind,
+		PageToken: page.Token,
+		Order:     string(page.Order),
+		Limit:     int32(page.Limit),
+	}
+	for _, f := range page.Filters {
+		req.Filters = append(req.Filters, f.String())
+	}
+	resp, err := c.rpc.List(ctx, req)
+	if err != nil {
+		return objectdb.Page{}, fromStatus(err)
+	}
+
+	objects, err := fromStoredObjects(resp.GetObjects())
+	if err != nil {
+		return objectdb.Page{}, err
+	}
+
+	return objectdb.Page{Items: objects, Next: resp.GetNextPageToken()}, nil
+}
+
+// ListObjectsByLabels lists the objects of kind matching selector, in the
+// syntax ParseSelector accepts.
+func (c *Client) ListObjectsByLabels(ctx context.Context, kind, selector string) ([]objectdb.Object, error) {
+	resp, err := c.rpc.List(ctx, &objectpb.ListRequest{Kind: kind, Selector: selector})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	return fromStoredObjects(resp.GetObjects())
+}
+
+// DeleteObject deletes kind/id. A zero policy leaves dependents alone.
+func (c *Client) DeleteObject(ctx context.Context, kind, id string, policy objectdb.DeletePolicy) error {
+	req := &objectpb.DeleteRequest{Kind: kind, Id: id}
+	switch policy {
+	case 0:
+	case objectdb.DeleteCascade:
+		req.Policy = objectpb.DeletePolicy_DELETE_POLICY_CASCADE
+	case objectdb.DeleteRestrict:
+		req.Policy = objectpb.DeletePolicy_DELETE_POLICY_RESTRICT
+	case objectdb.DeleteOrphan:
+		req.Policy = objectpb.DeletePolicy_DELETE_POLICY_ORPHAN
+	default:
+		return fmt.Errorf("unknown delete policy %d", policy)
+	}
+
+	_, err := c.rpc.Delete(ctx, req)
+	return fromStatus(err)
+}
+
+// ChangeIterator follows a remote change feed, like objectdb.ChangeIterator.
+type ChangeIterator struct {
+	stream objectpb.ObjectService_WatchClient
+	cur    objectdb.Change
+	err    error
+}
+
+// Watch follows changes to kind, or every kind if empty, after cursor. The
+// watch ends when ctx is done.
+func (c *Client) Watch(ctx context.Context, kind, cursor string) *ChangeIterator {
+	stream, err := c.rpc.Watch(ctx, &objectpb.WatchRequest{Kind: kind, Cursor: cursor})
+	return &ChangeIterator{stream: stream, err: fromStatus(err)}
+}
+
+func (it *ChangeIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	event, err := it.stream.Recv()
+	if err != nil {
+		it.err = fromStatus(err)
+		return false
+	}
+
+	change := objectdb.Change{
+		Cursor: event.GetCursor(),
+		Kind:   event.GetKind(),
+		ID:     event.GetId(),
+	}
+	switch event.GetType() {
+	case objectpb.ChangeType_CHANGE_TYPE_PUT:
+		change.Type = objectdb.ChangePut
+	case objectpb.ChangeType_CHANGE_TYPE_DELETE:
+		change.Type = objectdb.ChangeDelete
+	}
+	if event.GetObject() != nil {
+		change.Object, err = fromStoredObject(event.GetObject())
+		if err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	it.cur = change
+	return true
+}
+
+func (it *ChangeIterator) Change() objectdb.Change {
+	return it.cur
+}
+
+// Err returns the error that ended the watch. io.EOF means the server
+// closed the stream.
+func (it *ChangeIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,98 @@
+// Package grpcapi implements the objectpb.ObjectService gRPC API on top of
+// a RedisObjectDB, and a client for it that speaks in objectdb types.
+package grpcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectpb"
+)
+
+// toStoredObject wraps object in its kind's message, falling back to a
+// Struct of its JSON form for kinds without one.
+func toStoredObject(object objectdb.Object) (*objectpb.StoredObject, error) {
+	msg, err := objectdb.ObjectToProto(object)
+	if errors.Is(err, objectdb.ErrNoProtoMessage) {
+		msg, err = toStruct(object)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packed, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &objectpb.StoredObject{Kind: object.GetKind(), Object: packed}, nil
+}
+
+func fromStoredObject(stored *objectpb.StoredObject) (objectdb.Object, error) {
+	if stored.GetObject() == nil {
+		return nil, fmt.Errorf("%s object is missing", stored.GetKind())
+	}
+
+	msg, err := stored.GetObject().UnmarshalNew()
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := msg.(*structpb.Struct)
+	if !ok {
+		return objectdb.ObjectFromProto(stored.GetKind(), msg)
+	}
+
+	object, err := objectdb.NewObject(stored.GetKind())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func toStruct(object objectdb.Object) (*structpb.Struct, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStruct(doc)
+}
+
+func fromStoredObjects(stored []*objectpb.StoredObject) ([]objectdb.Object, error) {
+	objects := make([]objectdb.Object, 0, len(stored))
+	for _, s := range stored {
+		object, err := fromStoredObject(s)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// copyInto overwrites dst with src, both objects of the same kind.
+func copyInto(dst, src objectdb.Object) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
@@ -0,0 +1,166 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go-assignment/objectdb"
+)
+
+// errorDomain is the ErrorInfo domain of every store error.
+const errorDomain = "objectdb"
+
+// Reasons carried in ErrorInfo, one per store error.
+const (
+	reasonValidation      = "VALIDATION"
+	reasonNotFound        = "NOT_FOUND"
+	reasonUnknownKind     = "UNKNOWN_KIND"
+	reasonBrokenReference = "BROKEN_REFERENCE"
+	reasonRestricted      = "RESTRICTED"
+	reasonIntegrity       = "INTEGRITY"
+	reasonQuotaExceeded   = "QUOTA_EXCEEDED"
+	reasonTooLarge        = "TOO_LARGE"
+	reasonDenied          = "DENIED"
+	reasonForbidden       = "FORBIDDEN"
+	reasonInvalidToken    = "INVALID_PAGE_TOKEN"
+)
+
+var sentinels = map[string]error{
+	reasonNotFound:        objectdb.ErrNotFound,
+	reasonUnknownKind:     objectdb.ErrUnknownKind,
+	reasonBrokenReference: objectdb.ErrBrokenReference,
+	reasonRestricted:      objectdb.ErrRestricted,
+	reasonIntegrity:       objectdb.ErrIntegrity,
+	reasonQuotaExceeded:   objectdb.ErrQuotaExceeded,
+	reasonTooLarge:        objectdb.ErrObjectTooLarge,
+	reasonDenied:          objectdb.ErrDenied,
+	reasonForbidden:       objectdb.ErrForbidden,
+	reasonInvalidToken:    objectdb.ErrInvalidPageToken,
+}
+
+// toStatus maps a store error to a status with an ErrorInfo detail, and a
+// BadRequest detail for validation errors.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var validation *objectdb.ValidationError
+	var code codes.Code
+	var reason string
+	switch {
+	case errors.As(err, &validation):
+		code, reason = codes.InvalidArgument, reasonValidation
+	case errors.Is(err, objectdb.ErrNotFound):
+		code, reason = codes.NotFound, reasonNotFound
+	case errors.Is(err, objectdb.ErrUnknownKind):
+		code, reason = codes.NotFound, reasonUnknownKind
+	case errors.Is(err, objectdb.ErrBrokenReference):
+		code, reason = codes.FailedPrecondition, reasonBrokenReference
+	case errors.Is(err, objectdb.ErrRestricted):
+		code, reason = codes.FailedPrecondition, reasonRestricted
+	case errors.Is(err, objectdb.ErrIntegrity):
+		code, reason = codes.DataLoss, reasonIntegrity
+	case errors.Is(err, objectdb.ErrQuotaExceeded):
+		code, reason = codes.ResourceExhausted, reasonQuotaExceeded
+	case errors.Is(err, objectdb.ErrObjectTooLarge):
+		code, reason = codes.InvalidArgument, reasonTooLarge
+	case errors.Is(err, objectdb.ErrDenied):
+		code, reason = codes.PermissionDenied, reasonDenied
+	case errors.Is(err, objectdb.ErrForbidden):
+		code, reason = codes.PermissionDenied, reasonForbidden
+	case errors.Is(err, objectdb.ErrInvalidPageToken):
+		code, reason = codes.InvalidArgument, reasonInvalidToken
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	info := &errdetails.ErrorInfo{Domain: errorDomain, Reason: reason}
+	var badRequest *errdetails.BadRequest
+	if validation != nil {
+		info.Metadata = map[string]string{"kind": validation.Kind, "id": validation.ID}
+		badRequest = &errdetails.BadRequest{}
+		for _, v := range validation.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+			})
+		}
+	}
+
+	st := status.New(code, err.Error())
+	if badRequest != nil {
+		st, _ = st.WithDetails(info, badRequest)
+	} else {
+		st, _ = st.WithDetails(info)
+	}
+
+	return st.Err()
+}
+
+// Error is a store error received from the server. It unwraps to the
+// matching objectdb error, so errors.Is(err, objectdb.ErrNotFound) and
+// errors.As(err, &validationErr) work the same on both sides of the wire.
+type Error struct {
+	status *status.Status
+	err    error
+}
+
+func (e *Error) Error() string {
+	return e.status.Message()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// GRPCStatus makes status.FromError return the original status.
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// fromStatus turns a status error back into the store error it came from.
+// Errors without an objectdb ErrorInfo are returned unchanged.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+
+	var info *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		}
+	}
+	if info == nil || info.GetDomain() != errorDomain {
+		return err
+	}
+
+	if info.GetReason() == reasonValidation {
+		validation := &objectdb.ValidationError{Kind: info.GetMetadata()["kind"], ID: info.GetMetadata()["id"]}
+		for _, v := range badRequest.GetFieldViolations() {
+			validation.Violations = append(validation.Violations, objectdb.Violation{Field: v.GetField(), Message: v.GetDescription()})
+		}
+		return &Error{status: st, err: validation}
+	}
+
+	sentinel, ok := sentinels[info.GetReason()]
+	if !ok {
+		return err
+	}
+
+	return &Error{status: st, err: sentinel}
+}
@@ -0,0 +1,286 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectpb"
+)
+
+// Server implements objectpb.ObjectServiceServer:
+//
+//	objectpb.RegisterObjectServiceServer(grpcServer, grpcapi.NewServer(db))
+type Server struct {
+	objectpb.UnimplementedObjectServiceServer
+	db *objectdb.RedisObjectDB
+
+	policy       *objectdb.AccessPolicy
+	authenticate func(context.Context) (objectdb.Principal, bool)
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithAccessPolicy enforces policy on every call. authenticate names the
+// principal behind a call from its context, e.g. from metadata or the
+// peer's certificate; calls it does not recognise fail with
+// Unauthenticated.
+func WithAccessPolicy(policy *objectdb.AccessPolicy, authenticate func(context.Context) (objectdb.Principal, bool)) ServerOption {
+	return func(s *Server) {
+		s.policy = policy
+		s.authenticate = authenticate
+	}
+}
+
+func NewServer(db *objectdb.RedisObjectDB, opts ...ServerOption) *Server {
+	s := &Server{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// withPrincipal returns ctx with the caller's principal in it.
+func (s *Server) withPrincipal(ctx context.Context) (context.Context, error) {
+	if s.policy == nil {
+		return ctx, nil
+	}
+
+	principal, ok := s.authenticate(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated call")
+	}
+
+	return objectdb.ContextWithPrincipal(ctx, principal), nil
+}
+
+func (s *Server) Store(ctx context.Context, req *objectpb.StoreRequest) (*objectpb.StoreResponse, error) {
+	ctx, err := s.withPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := fromStoredObject(req.GetObject())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.policy.AuthorizeObject(ctx, objectdb.VerbStore, object); err != nil {
+		return nil, toStatus(err)
+	}
+	// Moving an object to another namespace needs access to both.
+	existing, err := s.db.GetObject(ctx, object.GetKind(), object.GetID())
+	if err == nil {
+		err = s.policy.AuthorizeObject(ctx, objectdb.VerbStore, existing)
+	}
+	if err != nil && !errors.Is(err, objectdb.ErrNotFound) {
+		return nil, toStatus(err)
+	}
+
+	if err := s.db.Store(ctx, object); err != nil {
+		return nil, toStatus(err)
+	}
+
+	stored, err := toStoredObject(object)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &objectpb.StoreResponse{Object: stored}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *objectpb.GetRequest) (*objectpb.GetResponse, error) {
+	ctx, err := s.withPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s.db.GetObject(ctx, req.GetKind(), req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.policy.AuthorizeObject(ctx, objectdb.VerbGet, object); err != nil {
+		return nil, toStatus(err)
+	}
+
+	stored, err := toStoredObject(object)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &objectpb.GetResponse{Object: stored}, nil
+}
+
+func (s *Server) List(ctx context.Context, req *objectpb.ListRequest) (*objectpb.ListResponse, error) {
+	ctx, err := s.withPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := resolveKind(req.GetKind())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var objects []objectdb.Object
+	var next string
+	if req.GetSelector() != "" {
+		selector, err := objectdb.ParseSelector(req.GetSelector())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		objects, err = s.db.ListObjectsByLabels(ctx, kind, selector)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+	} else {
+		page := objectdb.PageRequest{
+			Kind:  kind,
+			Order: objectdb.PageOrder(req.GetOrder()),
+			Limit: int(req.GetLimit()),
+			Token: req.GetPageToken(),
+		}
+		if page.Order != "" && page.Order != objectdb.OrderByID && page.Order != objectdb.OrderByCreatedAt {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid order '%s'", page.Order)
+		}
+		for _, raw := range req.GetFilters() {
+			f, err := objectdb.ParseFilter(raw)
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			page.Filters = append(page.Filters, f)
+		}
+		listed, err := s.db.ListPage(ctx, page)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		objects, next = listed.Items, listed.Next
+	}
+
+	resp := &objectpb.ListResponse{NextPageToken: next}
+	for _, object := range s.policy.FilterObjects(ctx, objectdb.VerbList, objects) {
+		stored, err := toStoredObject(object)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Objects = append(resp.Objects, stored)
+	}
+
+	return resp, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *objectpb.DeleteRequest) (*objectpb.DeleteResponse, error) {
+	ctx, err := s.withPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []objectdb.DeleteOption
+	switch req.GetPolicy() {
+	case objectpb.DeletePolicy_DELETE_POLICY_UNSPECIFIED:
+	case objectpb.DeletePolicy_DELETE_POLICY_CASCADE:
+		opts = append(opts, objectdb.DeleteCascade)
+	case objectpb.DeletePolicy_DELETE_POLICY_RESTRICT:
+		opts = append(opts, objectdb.DeleteRestrict)
+	case objectpb.DeletePolicy_DELETE_POLICY_ORPHAN:
+		opts = append(opts, objectdb.DeleteOrphan)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown delete policy %d", req.GetPolicy())
+	}
+
+	// DeleteObject looks objects up by ID alone; reading first makes sure
+	// the ID belongs to the requested kind.
+	object, err := s.db.GetObject(ctx, req.GetKind(), req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.policy.AuthorizeObject(ctx, objectdb.VerbDelete, object); err != nil {
+		return nil, toStatus(err)
+	}
+
+	opts = append(opts, objectdb.AuthorizeDependents(s.policy))
+	if err := s.db.DeleteObject(ctx, req.GetId(), opts...); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &objectpb.DeleteResponse{}, nil
+}
+
+// Watch streams the change feed. The store must be created with
+// objectdb.WithChangeFeed for there to be any changes to watch.
+func (s *Server) Watch(req *objectpb.WatchRequest, stream objectpb.ObjectService_WatchServer) error {
+	var kind string
+	if req.GetKind() != "" {
+		var err error
+		kind, err = resolveKind(req.GetKind())
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+
+	ctx, err := s.withPrincipal(stream.Context())
+	if err != nil {
+		return err
+	}
+	// Changes are not filtered by namespace, so watching takes list
+	// access to all of them.
+	watched := kind
+	if watched == "" {
+		watched = "*"
+	}
+	if err := s.policy.Authorize(ctx, objectdb.VerbList, watched, objectdb.AllNamespaces); err != nil {
+		return toStatus(err)
+	}
+
+	it := s.db.Watch(ctx, kind, req.GetCursor())
+	for it.Next(ctx) {
+		event, err := toWatchEvent(it.Change())
+		if err != nil {
+			return toStatus(err)
+		}
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	return toStatus(it.Err())
+}
+
+func toWatchEvent(change objectdb.Change) (*objectpb.WatchEvent, error) {
+	event := &objectpb.WatchEvent{
+		Cursor: change.Cursor,
+		Kind:   change.Kind,
+		Id:     change.ID,
+	}
+
+	switch change.Type {
+	case objectdb.ChangePut:
+		event.Type = objectpb.ChangeType_CHANGE_TYPE_PUT
+	case objectdb.ChangeDelete:
+		event.Type = objectpb.ChangeType_CHANGE_TYPE_DELETE
+	}
+
+	if change.Object != nil {
+		stored, err := toStoredObject(change.Object)
+		if err != nil {
+			return nil, err
+		}
+		event.Object = stored
+	}
+
+	return event, nil
+}
+
+// resolveKind turns a short kind name into the registered one.
+func resolveKind(kind string) (string, error) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		return "", err
+	}
+
+	return object.GetKind(), nil
+}
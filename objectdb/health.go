@@ -0,0 +1,127 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// healthSampleSize bounds how many stored objects Healthy decodes.
+const healthSampleSize = 20
+
+// ErrIndexDrift is returned by Healthy for sampled objects missing from
+// the indexes listing their kind; RebuildIndexes repairs them.
+var ErrIndexDrift = errors.New("index drift")
+
+// Healthy pings Redis and then decodes a sample of stored objects, checking
+// that each one still carries the ID of the key it is stored under and is
+// in the kind and ID indexes that listing relies on. It only reads: the
+// values of archived objects are not fetched, their stubs being checked
+// against the indexes alone.
+func (db *RedisObjectDB) Healthy(ctx context.Context) error {
+	err := db.redisClient.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	keys, _, err := db.redisClient.Scan(ctx, 0, "*", healthSampleSize).Result()
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	var sampled []string
+	for _, key := range keys {
+		kind, id, ok := parseObjectKey(key)
+		if !ok {
+			continue
+		}
+
+		val, err := db.redisClient.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("get '%s': %w", key, err)
+		}
+		if db.isArchived(val) {
+			sampled = append(sampled, key)
+			continue
+		}
+
+		object, err := db.decode(kind, val)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", key, err)
+		}
+
+		if object.GetID() != id {
+			return fmt.Errorf("key '%s' holds object with ID '%s'", key, object.GetID())
+		}
+		sampled = append(sampled, key)
+	}
+
+	return db.checkIndexed(ctx, sampled)
+}
+
+// checkIndexed checks that the object keys are in the kind and ID indexes
+// of their kind, reporting every one that is not.
+func (db *RedisObjectDB) checkIndexed(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := db.redisClient.Pipeline()
+	inKind := make([]*redis.BoolCmd, len(keys))
+	inID := make([]*redis.FloatCmd, len(keys))
+	for i, key := range keys {
+		kind, _, _ := parseObjectKey(key)
+		inKind[i] = pipe.SIsMember(ctx, kindIndexKey(kind), key)
+		inID[i] = pipe.ZScore(ctx, idIndexKey(kind), key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("check indexes: %w", err)
+	}
+
+	var drift []string
+	for i, key := range keys {
+		kind, _, _ := parseObjectKey(key)
+		if !inKind[i].Val() {
+			drift = append(drift, fmt.Sprintf("'%s' is not in '%s'", key, kindIndexKey(kind)))
+		}
+		if err := inID[i].Err(); err == redis.Nil {
+			drift = append(drift, fmt.Sprintf("'%s' is not in '%s'", key, idIndexKey(kind)))
+		} else if err != nil {
+			return fmt.Errorf("check indexes: %w", err)
+		}
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("%w: %s", ErrIndexDrift, strings.Join(drift, "; "))
+	}
+
+	return nil
+}
+
+// NewHealthHandler serves Kubernetes-style probes. /healthz only reports
+// that the process is up; /readyz runs Healthy against Redis.
+func NewHealthHandler(db *RedisObjectDB) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := db.Healthy(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+}
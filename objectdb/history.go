@@ -0,0 +1,303 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrNoHistory is returned by GetObjectAsOf and ListObjectsAsOf for times
+// the recorded history does not reach back to.
+var ErrNoHistory = errors.New("no history that far back")
+
+// History entries are the stored value prefixed with historyPut, or
+// historyDelete and the time of the delete, scored by the time of the
+// write in microseconds.
+const (
+	historyPut    = "p"
+	historyDelete = "d"
+)
+
+const (
+	// addHistoryScript adds ARGV[2] to the history KEYS[1] of object ARGV[3]
+	// at ARGV[1], or just after its newest entry if that is not earlier, so
+	// entries sort in write order. With a cutoff in ARGV[4] it then trims
+	// the history as trimHistoryScript does.
+	addHistoryScript = `
+local score = ARGV[1]
+local last = redis.call("ZREVRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if #last > 0 and tonumber(last[2]) >= tonumber(score) then
+	score = string.format("%.0f", tonumber(last[2]) + 1)
+end
+redis.call("ZADD", KEYS[1], score, ARGV[2])
+redis.call("SADD", KEYS[2], ARGV[3])
+if ARGV[4] ~= "" then
+` + trimHistoryLua + `
+end
+return 0`
+
+	// trimHistoryLua drops the entries of history KEYS[1] older than
+	// ARGV[4] but the newest of them, which still tells what the object was
+	// at ARGV[4]. If that is a delete with nothing after it, the key goes,
+	// taking object ARGV[3] out of the history index KEYS[2].
+	trimHistoryLua = `
+local old = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", "(" .. ARGV[4])
+if #old > 1 then
+	redis.call("ZREMRANGEBYRANK", KEYS[1], 0, #old - 2)
+end
+if #old > 0 and redis.call("ZCARD", KEYS[1]) == 1 and string.sub(old[#old], 1, 1) == "d" then
+	redis.call("DEL", KEYS[1])
+	redis.call("SREM", KEYS[2], ARGV[3])
+	return #old
+end
+return math.max(#old - 1, 0)`
+)
+
+var trimHistoryScript = redis.NewScript(trimHistoryLua)
+
+// WithHistory keeps every version of every object written, and a marker
+// for every delete, in the same MULTI as the write itself, for
+// GetObjectAsOf and ListObjectsAsOf. Versions older than retention are
+// dropped when their object is written again and by PruneHistory, all but
+// the last, which the object still was at the cutoff; 0 keeps them all.
+// History only reaches back to when it was enabled, and costs as much
+// memory as the versions it keeps.
+func WithHistory(retention time.Duration) Option {
+	return func(db *RedisObjectDB) {
+		db.history = true
+		if retention > 0 {
+			db.historyRetention = retention
+		}
+	}
+}
+
+// historyKey holds the versions of kind/id in a sorted set.
+func historyKey(kind, id string) string {
+	return fmt.Sprintf("history:%s:%s", kind, id)
+}
+
+// historyIndexKey holds the IDs of the objects of kind with a history.
+func historyIndexKey(kind string) string {
+	return fmt.Sprintf("history:%s", kind)
+}
+
+// queueHistory queues recording a write in the history on pipe. value is
+// the stored value, or nil for deletes.
+func (db *RedisObjectDB) queueHistory(ctx context.Context, pipe redis.Pipeliner, typ ChangeType, kind, id string, value []byte) {
+	now := time.Now()
+	entry := historyDelete + strconv.FormatInt(now.UnixNano(), 10)
+	if typ == ChangePut {
+		entry = historyPut + string(value)
+	}
+	cutoff := ""
+	if db.historyRetention > 0 {
+		cutoff = strconv.FormatInt(now.Add(-db.historyRetention).UnixMicro(), 10)
+	}
+
+	pipe.Eval(ctx, addHistoryScript, []string{historyKey(kind, id), historyIndexKey(kind)},
+		now.UnixMicro(), entry, id, cutoff)
+}
+
+// PruneHistory trims the history of every object, as writing it again
+// would, and returns how many entries it dropped. Objects deleted or not
+// written in a long time are only trimmed by it, so with a retention it
+// should run every so often.
+func (db *RedisObjectDB) PruneHistory(ctx context.Context) (int64, error) {
+	if db.historyRetention == 0 {
+		return 0, nil
+	}
+
+	cutoff := strconv.FormatInt(time.Now().Add(-db.historyRetention).UnixMicro(), 10)
+	var pruned int64
+	for _, kind := range Kinds() {
+		it := db.redisClient.SScan(ctx, historyIndexKey(kind), 0, "", db.scanCount).Iterator()
+		for it.Next(ctx) {
+			keys := []string{historyKey(kind, it.Val()), historyIndexKey(kind)}
+			n, err := trimHistoryScript.Run(ctx, db.redisClient, keys, "", "", it.Val(), cutoff).Int64()
+			if err != nil {
+				return pruned, err
+			}
+			pruned += n
+		}
+		if err := it.Err(); err != nil {
+			return pruned, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// GetObjectAsOf returns the object with id as it was at t, or ErrNotFound
+// if there was none then. It needs WithHistory, unless the object has not
+// been written since t.
+func (db *RedisObjectDB) GetObjectAsOf(ctx context.Context, id string, t time.Time) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "GetObjectAsOf")
+	defer cancel()
+
+	if err := db.checkHistoryReaches(t); err != nil {
+		return nil, err
+	}
+
+	var noHistory error
+	for _, kind := range Kinds() {
+		objects, err := db.objectsAsOf(ctx, kind, []string{id}, t)
+		if errors.Is(err, ErrNoHistory) {
+			noHistory = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(objects) > 0 {
+			return objects[0], nil
+		}
+	}
+	if noHistory != nil {
+		return nil, noHistory
+	}
+
+	return nil, fmt.Errorf("object with ID '%s' %w", id, ErrNotFound)
+}
+
+// ListObjectsAsOf returns the objects of kind, by registered or short name,
+// that existed at t as they were then, in ID order. Like GetObjectAsOf it
+// needs WithHistory for objects written since t.
+func (db *RedisObjectDB) ListObjectsAsOf(ctx context.Context, kind string, t time.Time) ([]Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListObjectsAsOf")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	if err := db.checkHistoryReaches(t); err != nil {
+		return nil, err
+	}
+
+	// Objects deleted since t only have a history; objects not written
+	// since history was enabled only have a value.
+	ids, err := db.redisClient.SMembers(ctx, historyIndexKey(kind)).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := db.redisClient.SMembers(ctx, kindIndexKey(kind)).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, id, ok := parseObjectKey(key); ok {
+			ids = append(ids, id)
+		}
+	}
+	ids = dedupe(ids)
+	sort.Strings(ids)
+
+	var objects []Object
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > db.fetchBatchSize {
+			n = db.fetchBatchSize
+		}
+		batch, err := db.objectsAsOf(ctx, kind, ids[:n], t)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, batch...)
+		ids = ids[n:]
+	}
+
+	return objects, nil
+}
+
+func (db *RedisObjectDB) checkHistoryReaches(t time.Time) error {
+	if db.historyRetention > 0 && t.Before(time.Now().Add(-db.historyRetention)) {
+		return fmt.Errorf("%w: history is kept for %s", ErrNoHistory, db.historyRetention)
+	}
+
+	return nil
+}
+
+// objectsAsOf returns those of the objects of kind with ids that existed at
+// t, as they were then, in the order of ids. For each it reads the last
+// history entry up to t, the first one after t and the current value:
+//
+//   - an entry up to t is what the object was;
+//   - else an entry after t that creates the object means there was none;
+//   - else with no entries at all the current value is what the object
+//     was, if it was written by t;
+//   - else the history starts too late.
+func (db *RedisObjectDB) objectsAsOf(ctx context.Context, kind string, ids []string, t time.Time) ([]Object, error) {
+	at := strconv.FormatInt(t.UnixMicro(), 10)
+	pipe := db.redisClient.Pipeline()
+	before := make([]*redis.StringSliceCmd, len(ids))
+	after := make([]*redis.StringSliceCmd, len(ids))
+	current := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		key := historyKey(kind, id)
+		before[i] = pipe.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Max: at, Min: "-inf", Count: 1})
+		after[i] = pipe.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "(" + at, Max: "+inf", Count: 1})
+		current[i] = pipe.Get(ctx, objectKey(kind, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var objects []Object
+	for i, id := range ids {
+		if entries := before[i].Val(); len(entries) > 0 {
+			object, err := db.decodeHistoryEntry(kind, entries[0])
+			if err != nil {
+				return nil, fmt.Errorf("history of %s '%s': %w", kind, id, err)
+			}
+			if object != nil {
+				objects = append(objects, object)
+			}
+			continue
+		}
+
+		if entries := after[i].Val(); len(entries) > 0 {
+			object, err := db.decodeHistoryEntry(kind, entries[0])
+			if err != nil {
+				return nil, fmt.Errorf("history of %s '%s': %w", kind, id, err)
+			}
+			if object == nil || object.GetObjectMeta().Version > 1 {
+				return nil, fmt.Errorf("%w: %s '%s' has none before %s", ErrNoHistory, kind, id, t.Format(time.RFC3339))
+			}
+			continue
+		}
+
+		data, err := current[i].Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		object, err := db.decode(kind, data)
+		if err != nil {
+			return nil, err
+		}
+		if object.GetObjectMeta().UpdatedAt.After(t) {
+			return nil, fmt.Errorf("%w: %s '%s' has none", ErrNoHistory, kind, id)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// decodeHistoryEntry returns the object a history entry holds, or nil for a
+// delete.
+func (db *RedisObjectDB) decodeHistoryEntry(kind, entry string) (Object, error) {
+	if entry[:1] != historyPut {
+		return nil, nil
+	}
+
+	return db.decode(kind, []byte(entry[1:]))
+}
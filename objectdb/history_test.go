@@ -0,0 +1,150 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// tick waits long enough for the next write to get a later history score,
+// and returns a time between the writes before and after it.
+func tick() time.Time {
+	time.Sleep(2 * time.Millisecond)
+	t := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	return t
+}
+
+func lastNameAsOf(t *testing.T, db *RedisObjectDB, id string, at time.Time) (string, error) {
+	t.Helper()
+
+	object, err := db.GetObjectAsOf(context.Background(), id, at)
+	if err != nil {
+		return "", err
+	}
+
+	return object.(*Person).LastName, nil
+}
+
+func TestGetObjectAsOfReadsEveryVersion(t *testing.T) {
+	db, _ := newTestDB(t, WithHistory(0))
+	ctx := context.Background()
+
+	beforeCreate := tick()
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	afterCreate := tick()
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Roe"}); err != nil {
+		t.Fatal(err)
+	}
+	afterUpdate := tick()
+	if err := db.DeleteObject(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	afterDelete := tick()
+
+	if _, err := db.GetObjectAsOf(ctx, "1", beforeCreate); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetObjectAsOf(before the create) = %v, want %v", err, ErrNotFound)
+	}
+	for at, want := range map[time.Time]string{afterCreate: "Doe", afterUpdate: "Roe"} {
+		got, err := lastNameAsOf(t, db, "1", at)
+		if err != nil || got != want {
+			t.Fatalf("GetObjectAsOf(%s) = %q, %v, want %q", at, got, err, want)
+		}
+	}
+	if _, err := db.GetObjectAsOf(ctx, "1", afterDelete); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetObjectAsOf(after the delete) = %v, want %v", err, ErrNotFound)
+	}
+
+	objects, err := db.ListObjectsAsOf(ctx, "person", afterCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].(*Person).LastName != "Doe" {
+		t.Fatalf("ListObjectsAsOf(after the create) = %v, want the first version", objects)
+	}
+	if objects, err = db.ListObjectsAsOf(ctx, "person", afterDelete); err != nil || len(objects) != 0 {
+		t.Fatalf("ListObjectsAsOf(after the delete) = %v, %v, want none", objects, err)
+	}
+}
+
+func TestRestoreFromHistory(t *testing.T) {
+	db, _ := newTestDB(t, WithHistory(0))
+	ctx := context.Background()
+
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	good := tick()
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Mistake"}); err != nil {
+		t.Fatal(err)
+	}
+	tick()
+	if err := db.DeleteObject(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring is storing a version read from the history again.
+	old, err := db.GetObjectAsOf(ctx, "1", good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Store(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	restored := tick()
+
+	current, err := db.GetObjectByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.(*Person).LastName != "Doe" {
+		t.Fatalf("restored %q, want %q", current.(*Person).LastName, "Doe")
+	}
+	if got, err := lastNameAsOf(t, db, "1", restored); err != nil || got != "Doe" {
+		t.Fatalf("GetObjectAsOf(after the restore) = %q, %v, want %q", got, err, "Doe")
+	}
+}
+
+func TestPruneHistoryKeepsTheVersionAtTheCutoff(t *testing.T) {
+	const retention = 200 * time.Millisecond
+	db, mr := newTestDB(t, WithHistory(retention))
+	ctx := context.Background()
+	kind := (&Person{}).GetKind()
+
+	for _, lastName := range []string{"Doe", "Roe", "Poe"} {
+		if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: lastName}); err != nil {
+			t.Fatal(err)
+		}
+		tick()
+	}
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "2", Name: "Jane"}, LastName: "Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	tick()
+	if err := db.DeleteObject(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * retention)
+
+	pruned, err := db.PruneHistory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two older versions of 1, and all of the deleted 2.
+	if pruned != 4 {
+		t.Fatalf("PruneHistory() = %d, want 4", pruned)
+	}
+	if got, err := lastNameAsOf(t, db, "1", time.Now()); err != nil || got != "Poe" {
+		t.Fatalf("GetObjectAsOf(now) = %q, %v, want %q", got, err, "Poe")
+	}
+	if mr.Exists(historyKey(kind, "2")) {
+		t.Fatal("the history of a deleted object outlived the retention")
+	}
+	if _, err := db.GetObjectAsOf(ctx, "1", time.Now().Add(-2*retention)); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("GetObjectAsOf(before the retention) = %v, want %v", err, ErrNoHistory)
+	}
+}
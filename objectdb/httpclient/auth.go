@@ -0,0 +1,24 @@
+package httpclient
+
+import "net/http"
+
+// TokenTransport sends Token as a bearer token with every request, e.g.
+//
+//	httpclient.New(url, &http.Client{Transport: &httpclient.TokenTransport{Token: token}})
+//
+// A nil Base uses http.DefaultTransport.
+type TokenTransport struct {
+	Token string
+	Base  http.RoundTripper
+}
+
+func (t *TokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.Token)
+	return base.RoundTrip(r)
+}
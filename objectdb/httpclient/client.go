@@ -0,0 +1,244 @@
+// Package httpclient is a typed client for the REST API served by
+// httpserver. The generic methods work with any kind; kinds_gen.go adds
+// typed methods per registered kind, such as GetPerson and ListAnimals.
+package httpclient
+
+//go:generate go run ../../cmd/clientgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-assignment/objectdb"
+	"go-assignment/objectdb/httpserver"
+)
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080". A nil httpClient uses http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: httpClient}
+}
+
+// Error is an error response from the server. It unwraps to the matching
+// objectdb error, so errors.Is(err, objectdb.ErrNotFound) and
+// errors.As(err, &validationErr) work as with a local store.
+type Error struct {
+	StatusCode int
+	Response   httpserver.ErrorResponse
+	err        error
+}
+
+func (e *Error) Error() string {
+	return e.Response.Error
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Retryable reports whether the server asked to be tried again later or
+// could not reach its backend, for objectdb.IsRetryable.
+func (e *Error) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// ErrAlreadyExists is returned by Create when the ID is taken.
+var ErrAlreadyExists = errors.New("already exists")
+
+var sentinels = map[string]error{
+	httpserver.CodeNotFound:         objectdb.ErrNotFound,
+	httpserver.CodeUnknownKind:      objectdb.ErrUnknownKind,
+	httpserver.CodeAlreadyExists:    ErrAlreadyExists,
+	httpserver.CodeBrokenReference:  objectdb.ErrBrokenReference,
+	httpserver.CodeRestricted:       objectdb.ErrRestricted,
+	httpserver.CodeQuotaExceeded:    objectdb.ErrQuotaExceeded,
+	httpserver.CodeTooLarge:         objectdb.ErrObjectTooLarge,
+	httpserver.CodeDenied:           objectdb.ErrDenied,
+	httpserver.CodeForbidden:        objectdb.ErrForbidden,
+	httpserver.CodeInvalidPageToken: objectdb.ErrInvalidPageToken,
+	httpserver.CodeInvalidPatch:     objectdb.ErrInvalidPatch,
+}
+
+// Kinds lists the kinds the server knows.
+func (c *Client) Kinds(ctx context.Context) ([]string, error) {
+	var kinds []string
+	err := c.do(ctx, http.MethodGet, "/kinds", nil, nil, &kinds)
+	return kinds, err
+}
+
+// Get reads kind/id into out.
+func (c *Client) Get(ctx context.Context, kind, id string, out objectdb.Object) error {
+	return c.do(ctx, http.MethodGet, objectPath(kind, id), nil, nil, out)
+}
+
+// Create stores a new object, failing with ErrAlreadyExists if its ID is
+// taken, and updates object with the metadata the server stamped.
+func (c *Client) Create(ctx context.Context, kind string, object objectdb.Object) error {
+	return c.do(ctx, http.MethodPost, objectPath(kind, object.GetID()), nil, object, object)
+}
+
+// Put creates or replaces object and updates it with the metadata the
+// server stamped.
+func (c *Client) Put(ctx context.Context, kind string, object objectdb.Object) error {
+	return c.do(ctx, http.MethodPut, objectPath(kind, object.GetID()), nil, object, object)
+}
+
+// Patch applies the RFC 6902 JSON Patch document patch to kind/id and reads
+// the patched object into out.
+func (c *Client) Patch(ctx context.Context, kind, id string, patch []byte, out objectdb.Object) error {
+	return c.do(ctx, http.MethodPatch, objectPath(kind, id), nil, json.RawMessage(patch), out)
+}
+
+// Delete deletes kind/id. A zero policy leaves dependents alone.
+func (c *Client) Delete(ctx context.Context, kind, id string, policy objectdb.DeletePolicy) error {
+	query := url.Values{}
+	switch policy {
+	case 0:
+	case objectdb.DeleteCascade:
+		query.Set("policy", "cascade")
+	case objectdb.DeleteRestrict:
+		query.Set("policy", "restrict")
+	case objectdb.DeleteOrphan:
+		query.Set("policy", "orphan")
+	default:
+		return fmt.Errorf("unknown delete policy %d", policy)
+	}
+
+	return c.do(ctx, http.MethodDelete, objectPath(kind, id), query, nil, nil)
+}
+
+// ListPage lists one page of kind starting at cursor, "" for the first
+// page, and returns the cursor of the next; "" means done.
+func (c *Client) ListPage(ctx context.Context, kind, cursor string) ([]objectdb.Object, string, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	raw, next, err := listPage[json.RawMessage](ctx, c, kind, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	objects, err := decodeObjects(kind, raw)
+	return objects, next, err
+}
+
+// ListBySelector lists the objects of kind matching a label selector.
+func (c *Client) ListBySelector(ctx context.Context, kind, selector string) ([]objectdb.Object, error) {
+	raw, _, err := listPage[json.RawMessage](ctx, c, kind, url.Values{"selector": {selector}})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeObjects(kind, raw)
+}
+
+func decodeObjects(kind string, raw []json.RawMessage) ([]objectdb.Object, error) {
+	objects := make([]objectdb.Object, 0, len(raw))
+	for _, data := range raw {
+		object, err := objectdb.NewObject(kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// listPage lists one page of kind, decoding items as T.
+func listPage[T any](ctx context.Context, c *Client, kind string, query url.Values) ([]T, string, error) {
+	var resp struct {
+		Items  []T    `json:"items"`
+		Cursor string `json:"cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/kinds/"+url.PathEscape(kind)+"/objects", query, nil, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Items, resp.Cursor, nil
+}
+
+func objectPath(kind, id string) string {
+	return "/kinds/" + url.PathEscape(kind) + "/objects/" + url.PathEscape(id)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(&e.Response); err != nil || e.Response.Error == "" {
+		e.Response.Error = resp.Status
+	}
+
+	if e.Response.Code == httpserver.CodeValidation {
+		e.err = &objectdb.ValidationError{Violations: e.Response.Violations}
+	} else {
+		e.err = sentinels[e.Response.Code]
+	}
+
+	return e
+}
@@ -0,0 +1,110 @@
+// Code generated by clientgen. DO NOT EDIT.
+
+package httpclient
+
+import (
+	"context"
+	"net/url"
+
+	"go-assignment/objectdb"
+)
+
+// GetAnimal reads the Animal with the given ID.
+func (c *Client) GetAnimal(ctx context.Context, id string) (*objectdb.Animal, error) {
+	var out objectdb.Animal
+	if err := c.Get(ctx, "animal", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAnimal stores object, which must not exist yet; see Client.Create.
+func (c *Client) CreateAnimal(ctx context.Context, object *objectdb.Animal) error {
+	return c.Create(ctx, "animal", object)
+}
+
+// PutAnimal creates or replaces object; see Client.Put.
+func (c *Client) PutAnimal(ctx context.Context, object *objectdb.Animal) error {
+	return c.Put(ctx, "animal", object)
+}
+
+// PatchAnimal applies a JSON Patch to the Animal with the given ID and
+// returns the result; see Client.Patch.
+func (c *Client) PatchAnimal(ctx context.Context, id string, patch []byte) (*objectdb.Animal, error) {
+	var out objectdb.Animal
+	if err := c.Patch(ctx, "animal", id, patch, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAnimal deletes the Animal with the given ID; see Client.Delete.
+func (c *Client) DeleteAnimal(ctx context.Context, id string, policy objectdb.DeletePolicy) error {
+	return c.Delete(ctx, "animal", id, policy)
+}
+
+// ListAnimals lists one page of Animal objects starting at cursor,
+// "" for the first page, and returns the cursor of the next; "" means done.
+func (c *Client) ListAnimals(ctx context.Context, cursor string) ([]*objectdb.Animal, string, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	return listPage[*objectdb.Animal](ctx, c, "animal", query)
+}
+
+// ListAnimalsBySelector lists the Animal objects matching a label selector.
+func (c *Client) ListAnimalsBySelector(ctx context.Context, selector string) ([]*objectdb.Animal, error) {
+	items, _, err := listPage[*objectdb.Animal](ctx, c, "animal", url.Values{"selector": {selector}})
+	return items, err
+}
+
+// GetPerson reads the Person with the given ID.
+func (c *Client) GetPerson(ctx context.Context, id string) (*objectdb.Person, error) {
+	var out objectdb.Person
+	if err := c.Get(ctx, "person", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePerson stores object, which must not exist yet; see Client.Create.
+func (c *Client) CreatePerson(ctx context.Context, object *objectdb.Person) error {
+	return c.Create(ctx, "person", object)
+}
+
+// PutPerson creates or replaces object; see Client.Put.
+func (c *Client) PutPerson(ctx context.Context, object *objectdb.Person) error {
+	return c.Put(ctx, "person", object)
+}
+
+// PatchPerson applies a JSON Patch to the Person with the given ID and
+// returns the result; see Client.Patch.
+func (c *Client) PatchPerson(ctx context.Context, id string, patch []byte) (*objectdb.Person, error) {
+	var out objectdb.Person
+	if err := c.Patch(ctx, "person", id, patch, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePerson deletes the Person with the given ID; see Client.Delete.
+func (c *Client) DeletePerson(ctx context.Context, id string, policy objectdb.DeletePolicy) error {
+	return c.Delete(ctx, "person", id, policy)
+}
+
+// ListPersons lists one page of Person objects starting at cursor,
+// "" for the first page, and returns the cursor of the next; "" means done.
+func (c *Client) ListPersons(ctx context.Context, cursor string) ([]*objectdb.Person, string, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	return listPage[*objectdb.Person](ctx, c, "person", query)
+}
+
+// ListPersonsBySelector lists the Person objects matching a label selector.
+func (c *Client) ListPersonsBySelector(ctx context.Context, selector string) ([]*objectdb.Person, error) {
+	items, _, err := listPage[*objectdb.Person](ctx, c, "person", url.Values{"selector": {selector}})
+	return items, err
+}
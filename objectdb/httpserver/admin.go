@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-assignment/objectdb"
+)
+
+//go:embed admin
+var adminFiles embed.FS
+
+// adminAPIPrefix is where the admin UI reaches the object API, behind its
+// own login rather than the API's.
+const adminAPIPrefix = "/admin/api"
+
+// WithAdminUI serves a small dashboard at /admin/ for browsing, searching
+// and editing objects, behind HTTP basic auth with the given credentials.
+// The UI is off unless this option is given. Its calls to the object API
+// go through /admin/api/ behind the same login; with WithAccessPolicy they
+// run as the principal AdminPrincipal(username), which the policy has to
+// grant access like any other. Browsers resend basic auth credentials on
+// their own, so writes through /admin/api/ must come from the UI's own
+// origin and carry a JSON body; see checkAdminWrite.
+func WithAdminUI(username, password string) Option {
+	return func(s *Server) {
+		files, _ := fs.Sub(adminFiles, "admin")
+		ui := http.StripPrefix("/admin/", http.FileServer(http.FS(files)))
+		principal := AdminPrincipal(username)
+
+		s.admin = basicAuth(username, password, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/admin" {
+				http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+				return
+			}
+			if path, ok := strings.CutPrefix(r.URL.Path, adminAPIPrefix); ok && strings.HasPrefix(path, "/") {
+				if err := checkAdminWrite(r); errors.Is(err, errNotJSON) {
+					writeError(w, http.StatusUnsupportedMediaType, CodeBadRequest, err)
+					return
+				} else if err != nil {
+					writeError(w, http.StatusForbidden, CodeForbidden, err)
+					return
+				}
+				r = r.Clone(objectdb.ContextWithPrincipal(r.Context(), principal))
+				r.URL.Path, r.URL.RawPath = path, ""
+				s.serveAPI(w, r)
+				return
+			}
+			ui.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// AdminPrincipal is the principal the admin UI of username acts as, named
+// "admin:<username>" so that it cannot be mistaken for a token or client
+// certificate.
+func AdminPrincipal(username string) objectdb.Principal {
+	return objectdb.Principal{Name: "admin:" + username}
+}
+
+// checkAdminWrite guards admin API writes against cross-site request
+// forgery. Another site can make a logged-in browser send a form, which
+// carries the admin's credentials: writes are refused unless Origin, or
+// failing that Sec-Fetch-Site, says they come from this host, and bodies
+// must be JSON, which forms cannot send without a CORS preflight.
+func checkAdminWrite(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return fmt.Errorf("cross-origin admin request from '%s'", origin)
+		}
+	} else if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return fmt.Errorf("cross-site admin request (%s)", site)
+	}
+
+	if r.Method == http.MethodDelete {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/json-patch+json" {
+		return errNotJSON
+	}
+
+	return nil
+}
+
+var errNotJSON = errors.New("admin API writes need a JSON body with Content-Type: application/json")
+
+func basicAuth(username, password string, next http.Handler) http.Handler {
+	wantUser, wantPass := sha256.Sum256([]byte(username)), sha256.Sum256([]byte(password))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		gotUser, gotPass := sha256.Sum256([]byte(user)), sha256.Sum256([]byte(pass))
+		// Compare hashes so the comparison takes the same time whatever
+		// the lengths involved.
+		if !ok || subtle.ConstantTimeCompare(gotUser[:], wantUser[:])&subtle.ConstantTimeCompare(gotPass[:], wantPass[:]) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="objectdb admin", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
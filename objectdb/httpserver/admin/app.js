@@ -0,0 +1,154 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+let kind = null;      // short name of the kind being browsed
+let cursor = "";      // cursor of the next page, "" for the first
+let done = true;      // whether every page was loaded
+let objects = [];     // objects loaded so far
+let editing = null;   // ID of the object in the editor, null for a new one
+
+function shortName(kind) {
+  return kind.slice(kind.lastIndexOf(".") + 1).replace(/^\*/, "").toLowerCase();
+}
+
+async function api(method, path, body) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+  // Relative to /admin/, so calls go through the admin login.
+  const resp = await fetch("api" + path, opts);
+  if (resp.status === 204) return null;
+  const data = await resp.json();
+  if (!resp.ok) {
+    const violations = (data.violations || []).map((v) => `${v.field}: ${v.message}`);
+    throw new Error([data.error, ...violations].join("\n"));
+  }
+  return data;
+}
+
+async function loadKinds() {
+  const list = $("kinds");
+  list.innerHTML = "";
+  for (const full of await api("GET", "/kinds")) {
+    const name = shortName(full);
+    const li = document.createElement("li");
+    li.textContent = name;
+    li.dataset.kind = name;
+    const count = document.createElement("span");
+    count.className = "count";
+    li.appendChild(count);
+    li.onclick = () => browse(name);
+    list.appendChild(li);
+    api("GET", `/kinds/${name}/count`).then((c) => { count.textContent = c.count; });
+  }
+}
+
+async function browse(name, selector) {
+  kind = name;
+  objects = [];
+  cursor = "";
+  done = false;
+  for (const li of $("kinds").children) li.classList.toggle("active", li.dataset.kind === name);
+  $("kind-title").textContent = name;
+  $("browser").hidden = false;
+  $("editor").hidden = true;
+  if (selector) {
+    const page = await api("GET", `/kinds/${name}/objects?selector=${encodeURIComponent(selector)}`);
+    objects = page.items;
+    done = true;
+  } else {
+    await loadMore();
+  }
+  render();
+}
+
+async function loadMore() {
+  // A SCAN page may be empty before the end, so keep going until something
+  // arrives or the listing is done.
+  do {
+    const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : "";
+    const page = await api("GET", `/kinds/${kind}/objects${query}`);
+    objects.push(...page.items);
+    cursor = page.cursor || "";
+    done = !cursor;
+  } while (!done && objects.length === 0);
+}
+
+function render() {
+  const term = $("search").value.toLowerCase();
+  const body = $("objects");
+  body.innerHTML = "";
+  const shown = objects
+    .filter((o) => !term || JSON.stringify(o).toLowerCase().includes(term))
+    .sort((a, b) => (a.id < b.id ? -1 : a.id > b.id ? 1 : 0));
+  for (const o of shown) {
+    const tr = document.createElement("tr");
+    const labels = Object.entries(o.labels || {}).map(([k, v]) => `${k}=${v}`).join(", ");
+    for (const text of [o.id, o.name, o.version, labels, o.updated_at || ""]) {
+      const td = document.createElement("td");
+      td.textContent = text;
+      tr.appendChild(td);
+    }
+    tr.onclick = () => edit(o);
+    body.appendChild(tr);
+  }
+  $("load-more").hidden = done;
+}
+
+function edit(object) {
+  editing = object ? object.id : null;
+  $("editor-title").textContent = object ? `${kind} ${object.id}` : `new ${kind}`;
+  $("json").value = JSON.stringify(object || { id: "", name: "" }, null, 2);
+  $("delete").hidden = !object;
+  setStatus("");
+  $("editor").hidden = false;
+}
+
+function setStatus(text, error) {
+  $("status").textContent = text;
+  $("status").className = error ? "error" : "";
+}
+
+async function save() {
+  try {
+    const object = JSON.parse($("json").value);
+    if (!object.id) throw new Error("id is required");
+    const method = editing === null ? "POST" : "PUT";
+    const saved = await api(method, `/kinds/${kind}/objects/${encodeURIComponent(object.id)}`, object);
+    editing = saved.id;
+    $("json").value = JSON.stringify(saved, null, 2);
+    setStatus(`saved version ${saved.version}`);
+    const i = objects.findIndex((o) => o.id === saved.id);
+    if (i >= 0) objects[i] = saved; else objects.push(saved);
+    render();
+    loadKinds();
+  } catch (e) {
+    setStatus(e.message, true);
+  }
+}
+
+async function remove() {
+  if (!confirm(`Delete ${kind} ${editing}?`)) return;
+  try {
+    await api("DELETE", `/kinds/${kind}/objects/${encodeURIComponent(editing)}`);
+    objects = objects.filter((o) => o.id !== editing);
+    $("editor").hidden = true;
+    render();
+    loadKinds();
+  } catch (e) {
+    setStatus(e.message, true);
+  }
+}
+
+$("search").oninput = render;
+$("apply-selector").onclick = () => browse(kind, $("selector").value.trim());
+$("load-more").onclick = async () => { await loadMore(); render(); };
+$("new-object").onclick = () => edit(null);
+$("save").onclick = save;
+$("delete").onclick = remove;
+$("close").onclick = () => { $("editor").hidden = true; };
+
+loadKinds();
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"go-assignment/objectdb"
+)
+
+func newAdminServer(t *testing.T) (*Server, *objectdb.RedisObjectDB) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	db := objectdb.NewRedisObjectDB(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { db.Close() })
+
+	return New(db, WithAdminUI("admin", "secret")), db
+}
+
+func adminRequest(method, path, contentType, body string, header map[string]string) *http.Request {
+	r := httptest.NewRequest(method, "http://objects.example"+path, strings.NewReader(body))
+	r.SetBasicAuth("admin", "secret")
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+
+	return r
+}
+
+func TestAdminAPIRejectsCrossSiteWrites(t *testing.T) {
+	s, db := newAdminServer(t)
+	const path = "/admin/api/kinds/person/objects/1"
+	body := `{"name":"Mallory"}`
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		header      map[string]string
+		want        int
+	}{
+		{"text/plain form", http.MethodPost, "text/plain", nil, http.StatusUnsupportedMediaType},
+		{"urlencoded form", http.MethodPut, "application/x-www-form-urlencoded", nil, http.StatusUnsupportedMediaType},
+		{"no content type", http.MethodPost, "", nil, http.StatusUnsupportedMediaType},
+		{"foreign origin", http.MethodPost, "application/json", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"cross-site fetch", http.MethodPut, "application/json", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"cross-site delete", http.MethodDelete, "", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, adminRequest(tt.method, path, tt.contentType, body, tt.header))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	if _, err := db.GetObjectByID(context.Background(), "1"); err == nil {
+		t.Fatal("a rejected request stored the object")
+	}
+}
+
+func TestAdminAPIAcceptsSameOriginJSON(t *testing.T) {
+	s, db := newAdminServer(t)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, adminRequest(http.MethodPost, "/admin/api/kinds/person/objects/1", "application/json", `{"name":"Ann"}`,
+		map[string]string{"Origin": "http://objects.example", "Sec-Fetch-Site": "same-origin"}))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if _, err := db.GetObjectByID(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, adminRequest(http.MethodGet, "/admin/api/kinds/person/objects/1", "", "", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-assignment/objectdb"
+)
+
+// WithAccessPolicy enforces policy on the object routes. authenticate
+// names the principal behind a request, from a token, client certificate
+// or whatever the deployment uses; requests it does not recognise get 401.
+// Health checks, /openapi.json and the admin UI's static files are not
+// covered; the admin UI has its own login, and its API calls run as
+// AdminPrincipal.
+func WithAccessPolicy(policy *objectdb.AccessPolicy, authenticate func(*http.Request) (objectdb.Principal, bool)) Option {
+	return func(s *Server) {
+		s.policy = policy
+		s.authenticate = authenticate
+	}
+}
+
+// withPrincipal returns r with its principal in the context, or writes a
+// 401 and returns nil.
+func (s *Server) withPrincipal(w http.ResponseWriter, r *http.Request) *http.Request {
+	if s.policy == nil {
+		return r
+	}
+
+	principal, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="objectdb"`)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, fmt.Errorf("unauthenticated request"))
+		return nil
+	}
+
+	return r.WithContext(objectdb.ContextWithPrincipal(r.Context(), principal))
+}
+
+// BearerTokens authenticates requests by their "Authorization: Bearer"
+// header.
+func BearerTokens(tokens *objectdb.TokenSet) func(*http.Request) (objectdb.Principal, bool) {
+	return func(r *http.Request) (objectdb.Principal, bool) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return objectdb.Principal{}, false
+		}
+
+		return tokens.Authenticate(strings.TrimSpace(token))
+	}
+}
+
+// ClientCertificates authenticates requests by their verified TLS client
+// certificate, see objectdb.PrincipalFromCertificate. The server's TLS
+// config must verify client certificates for there to be any.
+func ClientCertificates(r *http.Request) (objectdb.Principal, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return objectdb.Principal{}, false
+	}
+
+	return objectdb.PrincipalFromCertificate(r.TLS.VerifiedChains[0][0])
+}
+
+// FirstOf authenticates requests with the first of authenticators that
+// recognises them.
+func FirstOf(authenticators ...func(*http.Request) (objectdb.Principal, bool)) func(*http.Request) (objectdb.Principal, bool) {
+	return func(r *http.Request) (objectdb.Principal, bool) {
+		for _, authenticate := range authenticators {
+			if p, ok := authenticate(r); ok {
+				return p, true
+			}
+		}
+
+		return objectdb.Principal{}, false
+	}
+}
@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-assignment/objectdb"
+)
+
+// heartbeatInterval keeps idle event streams from being cut by proxies.
+const heartbeatInterval = 15 * time.Second
+
+type changeEvent struct {
+	Kind   string          `json:"kind"`
+	ID     string          `json:"id"`
+	Object objectdb.Object `json:"object,omitempty"`
+}
+
+// events streams the change feed as Server-Sent Events, optionally for one
+// ?kind=. Each event is named after the change type ("put" or "delete")
+// and its id is the feed cursor, so a reconnecting EventSource resumes
+// from where it stopped through Last-Event-ID. The store needs
+// objectdb.WithChangeFeed for any events to appear.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	var kind string
+	if k := r.URL.Query().Get("kind"); k != "" {
+		object, err := objectdb.NewObject(k)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		kind = object.GetKind()
+	}
+	// Changes are not filtered by namespace, so watching takes list
+	// access to all of them.
+	watched := kind
+	if watched == "" {
+		watched = "*"
+	}
+	if err := s.policy.Authorize(r.Context(), objectdb.VerbList, watched, objectdb.AllNamespaces); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("cursor")
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	changes := make(chan objectdb.Change)
+	it := s.db.Watch(ctx, kind, cursor)
+	go func() {
+		defer close(changes)
+		for it.Next(ctx) {
+			select {
+			case changes <- it.Change():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				if err := it.Err(); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", jsonString(err.Error()))
+					flusher.Flush()
+				}
+				return
+			}
+
+			data, err := json.Marshal(changeEvent{Kind: change.Kind, ID: change.ID, Object: change.Object})
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.Cursor, change.Type, data)
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,285 @@
+package httpserver
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go-assignment/objectdb"
+)
+
+// OpenAPI describes the routes served by Server as an OpenAPI 3 document,
+// with one schema per registered kind derived from its Go type and
+// validation rules. Server serves it at /openapi.json.
+func OpenAPI() map[string]interface{} {
+	paths := map[string]interface{}{
+		"/kinds": map[string]interface{}{
+			"get": operation("listKinds", "Registered kinds", nil, nil,
+				response("200", "Kind names", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}})),
+		},
+	}
+	events := operation("watchEvents", "Stream changes as Server-Sent Events", []interface{}{
+		parameter("kind", "query", false, "Only changes to this kind"),
+		parameter("cursor", "query", false, "Resume after this event id; Last-Event-ID takes precedence"),
+	}, nil)
+	events["responses"].(map[string]interface{})["200"] = map[string]interface{}{
+		"description": `"put" and "delete" events whose data is {"kind", "id", "object"}`,
+		"content":     map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+	}
+	paths["/events"] = map[string]interface{}{"get": events}
+
+	schemas := map[string]interface{}{
+		"Error": typeSchema(reflect.TypeOf(ErrorResponse{})),
+	}
+
+	for _, kind := range objectdb.Kinds() {
+		object, _ := objectdb.NewObject(kind)
+		t := reflect.TypeOf(object).Elem()
+		name, short := t.Name(), strings.ToLower(t.Name())
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+
+		schema := typeSchema(t)
+		applyRules(schema, kind)
+		schemas[name] = schema
+		schemas[name+"List"] = map[string]interface{}{
+			"type":     "object",
+			"required": []string{"items", "cursor"},
+			"properties": map[string]interface{}{
+				"items":  map[string]interface{}{"type": "array", "items": ref},
+				"cursor": map[string]interface{}{"type": "string", "description": `Pass as ?cursor=, with the same filters and order, to continue; empty once the listing is complete.`},
+			},
+		}
+
+		idParam := parameter("id", "path", true, "Object ID")
+		policyParam := parameter("policy", "query", false, "What happens to objects referencing this one")
+		policyParam["schema"] = map[string]interface{}{"type": "string", "enum": []string{"cascade", "restrict", "orphan"}}
+		orderParam := parameter("order", "query", false, "Sort order, id by default")
+		orderParam["schema"] = map[string]interface{}{"type": "string", "enum": []string{"id", "created_at"}}
+
+		paths["/kinds/"+short+"/objects"] = map[string]interface{}{
+			"get": operation("list"+name+"s", "List "+name+" objects", []interface{}{
+				parameter("filter", "query", false, "Field filter, e.g. type=Cat or version>=2; may be repeated"),
+				orderParam,
+				parameter("limit", "query", false, "Most objects to return, 100 by default"),
+				parameter("cursor", "query", false, "Cursor returned by the previous page"),
+				parameter("selector", "query", false, "Label selector, e.g. env=prod,tier!=db; disables paging"),
+			}, nil, response("200", name+" page", map[string]interface{}{"$ref": "#/components/schemas/" + name + "List"})),
+		}
+		paths["/kinds/"+short+"/count"] = map[string]interface{}{
+			"get": operation("count"+name+"s", "Count "+name+" objects", []interface{}{
+				parameter("filter", "query", false, "Field filter, as for listing; may be repeated"),
+			}, nil,
+				response("200", name+" count", map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"count": map[string]interface{}{"type": "integer", "format": "int64"}},
+				})),
+		}
+		paths["/kinds/"+short+"/groups"] = map[string]interface{}{
+			"get": operation("group"+name+"s", "Count "+name+" objects per field value", []interface{}{
+				parameter("by", "query", false, "Field to group by, e.g. type; one group of every object if omitted"),
+				parameter("measure", "query", false, "Numeric or time field to summarize per group, times in microseconds since the epoch; may be repeated"),
+			}, nil, response("200", "Groups in order of value", map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{"groups": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"value": map[string]interface{}{"type": "string"},
+							"count": map[string]interface{}{"type": "integer", "format": "int64"},
+							"stats": map[string]interface{}{
+								"type": "object",
+								"additionalProperties": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"count": map[string]interface{}{"type": "integer", "format": "int64"},
+										"sum":   map[string]interface{}{"type": "number"},
+										"min":   map[string]interface{}{"type": "number"},
+										"max":   map[string]interface{}{"type": "number"},
+										"avg":   map[string]interface{}{"type": "number"},
+									},
+								},
+							},
+						},
+					},
+				}},
+			})),
+		}
+		paths["/kinds/"+short+"/names"] = map[string]interface{}{
+			"get": operation("complete"+name+"Names", "Complete "+name+" names", []interface{}{
+				parameter("prefix", "query", false, "Start of the name, in any case"),
+				parameter("limit", "query", false, "Most names to return, 10 by default"),
+			}, nil, response("200", "Matching names in alphabetical order",
+				map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}})),
+		}
+		paths["/kinds/"+short+"/objects/{id}"] = map[string]interface{}{
+			"get": operation("get"+name, "Read a "+name, []interface{}{idParam}, nil,
+				response("200", "The "+name, ref)),
+			"post": operation("create"+name, "Create a "+name, []interface{}{idParam}, ref,
+				response("201", "Created", ref)),
+			"put": operation("put"+name, "Create or replace a "+name, []interface{}{idParam}, ref,
+				response("200", "Replaced", ref), response("201", "Created", ref)),
+			"patch": patchOperation("patch"+name, "Apply a JSON Patch to a "+name, idParam,
+				response("200", "Patched", ref)),
+			"delete": operation("delete"+name, "Delete a "+name, []interface{}{idParam, policyParam}, nil,
+				map[string]interface{}{"204": map[string]interface{}{"description": "Deleted"}}),
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "objectdb",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+func operation(id, summary string, params []interface{}, body interface{}, responses ...map[string]interface{}) map[string]interface{} {
+	all := map[string]interface{}{
+		"default": map[string]interface{}{
+			"description": "Error; code identifies the store error",
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+		},
+	}
+	for _, r := range responses {
+		for status, v := range r {
+			all[status] = v
+		}
+	}
+
+	op := map[string]interface{}{
+		"operationId": id,
+		"summary":     summary,
+		"responses":   all,
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = map[string]interface{}{"required": true, "content": jsonContent(body)}
+	}
+
+	return op
+}
+
+func response(status, description string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		status: map[string]interface{}{"description": description, "content": jsonContent(schema)},
+	}
+}
+
+func parameter(name, in string, required bool, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          in,
+		"required":    required,
+		"description": description,
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+}
+
+// patchOperation is an operation taking an RFC 6902 JSON Patch document.
+func patchOperation(id, summary string, idParam map[string]interface{}, responses ...map[string]interface{}) map[string]interface{} {
+	op := operation(id, summary, []interface{}{idParam}, nil, responses...)
+	op["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{"application/json-patch+json": map[string]interface{}{"schema": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "object", "required": []string{"op", "path"}},
+		}}},
+	}
+
+	return op
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema maps a Go type to a schema the way encoding/json would
+// marshal it.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for _, f := range reflect.VisibleFields(t) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = typeSchema(f.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+
+	return map[string]interface{}{}
+}
+
+// applyRules adds the kind's registered validation rules on top-level
+// fields to schema.
+func applyRules(schema map[string]interface{}, kind string) {
+	rules, ok := objectdb.LookupSchema(kind)
+	if !ok {
+		return
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	var required []string
+	for path, rule := range rules.Fields {
+		prop, ok := properties[path].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if rule.Required {
+			required = append(required, path)
+		}
+		if rule.Format != "" {
+			prop["format"] = rule.Format
+		}
+		if rule.Pattern != "" {
+			prop["pattern"] = "^(?:" + rule.Pattern + ")$"
+		}
+		if len(rule.Enum) > 0 {
+			prop["enum"] = rule.Enum
+		}
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+}
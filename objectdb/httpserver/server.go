@@ -0,0 +1,523 @@
+// Package httpserver exposes an ObjectDB over a JSON REST API:
+//
+//	GET    /kinds                           registered kinds
+//	GET    /kinds/{kind}/objects            objects of a kind, one SCAN page at a time
+//	GET    /kinds/{kind}/count              number of objects of a kind, ?filter=
+//	GET    /kinds/{kind}/groups             objects per value of ?by=, stats of ?measure=
+//	GET    /kinds/{kind}/names              names starting with ?prefix=, for typeahead
+//	POST   /kinds/{kind}/objects/{id}       create, 409 if the ID is taken
+//	GET    /kinds/{kind}/objects/{id}       read
+//	PUT    /kinds/{kind}/objects/{id}       create or replace
+//	PATCH  /kinds/{kind}/objects/{id}       apply a JSON Patch (RFC 6902)
+//	DELETE /kinds/{kind}/objects/{id}       delete, ?policy=cascade|restrict|orphan
+//
+// Kinds may be given by their short name, e.g. /kinds/person/objects/123.
+// GET /events streams changes as Server-Sent Events, and GET /openapi.json
+// describes the rest.
+// Listing takes ?filter=, e.g. ?filter=type=Cat, any number of times,
+// ?order=id|created_at, ?limit= and ?cursor= to continue a previous page of
+// the same filters and order, or ?selector= to filter by labels instead.
+// WithAccessPolicy limits what each caller may see and change.
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-assignment/objectdb"
+)
+
+// defaultMaxBodySize bounds request bodies for stores without
+// WithMaxObjectSize.
+const defaultMaxBodySize = 10 << 20
+
+type Server struct {
+	db     *objectdb.RedisObjectDB
+	health http.Handler
+	admin  http.Handler
+
+	policy       *objectdb.AccessPolicy
+	authenticate func(*http.Request) (objectdb.Principal, bool)
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+func New(db *objectdb.RedisObjectDB, opts ...Option) *Server {
+	s := &Server{db: db, health: objectdb.NewHealthHandler(db)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+type listResponse struct {
+	Items []objectdb.Object `json:"items"`
+	// Cursor is a signed token resuming the listing, empty once every
+	// object was returned.
+	Cursor string `json:"cursor"`
+}
+
+// ErrorResponse is the body of every error. Code tells clients which store
+// error occurred without parsing the message.
+type ErrorResponse struct {
+	Error      string               `json:"error"`
+	Code       string               `json:"code"`
+	Violations []objectdb.Violation `json:"violations,omitempty"`
+}
+
+const (
+	CodeBadRequest       = "bad_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotFound         = "not_found"
+	CodeUnknownKind      = "unknown_kind"
+	CodeAlreadyExists    = "already_exists"
+	CodeValidation       = "validation"
+	CodeBrokenReference  = "broken_reference"
+	CodeRestricted       = "restricted"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeDenied           = "denied"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeTooLarge         = "too_large"
+	CodeInvalidPageToken = "invalid_page_token"
+	CodeInvalidPatch     = "invalid_patch"
+	CodeInternal         = "internal"
+)
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+		s.health.ServeHTTP(w, r)
+		return
+	}
+	if r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/") {
+		if s.admin == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.admin.ServeHTTP(w, r)
+		return
+	}
+	if r.URL.Path == "/openapi.json" {
+		if s.allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, OpenAPI())
+		}
+		return
+	}
+	if r = s.withPrincipal(w, r); r == nil {
+		return
+	}
+	s.serveAPI(w, r)
+}
+
+// serveAPI routes the object API and /events, for requests carrying their
+// principal.
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/events" {
+		if s.allow(w, r, http.MethodGet) {
+			s.events(w, r)
+		}
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "kinds" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if s.allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, objectdb.Kinds())
+		}
+
+	case len(parts) == 3 && parts[2] == "objects":
+		if s.allow(w, r, http.MethodGet) {
+			s.list(w, r, parts[1])
+		}
+
+	case len(parts) == 3 && parts[2] == "count":
+		if s.allow(w, r, http.MethodGet) {
+			s.count(w, r, parts[1])
+		}
+
+	case len(parts) == 3 && parts[2] == "groups":
+		if s.allow(w, r, http.MethodGet) {
+			s.groups(w, r, parts[1])
+		}
+
+	case len(parts) == 3 && parts[2] == "names":
+		if s.allow(w, r, http.MethodGet) {
+			s.names(w, r, parts[1])
+		}
+
+	case len(parts) == 4 && parts[2] == "objects" && parts[3] != "":
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, r, parts[1], parts[3])
+		case http.MethodPost:
+			s.put(w, r, parts[1], parts[3], true)
+		case http.MethodPut:
+			s.put(w, r, parts[1], parts[3], false)
+		case http.MethodPatch:
+			s.patch(w, r, parts[1], parts[3])
+		case http.MethodDelete:
+			s.delete(w, r, parts[1], parts[3])
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, PATCH, DELETE")
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, kind string) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	resp := listResponse{Items: []objectdb.Object{}}
+	query := r.URL.Query()
+
+	if query.Has("selector") {
+		selector, err := objectdb.ParseSelector(query.Get("selector"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err)
+			return
+		}
+
+		objects, err := s.db.ListObjectsByLabels(r.Context(), object.GetKind(), selector)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		resp.Items = append(resp.Items, s.policy.FilterObjects(r.Context(), objectdb.VerbList, objects)...)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	req := objectdb.PageRequest{
+		Kind:  object.GetKind(),
+		Order: objectdb.PageOrder(query.Get("order")),
+		Token: query.Get("cursor"),
+	}
+	if req.Order != "" && req.Order != objectdb.OrderByID && req.Order != objectdb.OrderByCreatedAt {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Errorf("invalid order '%s'", req.Order))
+		return
+	}
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if req.Limit, err = strconv.Atoi(l); err != nil || req.Limit < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Errorf("invalid limit '%s'", l))
+			return
+		}
+	}
+	for _, raw := range query["filter"] {
+		f, err := objectdb.ParseFilter(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err)
+			return
+		}
+		req.Filters = append(req.Filters, f)
+	}
+
+	page, err := s.db.ListPage(r.Context(), req)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	resp.Items = append(resp.Items, s.policy.FilterObjects(r.Context(), objectdb.VerbList, page.Items)...)
+	resp.Cursor = page.Next
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) count(w http.ResponseWriter, r *http.Request, kind string) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if err := s.policy.Authorize(r.Context(), objectdb.VerbList, object.GetKind(), objectdb.AllNamespaces); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	var filters []objectdb.Filter
+	for _, raw := range r.URL.Query()["filter"] {
+		f, err := objectdb.ParseFilter(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err)
+			return
+		}
+		filters = append(filters, f)
+	}
+
+	n, err := s.db.Count(r.Context(), object.GetKind(), filters...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"count": n})
+}
+
+// groups counts the objects of kind by their value of ?by=, or all of
+// them without it, and summarizes each ?measure= field over the groups.
+func (s *Server) groups(w http.ResponseWriter, r *http.Request, kind string) {
+	query := r.URL.Query()
+	if err := s.policy.Authorize(r.Context(), objectdb.VerbList, kind, objectdb.AllNamespaces); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	groups, err := s.db.Aggregate(r.Context(), kind, query.Get("by"), query["measure"]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]objectdb.Group{"groups": groups})
+}
+
+// names completes ?prefix= with up to ?limit= names.
+func (s *Server) names(w http.ResponseWriter, r *http.Request, kind string) {
+	limit := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Errorf("invalid limit '%s'", l))
+			return
+		}
+	}
+
+	if err := s.policy.Authorize(r.Context(), objectdb.VerbList, kind, objectdb.AllNamespaces); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	names, err := s.db.AutocompleteName(r.Context(), kind, r.URL.Query().Get("prefix"), limit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, kind, id string) {
+	object, err := s.db.GetObject(r.Context(), kind, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Objects the caller may not get read as missing, not forbidden, so
+	// that the IDs taken in other namespaces do not show.
+	if s.policy.AuthorizeObject(r.Context(), objectdb.VerbGet, object) != nil {
+		writeStoreError(w, fmt.Errorf("%s with ID '%s' %w", object.GetKind(), id, objectdb.ErrNotFound))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, object)
+}
+
+// put stores the request body under id. The ID in the path wins over any
+// ID in the body. With create set, an existing object is a conflict; the
+// check and the write are not atomic, so concurrent creates may both win.
+func (s *Server) put(w http.ResponseWriter, r *http.Request, kind, id string, create bool) {
+	object, err := objectdb.NewObject(kind)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if err := json.NewDecoder(s.limitBody(w, r)).Decode(object); err != nil {
+		writeBodyError(w, fmt.Errorf("decode body: %w", err))
+		return
+	}
+	if body := object.GetID(); body != "" && body != id {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Errorf("body ID '%s' does not match path ID '%s'", body, id))
+		return
+	}
+	object.SetID(id)
+	if err := s.policy.AuthorizeObject(r.Context(), objectdb.VerbStore, object); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	existing, err := s.db.GetObject(r.Context(), kind, id)
+	exists := err == nil
+	if err != nil && !errors.Is(err, objectdb.ErrNotFound) {
+		writeStoreError(w, err)
+		return
+	}
+	if exists {
+		// Moving an object to another namespace needs access to both.
+		if err := s.policy.AuthorizeObject(r.Context(), objectdb.VerbStore, existing); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+	}
+	if create && exists {
+		writeError(w, http.StatusConflict, CodeAlreadyExists, fmt.Errorf("%s with ID '%s' already exists", object.GetKind(), id))
+		return
+	}
+
+	if err := s.db.Store(r.Context(), object); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !exists {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, object)
+}
+
+// patch applies the request body, a JSON Patch, to kind/id. The principal
+// needs access to the object both as it was and as patched, which is
+// checked in the same update that writes it.
+func (s *Server) patch(w http.ResponseWriter, r *http.Request, kind, id string) {
+	patch, err := io.ReadAll(s.limitBody(w, r))
+	if err != nil {
+		writeBodyError(w, fmt.Errorf("read body: %w", err))
+		return
+	}
+
+	object, err := s.db.ApplyPatch(r.Context(), kind, id, patch, objectdb.AuthorizePatch(s.policy))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, object)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, kind, id string) {
+	var opts []objectdb.DeleteOption
+	switch policy := r.URL.Query().Get("policy"); policy {
+	case "":
+	case "cascade":
+		opts = append(opts, objectdb.DeleteCascade)
+	case "restrict":
+		opts = append(opts, objectdb.DeleteRestrict)
+	case "orphan":
+		opts = append(opts, objectdb.DeleteOrphan)
+	default:
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Errorf("unknown delete policy '%s'", policy))
+		return
+	}
+
+	// DeleteObject looks objects up by ID alone; reading first makes sure
+	// the ID belongs to the kind in the path.
+	object, err := s.db.GetObject(r.Context(), kind, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if err := s.policy.AuthorizeObject(r.Context(), objectdb.VerbDelete, object); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	opts = append(opts, objectdb.AuthorizeDependents(s.policy))
+	if err := s.db.DeleteObject(r.Context(), id, opts...); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStoreError maps the store's sentinel errors to status codes.
+// Anything unrecognised is reported as a server error.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var validation *objectdb.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error(), Code: CodeValidation, Violations: validation.Violations})
+	case errors.Is(err, objectdb.ErrNotFound):
+		writeError(w, http.StatusNotFound, CodeNotFound, err)
+	case errors.Is(err, objectdb.ErrUnknownKind):
+		writeError(w, http.StatusNotFound, CodeUnknownKind, err)
+	case errors.Is(err, objectdb.ErrBrokenReference):
+		writeError(w, http.StatusUnprocessableEntity, CodeBrokenReference, err)
+	case errors.Is(err, objectdb.ErrRestricted):
+		writeError(w, http.StatusConflict, CodeRestricted, err)
+	case errors.Is(err, objectdb.ErrQuotaExceeded):
+		writeError(w, http.StatusInsufficientStorage, CodeQuotaExceeded, err)
+	case errors.Is(err, objectdb.ErrObjectTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, err)
+	case errors.Is(err, objectdb.ErrDenied):
+		writeError(w, http.StatusForbidden, CodeDenied, err)
+	case errors.Is(err, objectdb.ErrForbidden):
+		writeError(w, http.StatusForbidden, CodeForbidden, err)
+	case errors.Is(err, objectdb.ErrInvalidPageToken):
+		writeError(w, http.StatusBadRequest, CodeInvalidPageToken, err)
+	case errors.Is(err, objectdb.ErrInvalidPatch):
+		writeError(w, http.StatusUnprocessableEntity, CodeInvalidPatch, err)
+	default:
+		writeError(w, http.StatusInternalServerError, CodeInternal, err)
+	}
+}
+
+// limitBody returns the body of r, failing reads past the store's
+// WithMaxObjectSize, so that an oversized object is turned away before it
+// is read in full rather than once encoded. A JSON body is rarely smaller
+// than the value stored from it, so with a compact codec or compression
+// an object that would have fit may be turned away too.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request) io.Reader {
+	limit := int64(s.db.MaxObjectSize())
+	if limit == 0 {
+		limit = defaultMaxBodySize
+	}
+
+	return http.MaxBytesReader(w, r.Body, limit)
+}
+
+// writeBodyError writes err, from reading a request body, as 413 if the
+// body was over its limit and as 400 otherwise.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, err)
+		return
+	}
+
+	writeError(w, http.StatusBadRequest, CodeBadRequest, err)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error(), Code: code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
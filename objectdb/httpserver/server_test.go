@@ -0,0 +1,50 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"go-assignment/objectdb"
+)
+
+func TestWritesRejectBodiesOverTheObjectSizeLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := objectdb.NewRedisObjectDB(redis.NewClient(&redis.Options{Addr: mr.Addr()}), objectdb.WithMaxObjectSize(300))
+	t.Cleanup(func() { db.Close() })
+	s := New(db)
+
+	big := strings.Repeat("x", 400)
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+	}{
+		{"put", http.MethodPut, "application/json", `{"name":"` + big + `"}`},
+		{"post", http.MethodPost, "application/json", `{"name":"` + big + `"}`},
+		{"patch", http.MethodPatch, "application/json-patch+json", `[{"op":"replace","path":"/name","value":"` + big + `"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/kinds/person/objects/1", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "request body too large") {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/kinds/person/objects/1", strings.NewReader(`{"name":"Ann"}`))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status of a small body = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+}
@@ -0,0 +1,90 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrIdempotencyKeyReused is returned by Store when an idempotency key
+// already recorded for one object is presented with another.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different object")
+
+type idempotencyKey struct {
+	key string
+	ttl time.Duration
+}
+
+func (k idempotencyKey) applyStore(o *storeOptions) {
+	o.idempotencyKey, o.idempotencyTTL = k.key, k.ttl
+}
+
+// IdempotencyKey makes Store apply the write at most once per key within
+// ttl: a retry carrying the same key, after the first attempt succeeded,
+// returns nil without writing again. Queue consumers can pass a message ID
+// and HTTP handlers a client-supplied request ID. An idempotent Store
+// writes immediately, even in write-behind mode.
+func IdempotencyKey(key string, ttl time.Duration) StoreOption {
+	return idempotencyKey{key: key, ttl: ttl}
+}
+
+func idempotencyMarkerKey(key string) string {
+	return fmt.Sprintf("idem:%s", key)
+}
+
+// storeIdempotent writes object together with its idempotency marker, with
+// the marker under WATCH so that two concurrent retries write only once.
+func (db *RedisObjectDB) storeIdempotent(ctx context.Context, object Object, options storeOptions) error {
+	marker := idempotencyMarkerKey(options.idempotencyKey)
+	fingerprint := objectKey(object.GetKind(), object.GetID())
+
+	// seen reports whether the write was already applied under the key.
+	seen := func(get func() (string, error)) (bool, error) {
+		recorded, err := get()
+		if err == redis.Nil {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if recorded != fingerprint {
+			return false, fmt.Errorf("key '%s' was used for '%s': %w", options.idempotencyKey, recorded, ErrIdempotencyKeyReused)
+		}
+		return true, nil
+	}
+
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		done, err := seen(tx.Get(ctx, marker).Result)
+		if err != nil || done {
+			return err
+		}
+
+		previous, err := db.previousObjectVia(ctx, tx, object.GetKind(), object.GetID())
+		if err != nil {
+			return err
+		}
+		value, err := db.prepareWrite(ctx, object, previous)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			db.queueWrite(ctx, pipe, object, previous, value)
+			pipe.Set(ctx, marker, fingerprint, options.idempotencyTTL)
+			return nil
+		})
+		return err
+	}, marker)
+	if errors.Is(err, redis.TxFailedErr) {
+		// A concurrent attempt with the same key got there first.
+		done, seenErr := seen(db.redisClient.Get(ctx, marker).Result)
+		if seenErr != nil || done {
+			return seenErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,263 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Secondary indexes are Redis sets whose members are object keys, or sorted
+// sets for the ID index and the range, geo, name and group indexes in
+// rangeindex.go, geo.go, autocomplete.go and rollup.go. They are written
+// in the same MULTI as the object itself, so they only drift from the data
+// when a write is interrupted between reading the previous value and
+// committing.
+
+// indexEntry says that member (an object key, see indexObjectKey) belongs
+// in the set at key or, for sorted indexes, in the sorted set at key with
+// score.
+type indexEntry struct {
+	key    string
+	member string
+	sorted bool
+	score  float64
+}
+
+// slot identifies where an entry goes, whatever its score.
+func (e indexEntry) slot() string {
+	return e.key + "\x00" + e.member
+}
+
+// refIndexKey holds the keys of every object referencing kind/id.
+func refIndexKey(kind, id string) string {
+	return fmt.Sprintf("idx:ref:%s:%s", kind, id)
+}
+
+// kindIndexKey holds the keys of every object of kind. Negative label
+// selectors are computed by subtracting from it.
+func kindIndexKey(kind string) string {
+	return fmt.Sprintf("idx:kind:%s", kind)
+}
+
+// idIndexKey holds the keys of every object of kind in a sorted set with
+// equal scores, so they sort by ID for ListPage.
+func idIndexKey(kind string) string {
+	return fmt.Sprintf("idx:id:%s", kind)
+}
+
+// labelIndexKey holds the keys of objects of kind labelled key=value.
+func labelIndexKey(kind, key, value string) string {
+	return fmt.Sprintf("idx:label:%s:%s=%s", kind, key, value)
+}
+
+// labelKeyIndexKey holds the keys of objects of kind carrying label key,
+// whatever its value.
+func labelKeyIndexKey(kind, key string) string {
+	return fmt.Sprintf("idx:labelkey:%s:%s", kind, key)
+}
+
+func objectIndexEntries(object Object) []indexEntry {
+	kind := object.GetKind()
+	member := objectKey(kind, object.GetID())
+
+	entries := []indexEntry{
+		{key: kindIndexKey(kind), member: member},
+		{key: idIndexKey(kind), member: member, sorted: true},
+	}
+	for key, value := range object.GetObjectMeta().Labels {
+		entries = append(entries,
+			indexEntry{key: labelIndexKey(kind, key, value), member: member},
+			indexEntry{key: labelKeyIndexKey(kind, key), member: member},
+		)
+	}
+	for _, ref := range objectReferences(object) {
+		if ref.Kind != "" {
+			entries = append(entries, indexEntry{key: refIndexKey(ref.Kind, ref.ID), member: member})
+		}
+	}
+
+	entries = append(entries, textIndexEntries(object)...)
+	entries = append(entries, trigramIndexEntries(object)...)
+	entries = append(entries, rangeIndexEntries(object)...)
+	entries = append(entries, geoIndexEntries(object)...)
+	entries = append(entries, groupIndexEntries(object)...)
+	return append(entries, nameIndexEntries(object)...)
+}
+
+// updateIndexes queues the index changes for replacing previous with
+// current on pipe. Either side may be nil for a create or a delete.
+func updateIndexes(ctx context.Context, pipe redis.Pipeliner, previous, current Object) {
+	keep := map[string]bool{}
+	if current != nil {
+		for _, entry := range objectIndexEntries(current) {
+			keep[entry.slot()] = true
+			queueIndexAdd(ctx, pipe, entry)
+		}
+	}
+
+	if previous != nil {
+		for _, entry := range objectIndexEntries(previous) {
+			if !keep[entry.slot()] {
+				queueIndexRem(ctx, pipe, entry.key, entry.member)
+			}
+		}
+	}
+}
+
+// isSortedIndex reports whether the index at key is a sorted set: an ID,
+// range, geo, name or group index.
+func isSortedIndex(key string) bool {
+	for _, prefix := range []string{"idx:id:", "idx:range:", "idx:geo:", "idx:name:", "idx:group:"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexObjectKey returns the key of the object that member of index stands
+// for. That is the member itself except in name and group indexes, whose
+// members lead with the name or value so they sort by it.
+func indexObjectKey(index, member string) string {
+	if strings.HasPrefix(index, "idx:name:") {
+		if _, key, ok := parseNameMember(member); ok {
+			return key
+		}
+	}
+	if strings.HasPrefix(index, "idx:group:") {
+		if _, key, ok := parseGroupMember(member); ok {
+			return key
+		}
+	}
+
+	return member
+}
+
+func indexObjectKeys(index string, members []string) []string {
+	keys := make([]string, len(members))
+	for i, member := range members {
+		keys[i] = indexObjectKey(index, member)
+	}
+
+	return keys
+}
+
+// queueIndexAdd queues adding entry to its set or sorted set. The command
+// counts the entries added or, in a sorted set, rescored.
+func queueIndexAdd(ctx context.Context, pipe redis.Pipeliner, entry indexEntry) *redis.IntCmd {
+	if entry.sorted {
+		return pipe.ZAddCh(ctx, entry.key, &redis.Z{Score: entry.score, Member: entry.member})
+	}
+
+	return pipe.SAdd(ctx, entry.key, entry.member)
+}
+
+// queueIndexRem queues removing members from the index at key.
+func queueIndexRem(ctx context.Context, pipe redis.Pipeliner, key string, members ...interface{}) {
+	if isSortedIndex(key) {
+		pipe.ZRem(ctx, key, members...)
+		return
+	}
+
+	pipe.SRem(ctx, key, members...)
+}
+
+// scanIndex calls fn with the members of the index at key, sorted or not,
+// in batches of at most fetchBatchSize.
+func (db *RedisObjectDB) scanIndex(ctx context.Context, key string, fn func(members []string) error) error {
+	sorted := isSortedIndex(key)
+	var it *redis.ScanIterator
+	if sorted {
+		it = db.redisClient.ZScan(ctx, key, 0, "", int64(db.fetchBatchSize)).Iterator()
+	} else {
+		it = db.redisClient.SScan(ctx, key, 0, "", int64(db.fetchBatchSize)).Iterator()
+	}
+
+	var batch []string
+	for it.Next(ctx) {
+		batch = append(batch, it.Val())
+		// ZSCAN yields each member followed by its score.
+		if sorted && !it.Next(ctx) {
+			break
+		}
+		if len(batch) < db.fetchBatchSize {
+			continue
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		batch = nil
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return fn(batch)
+}
+
+// previousObject returns the currently stored version of kind/id, or nil if
+// there is none. A value that no longer decodes is treated as absent so it
+// can be overwritten.
+func (db *RedisObjectDB) previousObject(ctx context.Context, kind, id string) (Object, error) {
+	return db.previousObjectVia(ctx, db.redisClient, kind, id)
+}
+
+// previousObjectVia is previousObject reading through c, such as a
+// redis.Tx that already holds a connection.
+func (db *RedisObjectDB) previousObjectVia(ctx context.Context, c redis.Cmdable, kind, id string) (Object, error) {
+	val, err := c.Get(ctx, objectKey(kind, id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := db.decode(kind, val)
+	if err != nil {
+		return nil, nil
+	}
+
+	return object, nil
+}
+
+// ListReferrers returns every object with a `ref` field pointing at kind/id.
+func (db *RedisObjectDB) ListReferrers(ctx context.Context, kind, id string) ([]Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListReferrers")
+	defer cancel()
+
+	keys, err := db.redisClient.SMembers(ctx, refIndexKey(kind, id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.fetchKeys(ctx, keys)
+}
+
+// CountObjects returns how many objects of kind exist, from the kind index
+// rather than a scan.
+func (db *RedisObjectDB) CountObjects(ctx context.Context, kind string) (int64, error) {
+	return db.redisClient.SCard(ctx, kindIndexKey(kind)).Result()
+}
+
+func (db *RedisObjectDB) ListAnimalsByOwner(ctx context.Context, ownerID string) ([]*Animal, error) {
+	referrers, err := db.ListReferrers(ctx, (&Person{}).GetKind(), ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var animals []*Animal
+	for _, object := range referrers {
+		if animal, ok := object.(*Animal); ok && animal.OwnerID == ownerID {
+			animals = append(animals, animal)
+		}
+	}
+
+	return animals, nil
+}
@@ -0,0 +1,61 @@
+package objectdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrIntegrity is returned when a stored value does not carry a valid HMAC
+// while the store is configured with an integrity key.
+var ErrIntegrity = errors.New("integrity check failed")
+
+// Signed values are framed as 0x00, formatHMAC, the HMAC-SHA256 of the rest
+// and a complete framed (and possibly compressed) value.
+const formatHMAC byte = 0x20
+
+// WithIntegrityKey signs every value the store writes with HMAC-SHA256 and
+// verifies it on read. Unsigned values are rejected too, otherwise
+// stripping the signature would defeat the check, so existing data has to
+// be rewritten, e.g. by a migration, before the key is turned on.
+func WithIntegrityKey(key []byte) Option {
+	return func(db *RedisObjectDB) {
+		db.integrityKey = key
+	}
+}
+
+func (db *RedisObjectDB) sign(value []byte) []byte {
+	if db.integrityKey == nil {
+		return value
+	}
+
+	mac := hmac.New(sha256.New, db.integrityKey)
+	mac.Write(value)
+	signed := append([]byte{valueMarker, formatHMAC}, mac.Sum(nil)...)
+	return append(signed, value...)
+}
+
+// verify checks and strips the signature of value. Without an integrity key
+// values are returned as they are and decodeValue skips any signature.
+func (db *RedisObjectDB) verify(value []byte) ([]byte, error) {
+	if db.integrityKey == nil {
+		return value, nil
+	}
+	if !isSigned(value) {
+		return nil, fmt.Errorf("value is not signed: %w", ErrIntegrity)
+	}
+
+	sum, inner := value[2:2+sha256.Size], value[2+sha256.Size:]
+	mac := hmac.New(sha256.New, db.integrityKey)
+	mac.Write(inner)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return nil, fmt.Errorf("signature mismatch: %w", ErrIntegrity)
+	}
+
+	return inner, nil
+}
+
+func isSigned(value []byte) bool {
+	return len(value) >= 2+sha256.Size && value[0] == valueMarker && value[1] == formatHMAC
+}
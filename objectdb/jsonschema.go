@@ -0,0 +1,74 @@
+package objectdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var jsonSchemas = map[string]*jsonschema.Schema{}
+
+// RegisterJSONSchema attaches a JSON Schema document to kind. Store then
+// validates the serialized payload against it before writing, which guards
+// the dataset against objects built from external input.
+func RegisterJSONSchema(kind string, schema []byte) error {
+	url := "objectdb://" + shortKindName(kind) + ".json"
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(schema)); err != nil {
+		return err
+	}
+
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return fmt.Errorf("compile schema for %s: %w", kind, err)
+	}
+
+	jsonSchemas[kind] = compiled
+	return nil
+}
+
+// validateJSONSchema checks the JSON form of object whatever codec it ends
+// up stored with, since that is the form schemas are written against.
+func validateJSONSchema(object Object) error {
+	schema, ok := jsonSchemas[object.GetKind()]
+	if !ok {
+		return nil
+	}
+
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return err
+	}
+
+	err = schema.Validate(doc)
+
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	var violations []Violation
+	var collect func(*jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			field := strings.ReplaceAll(strings.TrimPrefix(ve.InstanceLocation, "/"), "/", ".")
+			violations = append(violations, Violation{Field: field, Message: ve.Message})
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(ve)
+
+	return &ValidationError{Kind: object.GetKind(), ID: object.GetID(), Violations: violations}
+}
@@ -0,0 +1,96 @@
+// Package kafkacdc publishes a store's change feed to Kafka as
+// Debezium-style change events, one topic per kind:
+//
+//	sink := kafkacdc.New([]string{"localhost:9092"})
+//	defer sink.Close()
+//	err := db.PublishChanges(ctx, sink, objectdb.PublishOptions{Name: "kafka"})
+//
+// Events are keyed by object ID, so the events of one object land on one
+// partition in order, and each delete is followed by a tombstone so that
+// compacted topics drop the object. Delivery is at least once: consumers
+// should expect to see the last events before a restart again.
+package kafkacdc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"go-assignment/objectdb"
+)
+
+const (
+	defaultTopicPrefix = "objects."
+	batchTimeout       = 10 * time.Millisecond
+)
+
+// Sink is an objectdb.ChangeSink writing to Kafka.
+type Sink struct {
+	writer *kafka.Writer
+	prefix string
+}
+
+// Option configures a Sink at construction time.
+type Option func(*Sink)
+
+// WithTopicPrefix sets what the short kind name is prefixed with to name a
+// kind's topic, "objects." by default, as in "objects.animal".
+func WithTopicPrefix(prefix string) Option {
+	return func(s *Sink) {
+		s.prefix = prefix
+	}
+}
+
+// WithTransport sets the transport used to reach the brokers, e.g. a
+// *kafka.Transport with TLS or SASL configured.
+func WithTransport(transport kafka.RoundTripper) Option {
+	return func(s *Sink) {
+		s.writer.Transport = transport
+	}
+}
+
+// New returns a Sink writing to the cluster brokers belong to. Topics are
+// created on first use if the cluster allows it.
+func New(brokers []string, opts ...Option) *Sink {
+	s := &Sink{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			BatchTimeout:           batchTimeout,
+			AllowAutoTopicCreation: true,
+		},
+		prefix: defaultTopicPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Publish writes events and returns once every in-sync replica has them.
+func (s *Sink) Publish(ctx context.Context, events []objectdb.ChangeEvent) error {
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		topic, key := s.prefix+event.Source.Table, event.Key()
+		msgs = append(msgs, kafka.Message{Topic: topic, Key: key, Value: value})
+		if event.Op == objectdb.ChangeOpDelete {
+			msgs = append(msgs, kafka.Message{Topic: topic, Key: key})
+		}
+	}
+
+	return s.writer.WriteMessages(ctx, msgs...)
+}
+
+// Close flushes and closes the connections to the brokers.
+func (s *Sink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,313 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type KindMigrationOptions struct {
+	// Rate caps how many objects are moved per second; zero means as fast
+	// as Redis allows.
+	Rate int
+	// OnProgress is called after each SCAN step.
+	OnProgress func(KindMigrationProgress)
+}
+
+type KindMigrationProgress struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Cursor  uint64 `json:"cursor"`
+	Scanned int64  `json:"scanned"`
+	Moved   int64  `json:"moved"`
+	Done    bool   `json:"done"`
+}
+
+func kindMigrationProgressKey(from, to string) string {
+	return fmt.Sprintf("kindmigration:%s:%s", from, to)
+}
+
+// MigrateKind moves every object stored under oldKind, a kind name no
+// longer registered, such as that of a type since renamed or moved to
+// another package, to newKind. Each object is re-keyed, re-encoded with
+// its pii fields encrypted for the new kind and re-indexed in one MULTI
+// under WATCH, with the referrer sets pointing at it moved along; once all
+// are moved the old kind's indexes are dropped. Archived objects are
+// restored into Redis on the way. Progress is saved in Redis after each
+// SCAN step, so calling MigrateKind again after a crash or cancellation
+// resumes where it stopped. Objects already stored under both kinds fail
+// the migration rather than being overwritten.
+func (db *RedisObjectDB) MigrateKind(ctx context.Context, oldKind, newKind string, opts KindMigrationOptions) (KindMigrationProgress, error) {
+	to, ok := lookupKind(newKind)
+	if !ok {
+		return KindMigrationProgress{}, fmt.Errorf("%w '%s'", ErrUnknownKind, newKind)
+	}
+	if oldKind == "" || strings.Contains(oldKind, ":") {
+		return KindMigrationProgress{}, fmt.Errorf("invalid kind name '%s'", oldKind)
+	}
+	if _, registered := kinds[oldKind]; registered {
+		return KindMigrationProgress{}, fmt.Errorf("%s is still registered", oldKind)
+	}
+
+	progressKey := kindMigrationProgressKey(oldKind, to)
+	progress, err := db.loadKindMigrationProgress(ctx, progressKey)
+	if err != nil {
+		return progress, err
+	}
+	progress.From, progress.To = oldKind, to
+
+	var throttle <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	prefix := oldKind + ":"
+	for !progress.Done {
+		keys, next, err := db.redisClient.Scan(ctx, progress.Cursor, prefix+"*", db.scanCount).Result()
+		if err != nil {
+			return progress, err
+		}
+
+		for _, key := range keys {
+			// Kind names may contain glob characters, so matches are
+			// confirmed by prefix.
+			id := strings.TrimPrefix(key, prefix)
+			if id == key || id == "" {
+				continue
+			}
+			if throttle != nil {
+				select {
+				case <-throttle:
+				case <-ctx.Done():
+					return progress, ctx.Err()
+				}
+			}
+
+			moved, err := db.moveObjectKind(ctx, oldKind, to, id)
+			if err != nil {
+				return progress, err
+			}
+
+			progress.Scanned++
+			if moved {
+				progress.Moved++
+			}
+		}
+
+		progress.Cursor = next
+		progress.Done = next == 0
+
+		err = db.saveKindMigrationProgress(ctx, progressKey, progress)
+		if err != nil {
+			return progress, err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	if err := db.dropKindIndexes(ctx, oldKind, to); err != nil {
+		return progress, err
+	}
+
+	return progress, db.redisClient.Del(ctx, progressKey).Err()
+}
+
+// moveObjectKind moves the object with id from one kind to the other. The
+// old key and its referrer set are watched so a referrer added meanwhile
+// is not lost.
+func (db *RedisObjectDB) moveObjectKind(ctx context.Context, from, to, id string) (bool, error) {
+	oldKey, newKey := objectKey(from, id), objectKey(to, id)
+	oldReferrers := refIndexKey(from, id)
+	moved := false
+
+	txf := func(tx *redis.Tx) error {
+		moved = false
+		val, err := tx.Get(ctx, oldKey).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		exists, err := tx.Exists(ctx, newKey).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("'%s' and '%s' both exist", oldKey, newKey)
+		}
+
+		data, err := db.verifyValue(val)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", oldKey, err)
+		}
+		object, err := decodeObject(to, data)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", oldKey, err)
+		}
+		if err := db.decryptFieldsAs(object, from); err != nil {
+			return fmt.Errorf("decode '%s': %w", oldKey, err)
+		}
+
+		stored, err := db.encryptFields(object)
+		if err != nil {
+			return err
+		}
+		value, err := db.encode(db.codec, stored)
+		if err != nil {
+			return err
+		}
+
+		referrers, err := tx.SMembers(ctx, oldReferrers).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			queueDel(ctx, pipe, oldKey)
+			queueSet(ctx, pipe, newKey, objectNamespace(object), value)
+			// The object's own entries in its targets' referrer sets still
+			// carry the old key. Targets of its own kind may not have been
+			// moved yet.
+			for _, ref := range objectReferences(object) {
+				pipe.SRem(ctx, refIndexKey(ref.Kind, ref.ID), oldKey)
+				if ref.Kind == to {
+					pipe.SRem(ctx, refIndexKey(from, ref.ID), oldKey)
+				}
+			}
+			updateIndexes(ctx, pipe, nil, object)
+			queueMoveReferrers(ctx, pipe, from, to, refIndexKey(to, id), referrers)
+			pipe.Del(ctx, oldReferrers)
+			db.recordChange(ctx, pipe, ChangeDelete, from, id, nil)
+			db.recordChange(ctx, pipe, ChangePut, to, id, value)
+			return nil
+		})
+		moved = err == nil
+		return err
+	}
+
+	for attempt := 0; attempt < updateRetries; attempt++ {
+		err := db.redisClient.Watch(ctx, txf, oldKey, newKey, oldReferrers)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return moved, err
+		}
+	}
+
+	return false, fmt.Errorf("'%s' kept changing: %w", oldKey, ErrVersionConflict)
+}
+
+// queueMoveReferrers queues adding referrers, keys of objects referencing
+// something, to the referrer set key, with those of kind from renamed to
+// kind to.
+func queueMoveReferrers(ctx context.Context, pipe redis.Pipeliner, from, to, key string, referrers []string) {
+	if len(referrers) == 0 {
+		return
+	}
+
+	members := make([]interface{}, len(referrers))
+	for i, member := range referrers {
+		if id := strings.TrimPrefix(member, from+":"); id != member {
+			member = objectKey(to, id)
+		}
+		members[i] = member
+	}
+	pipe.SAdd(ctx, key, members...)
+}
+
+// dropKindIndexes deletes the indexes of kind from once none of its objects
+// are left. Referrer sets of IDs with no object are kept for kind to, as
+// the object may yet be stored under it.
+func (db *RedisObjectDB) dropKindIndexes(ctx context.Context, from, to string) error {
+	err := db.redisClient.Del(ctx, kindIndexKey(from), idIndexKey(from), geoIndexKey(from), nameIndexKey(from)).Err()
+	if err != nil {
+		return err
+	}
+
+	prefixes := []string{
+		fmt.Sprintf("idx:label:%s:", from),
+		labelKeyIndexKey(from, ""),
+		rangeIndexKey(from, ""),
+		textIndexKey(from, ""),
+		trigramIndexKey(from, ""),
+		groupIndexKey(from, ""),
+		refIndexKey(from, ""),
+	}
+	for _, prefix := range prefixes {
+		keys := db.redisClient.Scan(ctx, 0, prefix+"*", db.scanCount).Iterator()
+		for keys.Next(ctx) {
+			key := keys.Val()
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if prefix == refIndexKey(from, "") {
+				if err := db.moveReferrerSet(ctx, from, to, key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := db.redisClient.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+		if err := keys.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *RedisObjectDB) moveReferrerSet(ctx context.Context, from, to, key string) error {
+	id := strings.TrimPrefix(key, refIndexKey(from, ""))
+	txf := func(tx *redis.Tx) error {
+		referrers, err := tx.SMembers(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			queueMoveReferrers(ctx, pipe, from, to, refIndexKey(to, id), referrers)
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < updateRetries; attempt++ {
+		err := db.redisClient.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("'%s' kept changing: %w", key, ErrVersionConflict)
+}
+
+func (db *RedisObjectDB) loadKindMigrationProgress(ctx context.Context, key string) (KindMigrationProgress, error) {
+	values, err := db.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return KindMigrationProgress{}, err
+	}
+
+	var progress KindMigrationProgress
+	progress.Cursor, _ = strconv.ParseUint(values["cursor"], 10, 64)
+	progress.Scanned, _ = strconv.ParseInt(values["scanned"], 10, 64)
+	progress.Moved, _ = strconv.ParseInt(values["moved"], 10, 64)
+
+	return progress, nil
+}
+
+func (db *RedisObjectDB) saveKindMigrationProgress(ctx context.Context, key string, progress KindMigrationProgress) error {
+	return db.redisClient.HSet(ctx, key,
+		"cursor", progress.Cursor,
+		"scanned", progress.Scanned,
+		"moved", progress.Moved,
+	).Err()
+}
@@ -0,0 +1,229 @@
+package objectdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownKind is returned for kind names that were never registered.
+var ErrUnknownKind = errors.New("unknown kind")
+
+// kinds maps a kind name to what the store knows about it: how to build an
+// empty object to decode into, and where each field lives.
+var kinds = map[string]*kindInfo{}
+
+type kindInfo struct {
+	newObject    func() Object
+	fields       map[string]fieldInfo
+	piiFields    [][]int
+	readDefaults []func(Object)
+	rangeFields  []indexedField
+	textFields   []indexedField
+	groupFields  []indexedField
+	geo          *geoField
+	unstructured bool
+}
+
+// ErrDuplicateKind is returned by RegisterKind for a kind whose name, or
+// short name, is already registered.
+var ErrDuplicateKind = errors.New("duplicate kind")
+
+// RegisteredKind describes a kind known to the store.
+type RegisteredKind struct {
+	// Name is the stable name used in keys, e.g. "*main.Person", and
+	// ShortName the lower-case alias accepted wherever a kind is given.
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	// New returns an empty object of the kind.
+	New func() Object `json:"-"`
+}
+
+// RegisterKind makes the objects newObject returns storable. Their GetKind
+// is the kind's stable name: it must not be empty or contain a colon, and
+// neither it nor its short name may already be taken. Every kind gets
+// range indexes on created_at and updated_at and a text index on its name.
+// Register kinds before opening a store.
+func RegisterKind(newObject func() Object) error {
+	if newObject == nil {
+		return errors.New("kind factory is nil")
+	}
+	object := newObject()
+	if object == nil {
+		return errors.New("kind factory returned nil")
+	}
+	t := reflect.TypeOf(object)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("kind %s is not a pointer to a struct", t)
+	}
+
+	name := object.GetKind()
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("%s has invalid kind name '%s'", t, name)
+	}
+	if _, ok := kinds[name]; ok {
+		return fmt.Errorf("%w '%s'", ErrDuplicateKind, name)
+	}
+	short := shortKindName(name)
+	for kind := range kinds {
+		if shortKindName(kind) == short {
+			return fmt.Errorf("%w: '%s' and '%s' share the short name '%s'", ErrDuplicateKind, kind, name, short)
+		}
+	}
+
+	kinds[name] = &kindInfo{
+		newObject: newObject,
+		fields:    buildFieldIndex(t.Elem()),
+		piiFields: piiFieldIndexes(t.Elem()),
+	}
+	RegisterRangeIndex(name, "created_at")
+	RegisterRangeIndex(name, "updated_at")
+	RegisterTextIndex(name, "name")
+	return nil
+}
+
+// registerKind is RegisterKind for generated code, where a failure is a
+// programming error.
+func registerKind(newObject func() Object) {
+	if err := RegisterKind(newObject); err != nil {
+		panic(err)
+	}
+}
+
+// ListKinds describes the registered kinds, sorted by name.
+func ListKinds() []RegisteredKind {
+	var list []RegisteredKind
+	for _, name := range Kinds() {
+		list = append(list, RegisteredKind{Name: name, ShortName: shortKindName(name), New: kinds[name].newObject})
+	}
+
+	return list
+}
+
+// lookupKind resolves a kind by its registered name or by its short,
+// lower-case type name ("person" for *main.Person), as used in ref tags.
+func lookupKind(name string) (string, bool) {
+	if _, ok := kinds[name]; ok {
+		return name, true
+	}
+
+	for kind := range kinds {
+		if shortKindName(kind) == strings.ToLower(name) {
+			return kind, true
+		}
+	}
+
+	return "", false
+}
+
+// Kinds returns the registered kind names, sorted.
+func Kinds() []string {
+	names := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewObject returns an empty object of kind, given by its registered or
+// short name, for callers that decode objects themselves.
+func NewObject(kind string) (Object, error) {
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	return kinds[name].newObject(), nil
+}
+
+func shortKindName(kind string) string {
+	if i := strings.LastIndex(kind, "."); i >= 0 {
+		kind = kind[i+1:]
+	}
+
+	return strings.ToLower(strings.TrimPrefix(kind, "*"))
+}
+
+func decodeObject(kind string, data []byte) (object Object, err error) {
+	// Codecs are registered by users too; a panic on a malformed value in
+	// one of them must not take the process down.
+	defer func() {
+		if r := recover(); r != nil {
+			object, err = nil, permanentError{fmt.Errorf("decode %s: %v", kind, r)}
+		}
+	}()
+
+	info, ok := kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	// A bad value stays bad, whatever error its decoder reports.
+	c, payload, err := decodeValue(data)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+
+	object, err = decodeVersion(info, kind, c, payload)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+
+	for _, setDefaults := range info.readDefaults {
+		setDefaults(object)
+	}
+
+	return object, nil
+}
+
+// checkObjectType rejects objects that are not of their kind's registered
+// type, such as an Unstructured of a struct kind or an older version of a
+// versioned one: indexes, schemas and hooks all expect that type.
+func checkObjectType(object Object) error {
+	info, ok := kinds[object.GetKind()]
+	if !ok {
+		return nil
+	}
+
+	if want := reflect.TypeOf(info.newObject()); reflect.TypeOf(object) != want {
+		return fmt.Errorf("%s objects must be stored as %s, not %T", object.GetKind(), want, object)
+	}
+	return nil
+}
+
+// RegisterReadDefaults adds fn to the functions run on every object of kind
+// right after it is decoded. It lets objects written before a field existed
+// come back with a sensible value instead of the zero value; fn should only
+// touch fields that are still unset.
+func RegisterReadDefaults(kind string, fn func(Object)) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	info.readDefaults = append(info.readDefaults, fn)
+	return nil
+}
+
+func objectKey(kind, id string) string {
+	return fmt.Sprintf("%s:%s", kind, id)
+}
+
+// parseObjectKey splits a key written by objectKey. Keys of kinds that are
+// not registered, or without an ID, are reported as not ok so scans can
+// skip foreign data.
+func parseObjectKey(key string) (kind, id string, ok bool) {
+	kind, id, found := strings.Cut(key, ":")
+	if !found || id == "" {
+		return "", "", false
+	}
+	if _, registered := kinds[kind]; !registered {
+		return "", "", false
+	}
+
+	return kind, id, true
+}
@@ -0,0 +1,156 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Ref is the target of a `ref`-tagged field, fetched on the first call to
+// Get and kept from then on.
+type Ref struct {
+	// Field is the Go field name holding the reference, e.g. "OwnerID".
+	Field string
+	// Kind is empty if the tag names a kind that is not registered.
+	Kind string
+	ID   string
+
+	loader *RefLoader
+	target *refTarget
+}
+
+type refTarget struct {
+	mu     sync.Mutex
+	done   bool
+	object Object
+	err    error
+}
+
+// RefLoader hands out lazy Refs. Refs from one loader share their targets,
+// so however many objects reference the same one it is fetched once. A
+// loader remembers every target it loaded; use one per request or listing
+// rather than one for the life of the store.
+type RefLoader struct {
+	db *RedisObjectDB
+
+	mu      sync.Mutex
+	targets map[string]*refTarget
+}
+
+// NewRefLoader returns an empty RefLoader reading from db.
+func (db *RedisObjectDB) NewRefLoader() *RefLoader {
+	return &RefLoader{db: db, targets: map[string]*refTarget{}}
+}
+
+// Refs returns the non-empty references object holds, by field name,
+// without fetching anything.
+func (l *RefLoader) Refs(object Object) map[string]*Ref {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	refs := map[string]*Ref{}
+	for _, ref := range objectReferences(object) {
+		key := objectKey(ref.Kind, ref.ID)
+		target, ok := l.targets[key]
+		if !ok {
+			target = &refTarget{}
+			if ref.Kind == "" {
+				target.done = true
+				target.err = fmt.Errorf("field '%s' of %s references unknown kind '%s'", ref.Field, object.GetKind(), ref.Tag)
+			}
+			l.targets[key] = target
+		}
+		refs[ref.Field] = &Ref{Field: ref.Field, Kind: ref.Kind, ID: ref.ID, loader: l, target: target}
+	}
+
+	return refs
+}
+
+// Get returns the referenced object, fetching it unless it already was.
+// A dangling reference is remembered too, and keeps returning the same
+// ErrNotFound; other errors are not, and the next Get tries again.
+func (r *Ref) Get(ctx context.Context) (Object, error) {
+	r.target.mu.Lock()
+	defer r.target.mu.Unlock()
+
+	if !r.target.done {
+		ctx, cancel := r.loader.db.withTimeout(ctx, "Get")
+		defer cancel()
+
+		object, err := r.loader.db.getObject(ctx, r.Kind, r.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		r.target.object, r.target.err, r.target.done = object, err, true
+	}
+
+	return r.target.object, r.target.err
+}
+
+// Loaded reports whether Get would return without fetching.
+func (r *Ref) Loaded() bool {
+	r.target.mu.Lock()
+	defer r.target.mu.Unlock()
+
+	return r.target.done
+}
+
+// LoadAll fetches every target handed out so far that has not been, with
+// one MGET per fetchBatchSize targets, for callers that are going to
+// follow most references after all.
+func (l *RefLoader) LoadAll(ctx context.Context) error {
+	ctx, cancel := l.db.withTimeout(ctx, "LoadAll")
+	defer cancel()
+
+	l.mu.Lock()
+	var keys []string
+	for key, target := range l.targets {
+		target.mu.Lock()
+		if !target.done {
+			keys = append(keys, key)
+		}
+		target.mu.Unlock()
+	}
+	l.mu.Unlock()
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > l.db.fetchBatchSize {
+			n = l.db.fetchBatchSize
+		}
+		if err := l.loadBatch(ctx, keys[:n]); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+func (l *RefLoader) loadBatch(ctx context.Context, keys []string) error {
+	vals, err := l.db.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+
+	for i, val := range vals {
+		kind, id, _ := parseObjectKey(keys[i])
+		l.mu.Lock()
+		target := l.targets[keys[i]]
+		l.mu.Unlock()
+
+		target.mu.Lock()
+		if !target.done {
+			if data, ok := val.(string); ok {
+				target.object, target.err = l.db.decode(kind, []byte(data))
+			} else {
+				target.object, target.err = nil, fmt.Errorf("%s with ID '%s' %w", kind, id, ErrNotFound)
+			}
+			target.done = true
+		}
+		target.mu.Unlock()
+	}
+
+	return nil
+}
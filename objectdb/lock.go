@@ -0,0 +1,156 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another lease holds the
+	// lock.
+	ErrLockHeld = errors.New("lock is held")
+	// ErrLeaseLost is returned by Lease methods once the lock has
+	// expired or been taken over by someone else.
+	ErrLeaseLost = errors.New("lease lost")
+)
+
+// lockRetryInterval is how often Lock retries a held lock.
+const lockRetryInterval = 50 * time.Millisecond
+
+// Releasing and extending only touch the key while it still holds our
+// token, as in the single-instance Redlock algorithm.
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+func lockKey(id string) string {
+	return fmt.Sprintf("lock:%s", id)
+}
+
+// Lease is a held lock on one object. It expires on its own after its TTL
+// unless refreshed, so a crashed holder does not block others forever.
+type Lease struct {
+	db      *RedisObjectDB
+	key     string
+	token   string
+	expires time.Time
+}
+
+// Lock takes the lock on objectID for ttl, waiting until it is free or ctx
+// ends. The lock is advisory: it serializes callers that take it, not
+// Store as such.
+func (db *RedisObjectDB) Lock(ctx context.Context, objectID string, ttl time.Duration) (*Lease, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		lease, err := db.TryLock(ctx, objectID, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("lock '%s': %w", objectID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// TryLock takes the lock on objectID for ttl, or fails at once with
+// ErrLockHeld.
+func (db *RedisObjectDB) TryLock(ctx context.Context, objectID string, ttl time.Duration) (*Lease, error) {
+	return db.TryLockAs(ctx, objectID, "", ttl)
+}
+
+// TryLockAs is TryLock recording holder as the lock's owner, for others to
+// see with LockHolder.
+func (db *RedisObjectDB) TryLockAs(ctx context.Context, objectID, holder string, ttl time.Duration) (*Lease, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	// The random part keeps two holders with the same name apart.
+	token := holder + "/" + hex.EncodeToString(b)
+	lease := &Lease{db: db, key: lockKey(objectID), token: token}
+	start := time.Now()
+	ok, err := db.redisClient.SetNX(ctx, lease.key, lease.token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("lock '%s': %w", objectID, ErrLockHeld)
+	}
+	lease.expires = start.Add(ttl)
+
+	return lease, nil
+}
+
+// LockHolder returns the holder recorded by TryLockAs for the lock on
+// objectID, or "" if nobody holds it or the holder gave no name.
+func (db *RedisObjectDB) LockHolder(ctx context.Context, objectID string) (string, error) {
+	token, err := db.redisClient.Get(ctx, lockKey(objectID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if i := strings.LastIndex(token, "/"); i >= 0 {
+		return token[:i], nil
+	}
+	return "", nil
+}
+
+// Expires is when the lease runs out unless refreshed. It is measured from
+// before the lock was taken, so it errs on the early side.
+func (l *Lease) Expires() time.Time {
+	return l.expires
+}
+
+// Refresh extends the lease to ttl from now.
+func (l *Lease) Refresh(ctx context.Context, ttl time.Duration) error {
+	start := time.Now()
+	n, err := refreshScript.Run(ctx, l.db.redisClient, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	l.expires = start.Add(ttl)
+
+	return nil
+}
+
+// Release gives up the lock. Releasing a lease that already expired
+// returns ErrLeaseLost and leaves whoever holds the lock now alone.
+func (l *Lease) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, l.db.redisClient, []string{l.key}, l.token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
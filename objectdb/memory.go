@@ -0,0 +1,121 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultMemorySamples = 100
+	defaultMemoryTop     = 10
+)
+
+// MemoryOptions tunes MemoryUsage. Zero values pick the defaults.
+type MemoryOptions struct {
+	// Samples is how many keys per kind are measured, 100 by default.
+	Samples int
+	// Top is how many of the largest sampled objects to list, 10 by
+	// default.
+	Top int
+}
+
+// KindMemory is the estimated Redis memory held by one kind.
+type KindMemory struct {
+	Kind    string `json:"kind"`
+	Objects int64  `json:"objects"`
+	Sampled int64  `json:"sampled"`
+	// EstimatedBytes extrapolates the sampled keys to all Objects. It is
+	// exact when every object was sampled.
+	EstimatedBytes int64 `json:"estimated_bytes"`
+	// Largest lists the biggest sampled objects, largest first.
+	Largest []KeyMemory `json:"largest"`
+}
+
+// KeyMemory is the memory one key takes, as reported by MEMORY USAGE.
+type KeyMemory struct {
+	Key   string `json:"key"`
+	Bytes int64  `json:"bytes"`
+}
+
+// MemoryUsage measures a random sample of each registered kind's keys with
+// MEMORY USAGE and estimates the kind's total footprint, including Redis's
+// per-key overhead that Stats does not see. Index sets are not counted.
+// Results come in Kinds order.
+func (db *RedisObjectDB) MemoryUsage(ctx context.Context, opts MemoryOptions) ([]KindMemory, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = defaultMemorySamples
+	}
+	if opts.Top <= 0 {
+		opts.Top = defaultMemoryTop
+	}
+
+	var usage []KindMemory
+	for _, kind := range Kinds() {
+		m, err := db.kindMemory(ctx, kind, opts)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, m)
+	}
+
+	return usage, nil
+}
+
+func (db *RedisObjectDB) kindMemory(ctx context.Context, kind string, opts MemoryOptions) (KindMemory, error) {
+	m := KindMemory{Kind: kind}
+
+	// Reservoir sampling keeps the sample uniform without holding every
+	// key in memory.
+	sample := make([]string, 0, opts.Samples)
+	it := db.redisClient.Scan(ctx, 0, fmt.Sprintf("%s:*", kind), db.scanCount).Iterator()
+	for it.Next(ctx) {
+		if k, _, ok := parseObjectKey(it.Val()); !ok || k != kind {
+			continue
+		}
+		m.Objects++
+		if len(sample) < opts.Samples {
+			sample = append(sample, it.Val())
+		} else if i := rand.Int63n(m.Objects); i < int64(opts.Samples) {
+			sample[i] = it.Val()
+		}
+	}
+	if err := it.Err(); err != nil {
+		return m, err
+	}
+	if len(sample) == 0 {
+		return m, nil
+	}
+
+	pipe := db.redisClient.Pipeline()
+	cmds := make([]*redis.IntCmd, len(sample))
+	for i, key := range sample {
+		cmds[i] = pipe.MemoryUsage(ctx, key)
+	}
+	// Keys deleted since the scan fail with redis.Nil and are left out.
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return m, err
+	}
+
+	var total int64
+	for i, cmd := range cmds {
+		if cmd.Err() != nil {
+			continue
+		}
+		m.Sampled++
+		total += cmd.Val()
+		m.Largest = append(m.Largest, KeyMemory{Key: sample[i], Bytes: cmd.Val()})
+	}
+	if m.Sampled > 0 {
+		m.EstimatedBytes = total * m.Objects / m.Sampled
+	}
+
+	sort.Slice(m.Largest, func(i, j int) bool { return m.Largest[i].Bytes > m.Largest[j].Bytes })
+	if len(m.Largest) > opts.Top {
+		m.Largest = m.Largest[:opts.Top]
+	}
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package objectdb
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ObjectMeta carries the fields every kind shares. Embedding it implements
+// all of Object except GetKind, which has to be defined on the outer type.
+//
+// Labels are indexed and can be queried; annotations are stored verbatim and
+// are meant for free-form notes such as who last touched an object.
+// CreatedAt, UpdatedAt, Version, SchemaVersion and APIVersion are
+// maintained by Store; SchemaVersion records which registered migration the
+// payload is shaped for, and APIVersion which version of a versioned kind,
+// see RegisterConversion.
+type ObjectMeta struct {
+	Name          string            `json:"name"`
+	ID            string            `json:"id"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Version       int64             `json:"version"`
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	APIVersion    string            `json:"api_version,omitempty"`
+}
+
+func (m *ObjectMeta) GetID() string {
+	return m.ID
+}
+
+func (m *ObjectMeta) GetName() string {
+	return m.Name
+}
+
+func (m *ObjectMeta) SetID(s string) {
+	m.ID = s
+}
+
+func (m *ObjectMeta) SetName(s string) {
+	m.Name = s
+}
+
+func (m *ObjectMeta) GetObjectMeta() *ObjectMeta {
+	return m
+}
+
+func (m *ObjectMeta) SetLabel(key, value string) {
+	if m.Labels == nil {
+		m.Labels = map[string]string{}
+	}
+	m.Labels[key] = value
+}
+
+func (m *ObjectMeta) SetAnnotation(key, value string) {
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[key] = value
+}
+
+// stampObjectMeta sets the bookkeeping fields of object for a write that
+// replaces previous, which is nil on create.
+func stampObjectMeta(object, previous Object, now time.Time) {
+	meta := object.GetObjectMeta()
+	meta.UpdatedAt = now
+	meta.SchemaVersion = currentSchemaVersion(object.GetKind())
+	meta.APIVersion = currentKindVersion(object.GetKind())
+	if previous == nil {
+		meta.CreatedAt = now
+		meta.Version = 1
+		return
+	}
+
+	meta.CreatedAt = previous.GetObjectMeta().CreatedAt
+	meta.Version = previous.GetObjectMeta().Version + 1
+}
+
+// validateLabels rejects label keys that would make index keys ambiguous.
+func validateLabels(labels map[string]string) error {
+	for key := range labels {
+		if key == "" || strings.ContainsAny(key, "=:") {
+			return fmt.Errorf("invalid label key '%s'", key)
+		}
+	}
+
+	return nil
+}
+
+// newObjectID returns a random (version 4) UUID for objects stored without
+// an ID.
+func newObjectID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
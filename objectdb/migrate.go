@@ -0,0 +1,258 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Migration moves stored objects of Kind between schema versions Version-1
+// and Version. Up and Down work on the decoded JSON document because old
+// payloads may not fit the current struct.
+type Migration struct {
+	Kind    string
+	Version int
+	Up      func(doc map[string]interface{}) error
+	Down    func(doc map[string]interface{}) error
+}
+
+// migrations holds the registered migrations of each kind, sorted by Version.
+var migrations = map[string][]Migration{}
+
+// RegisterMigration adds m to its kind. Versions start at 1 and must be
+// registered without gaps, so every stored version has a path to the latest.
+func RegisterMigration(m Migration) error {
+	if _, ok := kinds[m.Kind]; !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, m.Kind)
+	}
+	if m.Up == nil {
+		return fmt.Errorf("migration %s v%d has no Up function", m.Kind, m.Version)
+	}
+
+	existing := migrations[m.Kind]
+	if m.Version != len(existing)+1 {
+		return fmt.Errorf("migration %s v%d registered out of order, next version is %d", m.Kind, m.Version, len(existing)+1)
+	}
+
+	migrations[m.Kind] = append(existing, m)
+	return nil
+}
+
+// currentSchemaVersion is the version new objects of kind are written with.
+func currentSchemaVersion(kind string) int {
+	return len(migrations[kind])
+}
+
+// migrateDocument applies Up or Down migrations to doc until it is at
+// version target.
+func migrateDocument(kind string, doc map[string]interface{}, target int) error {
+	version := schemaVersionOf(doc)
+	steps := migrations[kind]
+
+	for version < target {
+		err := steps[version].Up(doc)
+		if err != nil {
+			return fmt.Errorf("%s migration v%d up: %w", kind, version+1, err)
+		}
+		version++
+	}
+	for version > target {
+		m := steps[version-1]
+		if m.Down == nil {
+			return fmt.Errorf("%s migration v%d cannot be reverted", kind, version)
+		}
+		err := m.Down(doc)
+		if err != nil {
+			return fmt.Errorf("%s migration v%d down: %w", kind, version, err)
+		}
+		version--
+	}
+
+	doc["schema_version"] = version
+	return nil
+}
+
+func schemaVersionOf(doc map[string]interface{}) int {
+	v, _ := toFloat(doc["schema_version"])
+	return int(v)
+}
+
+type MigrateOptions struct {
+	// Target is the schema version to migrate to. Zero means the latest
+	// registered version; use a lower value together with Down functions
+	// to roll back.
+	Target int
+	// Rollback must be set to migrate to version 0.
+	Rollback bool
+	// OnProgress is called after each SCAN step.
+	OnProgress func(MigrationProgress)
+}
+
+type MigrationProgress struct {
+	Kind     string `json:"kind"`
+	Target   int    `json:"target"`
+	Cursor   uint64 `json:"cursor"`
+	Scanned  int64  `json:"scanned"`
+	Migrated int64  `json:"migrated"`
+	Done     bool   `json:"done"`
+}
+
+func migrationProgressKey(kind string, target int) string {
+	return fmt.Sprintf("migration:%s:%d", kind, target)
+}
+
+// Migrate rewrites every object of kind whose schema version differs from
+// the target. Progress is saved in Redis after each SCAN step, so calling
+// Migrate again after a crash or cancellation resumes where it stopped.
+// Archived objects that need migrating are fetched from the archive and
+// stored in Redis again, see WithArchive.
+func (db *RedisObjectDB) Migrate(ctx context.Context, kind string, opts MigrateOptions) (MigrationProgress, error) {
+	target := opts.Target
+	if target == 0 && !opts.Rollback {
+		target = currentSchemaVersion(kind)
+	}
+	if target < 0 || target > currentSchemaVersion(kind) {
+		return MigrationProgress{}, fmt.Errorf("%s has no schema version %d", kind, target)
+	}
+
+	progressKey := migrationProgressKey(kind, target)
+	progress, err := db.loadMigrationProgress(ctx, progressKey)
+	if err != nil {
+		return progress, err
+	}
+	progress.Kind, progress.Target = kind, target
+
+	for !progress.Done {
+		keys, next, err := db.redisClient.Scan(ctx, progress.Cursor, fmt.Sprintf("%s:*", kind), db.scanCount).Result()
+		if err != nil {
+			return progress, err
+		}
+
+		for _, key := range keys {
+			if k, _, ok := parseObjectKey(key); !ok || k != kind {
+				continue
+			}
+
+			migrated, err := db.migrateKey(ctx, kind, key, target)
+			if err != nil {
+				return progress, err
+			}
+
+			progress.Scanned++
+			if migrated {
+				progress.Migrated++
+			}
+		}
+
+		progress.Cursor = next
+		progress.Done = next == 0
+
+		err = db.saveMigrationProgress(ctx, progressKey, progress)
+		if err != nil {
+			return progress, err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	return progress, db.redisClient.Del(ctx, progressKey).Err()
+}
+
+// migrateKey rewrites one object under WATCH so a concurrent Store is not
+// overwritten with a migrated copy of the value it replaced.
+func (db *RedisObjectDB) migrateKey(ctx context.Context, kind, key string, target int) (bool, error) {
+	migrated := false
+
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Archived objects are migrated into Redis, as writing them would.
+		verified, err := db.verifyValue(val)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", key, err)
+		}
+
+		codec, payload, err := decodeValue(verified)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", key, err)
+		}
+
+		var doc map[string]interface{}
+		err = codec.Unmarshal(payload, &doc)
+		if err != nil {
+			return fmt.Errorf("decode '%s': %w", key, err)
+		}
+		if schemaVersionOf(doc) == target {
+			return nil
+		}
+
+		err = migrateDocument(kind, doc, target)
+		if err != nil {
+			return fmt.Errorf("'%s': %w", key, err)
+		}
+
+		newVal, err := db.encode(codec, doc)
+		if err != nil {
+			return err
+		}
+
+		previous, _ := decodeObject(kind, verified)
+		current, _ := decodeObject(kind, newVal)
+
+		namespace := ""
+		if current != nil {
+			namespace = objectNamespace(current)
+		} else if previous != nil {
+			namespace = objectNamespace(previous)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			queueSet(ctx, pipe, key, namespace, newVal)
+			if current != nil {
+				updateIndexes(ctx, pipe, previous, current)
+			}
+			return nil
+		})
+		migrated = err == nil
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// Rewritten concurrently, and therefore already at the current
+		// version.
+		return false, nil
+	}
+
+	return migrated, err
+}
+
+func (db *RedisObjectDB) loadMigrationProgress(ctx context.Context, key string) (MigrationProgress, error) {
+	values, err := db.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return MigrationProgress{}, err
+	}
+
+	var progress MigrationProgress
+	progress.Cursor, _ = strconv.ParseUint(values["cursor"], 10, 64)
+	progress.Scanned, _ = strconv.ParseInt(values["scanned"], 10, 64)
+	progress.Migrated, _ = strconv.ParseInt(values["migrated"], 10, 64)
+
+	return progress, nil
+}
+
+func (db *RedisObjectDB) saveMigrationProgress(ctx context.Context, key string, progress MigrationProgress) error {
+	return db.redisClient.HSet(ctx, key,
+		"cursor", progress.Cursor,
+		"scanned", progress.Scanned,
+		"migrated", progress.Migrated,
+	).Err()
+}
@@ -0,0 +1,99 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithStrictNames makes GetObjectByName match names exactly, as written,
+// instead of by their folded form.
+func WithStrictNames() Option {
+	return func(db *RedisObjectDB) {
+		db.strictNames = true
+	}
+}
+
+// foldName is the form names are compared in: runs of white space become
+// one space and the ends are trimmed, case is folded and the result is
+// NFC-normalized, so " Rex", "REX" and "rex" are all the same name, as
+// are the composed and decomposed forms of "Zoë".
+func foldName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	return norm.NFC.String(cases.Fold().String(name))
+}
+
+// sameName reports whether object is called name, under the store's
+// matching rules.
+func (db *RedisObjectDB) sameName(object Object, name string) bool {
+	if db.strictNames {
+		return object.GetName() == name
+	}
+
+	return foldName(object.GetName()) == foldName(name)
+}
+
+// nameIndexedKey holds the kinds RebuildIndexes has indexed by name, all
+// of whose objects are in their name index.
+const nameIndexedKey = "names:indexed"
+
+// objectsByName returns the objects called name, looked up in the name
+// indexes. Objects written before those existed are not in them, so when
+// the indexes know no such name it falls back to scanning the kinds that
+// RebuildIndexes has not indexed yet. Once every kind has been, a name
+// that is not found costs no more than one that is.
+func (db *RedisObjectDB) objectsByName(ctx context.Context, name string) ([]Object, error) {
+	folded := foldName(name)
+	by := redis.ZRangeBy{Min: "[" + folded + "\x00", Max: "[" + folded + "\x00\xff"}
+
+	searched := Kinds()
+	pipe := db.redisClient.Pipeline()
+	var cmds []*redis.StringSliceCmd
+	for _, kind := range searched {
+		cmds = append(cmds, pipe.ZRangeByLex(ctx, nameIndexKey(kind), &by))
+	}
+	indexed := pipe.SMembersMap(ctx, nameIndexedKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for i, cmd := range cmds {
+		keys = append(keys, indexObjectKeys(nameIndexKey(searched[i]), cmd.Val())...)
+	}
+	candidates, err := db.loadKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []Object
+	for _, object := range candidates {
+		if db.sameName(object, name) {
+			objects = append(objects, object)
+		}
+	}
+	if len(objects) > 0 || len(keys) > 0 {
+		return objects, nil
+	}
+
+	for _, kind := range searched {
+		if _, ok := indexed.Val()[kind]; ok {
+			continue
+		}
+		err := db.scanResidentObjects(ctx, fmt.Sprintf("%s:*", kind), func(object Object) error {
+			if object.GetKind() == kind && db.sameName(object, name) {
+				objects = append(objects, object)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,36 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetObjectByNameScansOnlyUnindexedKinds(t *testing.T) {
+	db, mr := newTestDB(t)
+	ctx := context.Background()
+	kind := (&Person{}).GetKind()
+
+	if err := db.Store(ctx, &Person{ObjectMeta: ObjectMeta{ID: "1", Name: "John"}, LastName: "Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	// Objects written before the name index existed are not in it.
+	mr.Del(nameIndexKey(kind))
+	if _, err := db.GetObjectByName(ctx, "john"); err != nil {
+		t.Fatalf("GetObjectByName of an unindexed object: %v", err)
+	}
+
+	if _, err := db.RebuildIndexes(ctx, kind); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetObjectByName(ctx, "john"); err != nil {
+		t.Fatalf("GetObjectByName after RebuildIndexes: %v", err)
+	}
+
+	// Once the kind is indexed, entries missing from the index are not
+	// scanned for.
+	mr.Del(nameIndexKey(kind))
+	if _, err := db.GetObjectByName(ctx, "john"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetObjectByName of an indexed kind = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,96 @@
+// Package natscdc publishes a store's change feed to NATS JetStream as
+// Debezium-style change events, on subjects such as objects.animal.update:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	sink, _ := natscdc.New(nc)
+//	err := db.PublishChanges(ctx, sink, objectdb.PublishOptions{Name: "nats"})
+//
+// A stream has to capture the subjects, e.g. "objects.>". Each event
+// carries a Nats-Msg-Id made of the publisher name and change cursor, so
+// the events delivered again after a restart, as PublishChanges delivers
+// at least once, are dropped by the stream's duplicate window.
+package natscdc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"go-assignment/objectdb"
+)
+
+const defaultSubjectPrefix = "objects"
+
+// ops names a ChangeEvent's op in subjects.
+var ops = map[string]string{
+	objectdb.ChangeOpCreate: "create",
+	objectdb.ChangeOpUpdate: "update",
+	objectdb.ChangeOpDelete: "delete",
+}
+
+// Sink is an objectdb.ChangeSink publishing to JetStream.
+type Sink struct {
+	js     jetstream.JetStream
+	prefix string
+}
+
+// Option configures a Sink at construction time.
+type Option func(*Sink)
+
+// WithSubjectPrefix sets the first token of the subjects events are
+// published on, <prefix>.<kind>.<op> with the short kind name and one of
+// create, update and delete. It is "objects" by default.
+func WithSubjectPrefix(prefix string) Option {
+	return func(s *Sink) {
+		s.prefix = prefix
+	}
+}
+
+func New(nc *nats.Conn, opts ...Option) (*Sink, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{js: js, prefix: defaultSubjectPrefix}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Publish publishes events without waiting for each one in turn, and
+// returns once the stream has acknowledged them all.
+func (s *Sink) Publish(ctx context.Context, events []objectdb.ChangeEvent) error {
+	acks := make([]jetstream.PubAckFuture, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(s.prefix + "." + event.Source.Table + "." + ops[event.Op])
+		msg.Data = data
+		msg.Header.Set(jetstream.MsgIDHeader, event.Source.Name+":"+event.Source.Cursor)
+		ack, err := s.js.PublishMsgAsync(msg)
+		if err != nil {
+			return err
+		}
+		acks = append(acks, ack)
+	}
+
+	for _, ack := range acks {
+		select {
+		case <-ack.Ok():
+		case err := <-ack.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// With namespace keys, pii fields are encrypted with a data key of their
+// object's namespace instead of one key for the whole store. Data keys are
+// random, created on first use and stored in Redis wrapped by the master
+// key, so the master key never encrypts data itself and revoking one
+// namespace's data key makes its fields unreadable, and nothing else.
+const namespacePIIPrefix = "enc:v2:"
+
+// ErrKeyRevoked is returned by writes to a namespace whose data key was
+// revoked.
+var ErrKeyRevoked = errors.New("namespace key revoked")
+
+// revokedKey takes the place of a revoked data key. It is shorter than
+// any wrapped key, so the two cannot be confused.
+const revokedKey = "revoked"
+
+// namespaceKeyTTL is how long unwrapped data keys stay in memory, and so
+// how long another store may go on using a key after it was revoked.
+const namespaceKeyTTL = time.Minute
+
+type namespaceKeys struct {
+	master cipher.AEAD
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+type cachedKey struct {
+	aead     cipher.AEAD // nil once revoked
+	loadedAt time.Time
+}
+
+// WithNamespaceKeys enables envelope encryption of pii-tagged fields: each
+// namespace, see NamespaceLabel, gets its own AES-256 data key, wrapped by
+// masterKey, which must be 16, 24 or 32 bytes long. Values encrypted with
+// WithFieldEncryption still decrypt if that key is given too, and are
+// encrypted with namespace keys when next written.
+func WithNamespaceKeys(masterKey []byte) Option {
+	return func(db *RedisObjectDB) {
+		master, err := newGCM(masterKey)
+		if err != nil {
+			panic(fmt.Sprintf("namespace master key: %v", err))
+		}
+		db.namespaceKeys = &namespaceKeys{master: master, keys: map[string]cachedKey{}}
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func namespaceKeyKey(namespace string) string {
+	return fmt.Sprintf("key:namespace:%s", namespace)
+}
+
+func namespaceKeyAdditionalData(namespace string) []byte {
+	return []byte("namespace key\x00" + namespace)
+}
+
+// namespaceKey returns the data key of namespace, creating it if create is
+// set and there is none yet. Decoding has no context of its own, so keys
+// missing from memory are loaded under the store's lifetime.
+func (db *RedisObjectDB) namespaceKey(namespace string, create bool) (cipher.AEAD, error) {
+	nk := db.namespaceKeys
+	nk.mu.Lock()
+	cached, ok := nk.keys[namespace]
+	nk.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < namespaceKeyTTL {
+		if cached.aead == nil {
+			return nil, fmt.Errorf("%w: '%s'", ErrKeyRevoked, namespace)
+		}
+		return cached.aead, nil
+	}
+
+	ctx, cancel := db.withTimeout(db.lifetime, "NamespaceKey")
+	defer cancel()
+
+	wrapped, err := db.redisClient.Get(ctx, namespaceKeyKey(namespace)).Bytes()
+	if err == redis.Nil {
+		if !create {
+			return nil, fmt.Errorf("namespace '%s' has no data key", namespace)
+		}
+		wrapped, err = db.createNamespaceKey(ctx, namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached = cachedKey{loadedAt: time.Now()}
+	if string(wrapped) != revokedKey {
+		cached.aead, err = nk.unwrap(namespace, wrapped)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nk.mu.Lock()
+	nk.keys[namespace] = cached
+	nk.mu.Unlock()
+
+	if cached.aead == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrKeyRevoked, namespace)
+	}
+	return cached.aead, nil
+}
+
+// createNamespaceKey stores a new wrapped data key for namespace, unless
+// another store got there first, and returns whichever was stored.
+func (db *RedisObjectDB) createNamespaceKey(ctx context.Context, namespace string) ([]byte, error) {
+	key := make([]byte, 32)
+	nonce := make([]byte, db.namespaceKeys.master.NonceSize())
+	for _, b := range [][]byte{key, nonce} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	wrapped := db.namespaceKeys.master.Seal(nonce, nonce, key, namespaceKeyAdditionalData(namespace))
+
+	created, err := db.redisClient.SetNX(ctx, namespaceKeyKey(namespace), wrapped, 0).Result()
+	if err != nil || created {
+		return wrapped, err
+	}
+
+	return db.redisClient.Get(ctx, namespaceKeyKey(namespace)).Bytes()
+}
+
+func (nk *namespaceKeys) unwrap(namespace string, wrapped []byte) (cipher.AEAD, error) {
+	if len(wrapped) < nk.master.NonceSize() {
+		return nil, fmt.Errorf("data key of namespace '%s' is malformed", namespace)
+	}
+
+	nonce, sealed := wrapped[:nk.master.NonceSize()], wrapped[nk.master.NonceSize():]
+	key, err := nk.master.Open(nil, nonce, sealed, namespaceKeyAdditionalData(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key of namespace '%s': %w", namespace, err)
+	}
+
+	return newGCM(key)
+}
+
+// RevokeNamespaceKey destroys the data key of namespace, crypto-shredding
+// its pii fields: they read back empty from then on, and writes of objects
+// with pii fields to the namespace fail with ErrKeyRevoked. Revocation
+// cannot be undone. Other stores may keep a cached copy of the key for up
+// to a minute.
+func (db *RedisObjectDB) RevokeNamespaceKey(ctx context.Context, namespace string) error {
+	if db.namespaceKeys == nil {
+		return errors.New("namespace keys are not enabled")
+	}
+
+	if err := db.redisClient.Set(ctx, namespaceKeyKey(namespace), revokedKey, 0).Err(); err != nil {
+		return err
+	}
+
+	db.namespaceKeys.mu.Lock()
+	db.namespaceKeys.keys[namespace] = cachedKey{loadedAt: time.Now()}
+	db.namespaceKeys.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,484 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type Object interface {
+	GetKind() string
+	GetID() string
+	GetName() string
+	SetID(string)
+	SetName(string)
+	GetObjectMeta() *ObjectMeta
+}
+
+//go:generate go run ../cmd/objectgen -type=Person,Animal -kind-package=main
+
+type Person struct {
+	ObjectMeta
+	LastName  string    `json:"last_name"`
+	Birthday  string    `json:"birthday" pii:"encrypt"`
+	BirthDate time.Time `json:"birth_date"`
+}
+
+func (p *Person) Validate() error {
+	if p.BirthDate.After(time.Now()) {
+		return fmt.Errorf("birth date %s is in the future", p.BirthDate.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+type Animal struct {
+	ObjectMeta
+	Type    string `json:"type"`
+	OwnerID string `json:"owner_id" ref:"person"`
+}
+
+// Default capitalizes Type the way the schema's enum spells it, so "dog"
+// and " DOG" are stored as "Dog".
+func (a *Animal) Default() {
+	a.Type = strings.TrimSpace(a.Type)
+	if a.Type != "" {
+		a.Type = strings.ToUpper(a.Type[:1]) + strings.ToLower(a.Type[1:])
+	}
+}
+
+// ErrNotFound is wrapped by every lookup that finds no matching object.
+var ErrNotFound = errors.New("not found")
+
+type ObjectDB interface {
+	Store(ctx context.Context, object Object, opts ...StoreOption) error
+	GetObjectByID(ctx context.Context, id string) (Object, error)
+	GetObjectByName(ctx context.Context, name string) (Object, error)
+	ListObjects(ctx context.Context, kind string) ([]Object, error)
+	DeleteObject(ctx context.Context, id string, opts ...DeleteOption) error
+	Close() error
+}
+
+type RedisObjectDB struct {
+	redisClient    *redis.Client
+	fetchBatchSize int
+	scanCount      int64
+	validateRefs   bool
+	codec          Codec
+
+	compression          Compression
+	compressionThreshold int
+	piiAEAD              cipher.AEAD
+	namespaceKeys        *namespaceKeys
+	integrityKey         []byte
+	changeFeedMaxLen     int64
+	outbox               bool
+	history              bool
+	historyRetention     time.Duration
+	writeBehind          *writeBehind
+	quotas               *Quotas
+	maxObjectSize        int
+	gcInterval           time.Duration
+	gc                   gcState
+	retentionRules       []RetentionRule
+	retentionInterval    time.Duration
+	retention            retentionState
+	archive              ArchiveBackend
+	archivalRules        []ArchivalRule
+	archivalInterval     time.Duration
+	archival             archivalState
+	pageTokenKey         []byte
+	pageTokenTTL         time.Duration
+	timeout              time.Duration
+	opTimeouts           map[string]time.Duration
+	birthdays            *BirthdayOptions
+	strictNames          bool
+	admissionRules       []AdmissionRule
+
+	// lifetime is cancelled by Close to stop background goroutines.
+	lifetime  context.Context
+	cancel    context.CancelFunc
+	workers   sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewRedisObjectDB(client *redis.Client, opts ...Option) *RedisObjectDB {
+	lifetime, cancel := context.WithCancel(context.Background())
+	db := &RedisObjectDB{
+		redisClient:    client,
+		fetchBatchSize: defaultFetchBatchSize,
+		scanCount:      defaultScanCount,
+		codec:          JSONCodec{},
+		lifetime:       lifetime,
+		cancel:         cancel,
+		retention:      retentionState{total: map[string]int64{}},
+		pageTokenTTL:   defaultPageTokenTTL,
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	if db.pageTokenKey == nil {
+		db.pageTokenKey = randomPageTokenKey()
+	}
+	if db.writeBehind != nil {
+		db.startWriteBehind()
+	}
+	if db.gcInterval > 0 {
+		db.startIndexGC()
+	}
+	if db.retentionInterval > 0 && len(db.retentionRules) > 0 {
+		db.startRetention()
+	}
+	if db.archivalInterval > 0 && len(db.archivalRules) > 0 {
+		db.startArchival()
+	}
+
+	return db
+}
+
+func (db *RedisObjectDB) Store(ctx context.Context, object Object, opts ...StoreOption) error {
+	ctx, cancel := db.withTimeout(ctx, "Store")
+	defer cancel()
+
+	var options storeOptions
+	for _, opt := range opts {
+		opt.applyStore(&options)
+	}
+
+	err := db.validateObject(ctx, object, nil)
+	if err != nil {
+		return err
+	}
+	if options.dryRun != nil {
+		return db.planStore(ctx, object, options.dryRun)
+	}
+	if options.idempotencyKey != "" {
+		return db.storeIdempotent(ctx, object, options)
+	}
+	if db.writeBehind != nil {
+		return db.bufferWrite(ctx, object)
+	}
+
+	previous, err := db.previousObject(ctx, object.GetKind(), object.GetID())
+	if err != nil {
+		return err
+	}
+
+	value, err := db.prepareWrite(ctx, object, previous)
+	if err != nil {
+		return err
+	}
+
+	pipe := db.redisClient.TxPipeline()
+	db.queueWrite(ctx, pipe, object, previous, value)
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateObject runs every check Store makes before touching the stored
+// version: labels, schema, the kind's own Validate and, if enabled,
+// references. References to a key in pending count as existing, as those
+// objects are written in the same transaction.
+func (db *RedisObjectDB) validateObject(ctx context.Context, object Object, pending map[string]bool) error {
+	err := checkObjectType(object)
+	if err != nil {
+		return err
+	}
+
+	if d, ok := object.(Defaulter); ok {
+		d.Default()
+	}
+
+	err = db.normalizeBirthday(object)
+	if err != nil {
+		return err
+	}
+
+	err = validateLabels(object.GetObjectMeta().Labels)
+	if err != nil {
+		return err
+	}
+
+	err = validateSchema(object)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := object.(Validator); ok {
+		err = v.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if db.validateRefs {
+		err = db.checkReferences(ctx, object, pending)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareWrite stamps object's metadata against previous and returns the
+// value to store, once it has passed the JSON schema, admission rules, size
+// limit and quotas.
+func (db *RedisObjectDB) prepareWrite(ctx context.Context, object, previous Object) ([]byte, error) {
+	stampObjectMeta(object, previous, time.Now())
+
+	err := validateJSONSchema(object)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.admit(ctx, object, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := db.encryptFields(object)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := db.encode(db.codec, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.checkObjectSize(object, value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.checkQuotas(ctx, object, previous, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// queueWrite queues storing value for object, with its index and change
+// feed updates, on pipe.
+func (db *RedisObjectDB) queueWrite(ctx context.Context, pipe redis.Pipeliner, object, previous Object, value []byte) {
+	queueSet(ctx, pipe, objectKey(object.GetKind(), object.GetID()), objectNamespace(object), value)
+	updateIndexes(ctx, pipe, previous, object)
+	db.recordChange(ctx, pipe, ChangePut, object.GetKind(), object.GetID(), value)
+}
+
+// GetObjectByID returns the object with id of whichever kind has one,
+// reading its key in every kind rather than scanning.
+func (db *RedisObjectDB) GetObjectByID(ctx context.Context, id string) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "GetObjectByID")
+	defer cancel()
+
+	var keys []string
+	for _, kind := range Kinds() {
+		keys = append(keys, objectKey(kind, id))
+	}
+	objects, err := db.loadKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("object with ID '%s' %w", id, ErrNotFound)
+	}
+
+	return objects[0], nil
+}
+
+// GetObjectByName returns an object called name, ignoring case, Unicode
+// normalization and surrounding white space unless WithStrictNames is
+// used.
+func (db *RedisObjectDB) GetObjectByName(ctx context.Context, name string) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "GetObjectByName")
+	defer cancel()
+
+	objects, err := db.objectsByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("object with name '%s' %w", name, ErrNotFound)
+	}
+
+	return objects[0], nil
+}
+
+func (db *RedisObjectDB) ListObjects(ctx context.Context, kind string) ([]Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListObjects")
+	defer cancel()
+
+	var objects []Object
+	err := db.scanObjects(ctx, fmt.Sprintf("%s:*", kind), func(object Object) error {
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+func (db *RedisObjectDB) DeleteObject(ctx context.Context, id string, opts ...DeleteOption) error {
+	ctx, cancel := db.withTimeout(ctx, "DeleteObject")
+	defer cancel()
+
+	object, err := db.GetObjectByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var options deleteOptions
+	for _, opt := range opts {
+		opt.applyDelete(&options)
+	}
+
+	if options.access != nil {
+		err = db.authorizeDependents(ctx, object, options, map[string]bool{})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.handleDependents(ctx, object, options, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	return db.deleteOrPlan(ctx, object, options)
+}
+
+// removeObject deletes object and its index entries in one MULTI.
+func (db *RedisObjectDB) removeObject(ctx context.Context, object Object) error {
+	pipe := db.redisClient.TxPipeline()
+	queueDel(ctx, pipe, objectKey(object.GetKind(), object.GetID()))
+	updateIndexes(ctx, pipe, object, nil)
+	db.recordChange(ctx, pipe, ChangeDelete, object.GetKind(), object.GetID(), nil)
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// scanObjects walks every object key matching pattern and calls fn for each
+// decoded object. Values are fetched with one MGET per fetchBatchSize keys
+// rather than one GET per key.
+func (db *RedisObjectDB) scanObjects(ctx context.Context, pattern string, fn func(Object) error) error {
+	return db.scan(ctx, pattern, false, fn)
+}
+
+// scanResidentObjects is scanObjects skipping archived objects without
+// fetching them, for scans that look for objects the indexes miss: those
+// archived are still in the indexes.
+func (db *RedisObjectDB) scanResidentObjects(ctx context.Context, pattern string, fn func(Object) error) error {
+	return db.scan(ctx, pattern, true, fn)
+}
+
+func (db *RedisObjectDB) scan(ctx context.Context, pattern string, resident bool, fn func(Object) error) error {
+	iter := db.redisClient.Scan(ctx, 0, pattern, db.scanCount).Iterator()
+
+	keys := make([]string, 0, db.fetchBatchSize)
+	for iter.Next(ctx) {
+		if _, _, ok := parseObjectKey(iter.Val()); !ok {
+			continue
+		}
+
+		keys = append(keys, iter.Val())
+		if len(keys) < db.fetchBatchSize {
+			continue
+		}
+
+		err := db.fetch(ctx, keys, resident, fn)
+		if err != nil {
+			return err
+		}
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	return db.fetch(ctx, keys, resident, fn)
+}
+
+// loadKeys reads the objects stored at keys, which must be object keys,
+// with load rather than decode. Keys that no longer exist are skipped.
+func (db *RedisObjectDB) loadKeys(ctx context.Context, keys []string) ([]Object, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	vals, err := db.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []Object
+	for i, val := range vals {
+		data, ok := val.(string)
+		if !ok {
+			continue
+		}
+
+		kind, _, _ := parseObjectKey(keys[i])
+		object, err := db.load(kind, []byte(data))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+func (db *RedisObjectDB) fetchObjects(ctx context.Context, keys []string, fn func(Object) error) error {
+	return db.fetch(ctx, keys, false, fn)
+}
+
+func (db *RedisObjectDB) fetch(ctx context.Context, keys []string, resident bool, fn func(Object) error) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	vals, err := db.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+
+	for i, val := range vals {
+		// Keys deleted between SCAN and MGET come back as nil.
+		data, ok := val.(string)
+		if !ok || resident && db.isArchived([]byte(data)) {
+			continue
+		}
+
+		kind, _, _ := parseObjectKey(keys[i])
+		object, err := db.decode(kind, []byte(data))
+		if err != nil {
+			return err
+		}
+
+		err = fn(object)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package objectdb
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestDB returns a store with opts on a fresh miniredis, closed when t
+// ends, and the miniredis to inspect or tamper with.
+func newTestDB(t *testing.T, opts ...Option) (*RedisObjectDB, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	db := NewRedisObjectDB(redis.NewClient(&redis.Options{Addr: mr.Addr()}), opts...)
+	t.Cleanup(func() { db.Close() })
+
+	return db, mr
+}
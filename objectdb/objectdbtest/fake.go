@@ -0,0 +1,196 @@
+// Package objectdbtest provides ObjectDBs for testing code that uses a
+// store: FakeObjectDB needs no Redis behind it, and FaultyObjectDB makes a
+// real store misbehave on purpose.
+//
+// A fake with a canned error looks like this:
+//
+//	db := objectdbtest.NewFakeObjectDB(&objectdb.Person{ObjectMeta: objectdb.ObjectMeta{ID: "1"}})
+//	db.StoreFunc = func(ctx context.Context, object objectdb.Object, opts ...objectdb.StoreOption) error {
+//		return objectdb.ErrQuotaExceeded
+//	}
+//	runCodeUnderTest(db)
+//	if calls := db.CallsTo("Store"); len(calls) != 1 { ... }
+package objectdbtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go-assignment/objectdb"
+)
+
+// Call is one recorded method call, with its arguments in order after the
+// context. Variadic options are recorded as a single slice.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// FakeObjectDB is an in-memory ObjectDB that records every call. Setting
+// one of the Func fields replaces the in-memory behaviour of that method,
+// to return canned results or errors. Objects are kept by ID and copied in
+// and out, but are not validated, stamped or indexed, and store and delete
+// options are ignored. It is safe for concurrent use.
+type FakeObjectDB struct {
+	StoreFunc           func(ctx context.Context, object objectdb.Object, opts ...objectdb.StoreOption) error
+	GetObjectByIDFunc   func(ctx context.Context, id string) (objectdb.Object, error)
+	GetObjectByNameFunc func(ctx context.Context, name string) (objectdb.Object, error)
+	ListObjectsFunc     func(ctx context.Context, kind string) ([]objectdb.Object, error)
+	DeleteObjectFunc    func(ctx context.Context, id string, opts ...objectdb.DeleteOption) error
+	CloseFunc           func() error
+
+	mu      sync.Mutex
+	objects map[string]objectdb.Object
+	calls   []Call
+}
+
+var _ objectdb.ObjectDB = (*FakeObjectDB)(nil)
+
+// NewFakeObjectDB returns a fake holding copies of objects.
+func NewFakeObjectDB(objects ...objectdb.Object) *FakeObjectDB {
+	f := &FakeObjectDB{objects: map[string]objectdb.Object{}}
+	for _, object := range objects {
+		f.objects[object.GetID()] = clone(object)
+	}
+
+	return f
+}
+
+// Calls returns the calls made so far, oldest first.
+func (f *FakeObjectDB) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made so far to method, e.g. "Store".
+func (f *FakeObjectDB) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+func (f *FakeObjectDB) record(method string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+}
+
+func (f *FakeObjectDB) Store(ctx context.Context, object objectdb.Object, opts ...objectdb.StoreOption) error {
+	f.record("Store", object, opts)
+	if f.StoreFunc != nil {
+		return f.StoreFunc(ctx, object, opts...)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[object.GetID()] = clone(object)
+	return nil
+}
+
+func (f *FakeObjectDB) GetObjectByID(ctx context.Context, id string) (objectdb.Object, error) {
+	f.record("GetObjectByID", id)
+	if f.GetObjectByIDFunc != nil {
+		return f.GetObjectByIDFunc(ctx, id)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[id]
+	if !ok {
+		return nil, fmt.Errorf("object with ID '%s' %w", id, objectdb.ErrNotFound)
+	}
+	return clone(object), nil
+}
+
+func (f *FakeObjectDB) GetObjectByName(ctx context.Context, name string) (objectdb.Object, error) {
+	f.record("GetObjectByName", name)
+	if f.GetObjectByNameFunc != nil {
+		return f.GetObjectByNameFunc(ctx, name)
+	}
+
+	for _, object := range f.sorted("") {
+		if object.GetName() == name {
+			return object, nil
+		}
+	}
+	return nil, fmt.Errorf("object with name '%s' %w", name, objectdb.ErrNotFound)
+}
+
+func (f *FakeObjectDB) ListObjects(ctx context.Context, kind string) ([]objectdb.Object, error) {
+	f.record("ListObjects", kind)
+	if f.ListObjectsFunc != nil {
+		return f.ListObjectsFunc(ctx, kind)
+	}
+
+	return f.sorted(kind), nil
+}
+
+func (f *FakeObjectDB) DeleteObject(ctx context.Context, id string, opts ...objectdb.DeleteOption) error {
+	f.record("DeleteObject", id, opts)
+	if f.DeleteObjectFunc != nil {
+		return f.DeleteObjectFunc(ctx, id, opts...)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[id]; !ok {
+		return fmt.Errorf("object with ID '%s' %w", id, objectdb.ErrNotFound)
+	}
+	delete(f.objects, id)
+	return nil
+}
+
+func (f *FakeObjectDB) Close() error {
+	f.record("Close")
+	if f.CloseFunc != nil {
+		return f.CloseFunc()
+	}
+
+	return nil
+}
+
+// sorted returns copies of the objects of kind, or of all kinds for "",
+// ordered by ID.
+func (f *FakeObjectDB) sorted(kind string) []objectdb.Object {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var objects []objectdb.Object
+	for _, object := range f.objects {
+		if kind == "" || object.GetKind() == kind {
+			objects = append(objects, clone(object))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].GetID() < objects[j].GetID() })
+
+	return objects
+}
+
+// clone copies object through JSON so the caller and the fake never share
+// it.
+func clone(object objectdb.Object) objectdb.Object {
+	copied, err := objectdb.NewObject(object.GetKind())
+	if err != nil {
+		return object
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return object
+	}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return object
+	}
+
+	return copied
+}
@@ -0,0 +1,173 @@
+package objectdbtest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go-assignment/objectdb"
+)
+
+// ErrInjected is the error FaultyObjectDB fails calls with unless a Fault
+// names another.
+var ErrInjected = errors.New("injected fault")
+
+// Fault describes what goes wrong with the calls of one operation.
+type Fault struct {
+	// Latency delays every call, plus a random extra of up to Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the fraction of calls, from 0 to 1, that fail with Err,
+	// or ErrInjected if Err is nil, without reaching the store.
+	ErrorRate float64
+	Err       error
+	// TimeoutRate is the fraction of calls that hang until their context
+	// ends, as with an unresponsive server, and return its error.
+	TimeoutRate float64
+}
+
+// FaultOption configures a FaultyObjectDB at construction time.
+type FaultOption func(*FaultyObjectDB)
+
+// WithFault sets the fault of one operation, named after its ObjectDB
+// method, e.g. "ListObjects".
+func WithFault(op string, f Fault) FaultOption {
+	return func(db *FaultyObjectDB) {
+		db.faults[op] = f
+	}
+}
+
+// WithDefaultFault sets the fault of operations without their own.
+func WithDefaultFault(f Fault) FaultOption {
+	return func(db *FaultyObjectDB) {
+		db.fallback = f
+	}
+}
+
+// WithSeed makes the injected faults repeat from run to run.
+func WithSeed(seed int64) FaultOption {
+	return func(db *FaultyObjectDB) {
+		db.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// FaultyObjectDB wraps an ObjectDB and injects latency, errors and hangs
+// into its calls, so applications can check how they retry and degrade
+// when the store misbehaves. Close is passed through untouched.
+type FaultyObjectDB struct {
+	db objectdb.ObjectDB
+
+	mu       sync.Mutex
+	faults   map[string]Fault
+	fallback Fault
+	rand     *rand.Rand
+}
+
+var _ objectdb.ObjectDB = (*FaultyObjectDB)(nil)
+
+func NewFaultyObjectDB(db objectdb.ObjectDB, opts ...FaultOption) *FaultyObjectDB {
+	f := &FaultyObjectDB{
+		db:     db,
+		faults: map[string]Fault{},
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// SetFault changes the fault of op while the store is in use; the zero
+// Fault turns injection off for it.
+func (f *FaultyObjectDB) SetFault(op string, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults[op] = fault
+}
+
+// inject applies the fault of op to one call and returns the error the call
+// should fail with, if any.
+func (f *FaultyObjectDB) inject(ctx context.Context, op string) error {
+	f.mu.Lock()
+	fault, ok := f.faults[op]
+	if !ok {
+		fault = f.fallback
+	}
+	delay := fault.Latency
+	if fault.Jitter > 0 {
+		delay += time.Duration(f.rand.Int63n(int64(fault.Jitter)))
+	}
+	roll := f.rand.Float64()
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case roll < fault.TimeoutRate:
+		<-ctx.Done()
+		return ctx.Err()
+	case roll < fault.TimeoutRate+fault.ErrorRate:
+		if fault.Err != nil {
+			return fault.Err
+		}
+		return ErrInjected
+	}
+
+	return nil
+}
+
+func (f *FaultyObjectDB) Store(ctx context.Context, object objectdb.Object, opts ...objectdb.StoreOption) error {
+	if err := f.inject(ctx, "Store"); err != nil {
+		return err
+	}
+
+	return f.db.Store(ctx, object, opts...)
+}
+
+func (f *FaultyObjectDB) GetObjectByID(ctx context.Context, id string) (objectdb.Object, error) {
+	if err := f.inject(ctx, "GetObjectByID"); err != nil {
+		return nil, err
+	}
+
+	return f.db.GetObjectByID(ctx, id)
+}
+
+func (f *FaultyObjectDB) GetObjectByName(ctx context.Context, name string) (objectdb.Object, error) {
+	if err := f.inject(ctx, "GetObjectByName"); err != nil {
+		return nil, err
+	}
+
+	return f.db.GetObjectByName(ctx, name)
+}
+
+func (f *FaultyObjectDB) ListObjects(ctx context.Context, kind string) ([]objectdb.Object, error) {
+	if err := f.inject(ctx, "ListObjects"); err != nil {
+		return nil, err
+	}
+
+	return f.db.ListObjects(ctx, kind)
+}
+
+func (f *FaultyObjectDB) DeleteObject(ctx context.Context, id string, opts ...objectdb.DeleteOption) error {
+	if err := f.inject(ctx, "DeleteObject"); err != nil {
+		return err
+	}
+
+	return f.db.DeleteObject(ctx, id, opts...)
+}
+
+func (f *FaultyObjectDB) Close() error {
+	return f.db.Close()
+}
@@ -0,0 +1,82 @@
+package objectdb
+
+import "time"
+
+// Option configures a RedisObjectDB at construction time.
+type Option func(*RedisObjectDB)
+
+const (
+	defaultFetchBatchSize = 100
+	defaultScanCount      = 0 // let Redis pick, currently 10
+)
+
+// WithFetchBatchSize sets how many keys are fetched per MGET while listing.
+func WithFetchBatchSize(n int) Option {
+	return func(db *RedisObjectDB) {
+		if n > 0 {
+			db.fetchBatchSize = n
+		}
+	}
+}
+
+// WithReferenceValidation makes Store reject objects whose `ref` fields
+// point at objects that do not exist, with ErrBrokenReference.
+func WithReferenceValidation() Option {
+	return func(db *RedisObjectDB) {
+		db.validateRefs = true
+	}
+}
+
+// WithCodec sets the codec new writes use. c is registered if its format
+// is not known yet, so that values it writes can be read back.
+func WithCodec(c Codec) Option {
+	return func(db *RedisObjectDB) {
+		if _, ok := codecs[c.Format()]; !ok {
+			RegisterCodec(c)
+		}
+		db.codec = c
+	}
+}
+
+// WithMsgpack stores objects as MessagePack instead of JSON. Objects
+// already stored as JSON keep decoding, and are converted when rewritten.
+func WithMsgpack() Option {
+	return func(db *RedisObjectDB) {
+		db.codec = MsgpackCodec{}
+	}
+}
+
+// WithCBOR stores objects as CBOR (RFC 8949), a compact binary format that
+// keeps time.Time values intact including their zone offset.
+func WithCBOR() Option {
+	return func(db *RedisObjectDB) {
+		db.codec = CBORCodec{}
+	}
+}
+
+// WithProtobuf stores objects as their objectpb messages. Only kinds with
+// a protobuf converter can be stored this way.
+func WithProtobuf() Option {
+	return func(db *RedisObjectDB) {
+		db.codec = ProtobufCodec{}
+	}
+}
+
+// WithScanCount sets the COUNT hint passed to SCAN. Larger values mean fewer
+// round trips over big keyspaces at the cost of longer individual calls.
+func WithScanCount(n int64) Option {
+	return func(db *RedisObjectDB) {
+		db.scanCount = n
+	}
+}
+
+// StoreOption changes how a single Store call writes.
+type StoreOption interface {
+	applyStore(*storeOptions)
+}
+
+type storeOptions struct {
+	idempotencyKey string
+	idempotencyTTL time.Duration
+	dryRun         *DryRunResult
+}
@@ -0,0 +1,148 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The outbox is a Redis stream like the change feed, but entries stay in it
+// until RelayOutbox has published them instead of falling out after a
+// while.
+const outboxStreamKey = "outbox"
+
+// outboxDeadLetterKey is the stream RelayOutbox moves entries it cannot
+// decode to, with the fields they had plus "outbox_id", their ID in the
+// outbox, and "error".
+const outboxDeadLetterKey = "outbox:dead"
+
+// WithOutbox records every write in the outbox too, in the same MULTI as
+// the write itself, for RelayOutbox to drain to a message broker. Unlike
+// publishing the change feed, no event is lost however long the relay is
+// down, at the cost of the outbox growing meanwhile.
+func WithOutbox() Option {
+	return func(db *RedisObjectDB) {
+		db.outbox = true
+	}
+}
+
+type RelayOptions struct {
+	// Name is the ChangeSource.Name of the events; "outbox" if empty.
+	Name string
+	// OnProgress is called after each batch the sink acknowledged.
+	OnProgress func(PublishProgress)
+	// DecryptPII publishes pii fields decrypted, as reads return them,
+	// instead of as stored; see PublishOptions.DecryptPII.
+	DecryptPII bool
+}
+
+// RelayOutbox drains the outbox to sink until ctx ends or sink fails,
+// oldest entries first in batches of up to 100, deleting each batch once
+// sink has acknowledged it. A batch published just before a crash is
+// published again, so delivery is at least once. Entries that cannot be
+// decoded, e.g. signed with another integrity key or encrypted with a
+// revoked namespace key, would never publish; they are moved to the
+// "outbox:dead" stream instead, counted in PublishProgress.DeadLettered,
+// and the relay carries on. Run one relay per store, e.g. under an
+// election, as concurrent relays would publish the same entries.
+func (db *RedisObjectDB) RelayOutbox(ctx context.Context, sink ChangeSink, opts RelayOptions) error {
+	name := opts.Name
+	if name == "" {
+		name = "outbox"
+	}
+
+	progress := PublishProgress{Name: name}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Relayed entries are deleted, so reading from the start always
+		// finds the oldest one left.
+		streams, err := db.redisClient.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{outboxStreamKey, "0-0"},
+			Count:   watchBatchSize,
+			Block:   watchPollInterval,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var batch []ChangeEvent
+		var ids []string
+		var dead []redis.XMessage
+		var reasons []error
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				change, err := db.decodeChange(msg, !opts.DecryptPII)
+				if err != nil {
+					dead = append(dead, msg)
+					reasons = append(reasons, err)
+					continue
+				}
+				batch = append(batch, changeEvent(name, change))
+				ids = append(ids, msg.ID)
+			}
+		}
+
+		if len(dead) > 0 {
+			if err := db.deadLetterOutbox(ctx, dead, reasons); err != nil {
+				return err
+			}
+			progress.DeadLettered += int64(len(dead))
+			if len(batch) == 0 {
+				progress.Cursor = dead[len(dead)-1].ID
+				if opts.OnProgress != nil {
+					opts.OnProgress(progress)
+				}
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+
+		if err := sink.Publish(ctx, batch); err != nil {
+			return err
+		}
+		if err := db.redisClient.XDel(ctx, outboxStreamKey, ids...).Err(); err != nil {
+			return err
+		}
+		progress.Cursor = ids[len(ids)-1]
+		progress.Published += int64(len(batch))
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+}
+
+// queueDeadLetter queues adding msg to the dead-letter stream with the
+// fields it had, plus its ID under idField and reason under "error".
+func queueDeadLetter(ctx context.Context, pipe redis.Pipeliner, stream, idField string, msg redis.XMessage, reason error) {
+	values := make(map[string]interface{}, len(msg.Values)+2)
+	for field, value := range msg.Values {
+		values[field] = value
+	}
+	values[idField] = msg.ID
+	values["error"] = reason.Error()
+	pipe.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values})
+}
+
+// deadLetterOutbox moves msgs, which failed to decode for reasons, from the
+// outbox to the dead-letter stream in one MULTI.
+func (db *RedisObjectDB) deadLetterOutbox(ctx context.Context, msgs []redis.XMessage, reasons []error) error {
+	_, err := db.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		ids := make([]string, len(msgs))
+		for i, msg := range msgs {
+			queueDeadLetter(ctx, pipe, outboxDeadLetterKey, "outbox_id", msg, fmt.Errorf("outbox entry %s: %w", msg.ID, reasons[i]))
+			ids[i] = msg.ID
+		}
+		pipe.XDel(ctx, outboxStreamKey, ids...)
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,343 @@
+package objectdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidPageToken is returned by ListPage for tokens that were not
+// issued by a store sharing its page token key, have expired, or belong to
+// a different listing.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+const (
+	defaultPageTokenTTL = time.Hour
+	defaultPageLimit    = 100
+)
+
+// PageOrder is the order ListPage returns a kind's objects in.
+type PageOrder string
+
+const (
+	// OrderByID sorts by ID, byte-wise.
+	OrderByID PageOrder = "id"
+	// OrderByCreatedAt sorts oldest first, by ID among objects created in
+	// the same microsecond.
+	OrderByCreatedAt PageOrder = "created_at"
+)
+
+func (o PageOrder) valid() bool {
+	return o == OrderByID || o == OrderByCreatedAt
+}
+
+// WithPageTokens sets the key ListPage signs its continuation tokens with
+// and how long they stay valid, an hour if ttl is 0. Without a key each
+// store makes up its own, so stores behind one load balancer must share a
+// key for clients to page through them.
+func WithPageTokens(key []byte, ttl time.Duration) Option {
+	return func(db *RedisObjectDB) {
+		db.pageTokenKey = key
+		if ttl > 0 {
+			db.pageTokenTTL = ttl
+		}
+	}
+}
+
+// PageRequest asks ListPage for one page of a listing.
+type PageRequest struct {
+	// Kind is the kind listed, by registered or short name.
+	Kind string
+	// Filters limit the page to objects matching every one of them.
+	Filters []Filter
+	// Order is OrderByID if empty.
+	Order PageOrder
+	// Limit caps the objects on the page, 100 if 0.
+	Limit int
+	// Token continues the listing a previous Page.Next was returned for;
+	// empty starts at the beginning. The kind, filters and order must be the
+	// same as for the first page; the limit may change.
+	Token string
+}
+
+// Page is one page of a listing.
+type Page struct {
+	Items []Object `json:"items"`
+	// Next continues the listing, and is empty once it is complete.
+	Next string `json:"next,omitempty"`
+}
+
+// pageToken is what a continuation token carries, signed so clients can
+// neither forge positions nor replay a token against another listing.
+type pageToken struct {
+	Kind string `json:"k"`
+	// Query fingerprints the filters and order.
+	Query string `json:"q"`
+	// Cursor is the last object examined, as a pagePosition.
+	Cursor  string `json:"c"`
+	Expires int64  `json:"e"`
+}
+
+// ListPage returns one page of the objects of req.Kind matching
+// req.Filters, read in req.Order from the ID index or the created_at range
+// index. A token holds the position of the last object examined, not a
+// SCAN cursor, so writes during a listing neither skip nor repeat objects:
+// every object that exists throughout it is returned exactly once, and
+// ones created or deleted meanwhile may or may not be. Pages are full
+// unless the listing is complete, though the last page may be empty.
+// Objects written before the ID index existed are only listed once
+// RebuildIndexes has run for their kind.
+func (db *RedisObjectDB) ListPage(ctx context.Context, req PageRequest) (Page, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListPage")
+	defer cancel()
+
+	kind, ok := lookupKind(req.Kind)
+	if !ok {
+		return Page{}, fmt.Errorf("%w '%s'", ErrUnknownKind, req.Kind)
+	}
+	for _, f := range req.Filters {
+		if !f.Op.valid() {
+			return Page{}, fmt.Errorf("unknown filter operator '%s'", f.Op)
+		}
+		if !hasField(kind, f.Field) {
+			return Page{}, fmt.Errorf("%s has no field '%s'", kind, f.Field)
+		}
+	}
+	order := req.Order
+	if order == "" {
+		order = OrderByID
+	}
+	if !order.valid() {
+		return Page{}, fmt.Errorf("unknown page order '%s'", order)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	query := pageQuery(order, req.Filters)
+
+	var pos pagePosition
+	if req.Token != "" {
+		token, err := db.openPageToken(req.Token)
+		if err != nil {
+			return Page{}, err
+		}
+		if token.Kind != kind || token.Query != query {
+			return Page{}, fmt.Errorf("%w: token is for a different listing", ErrInvalidPageToken)
+		}
+		if pos, err = parsePagePosition(order, token.Cursor); err != nil {
+			return Page{}, err
+		}
+	}
+
+	page := Page{Items: []Object{}}
+	for len(page.Items) < limit {
+		// Without filters every candidate makes the page, so there is no
+		// point reading more than it has room for.
+		n := limit - len(page.Items)
+		if len(req.Filters) > 0 && n < db.fetchBatchSize {
+			n = db.fetchBatchSize
+		}
+		candidates, err := db.pageCandidates(ctx, kind, order, pos, n)
+		if err != nil {
+			return Page{}, err
+		}
+
+		objects := map[string]Object{}
+		keys := make([]string, len(candidates))
+		for i, c := range candidates {
+			keys[i] = c.member
+		}
+		err = db.fetchObjects(ctx, keys, func(object Object) error {
+			objects[objectKey(object.GetKind(), object.GetID())] = object
+			return nil
+		})
+		if err != nil {
+			return Page{}, err
+		}
+
+		examined := 0
+		for _, c := range candidates {
+			if len(page.Items) == limit {
+				break
+			}
+			pos = c
+			examined++
+			if object, ok := objects[c.member]; ok && matches(object, req.Filters) {
+				page.Items = append(page.Items, object)
+			}
+		}
+		if examined == len(candidates) && len(candidates) < n {
+			return page, nil
+		}
+	}
+
+	page.Next = db.signPageToken(pageToken{
+		Kind:    kind,
+		Query:   query,
+		Cursor:  pos.encode(order),
+		Expires: time.Now().Add(db.pageTokenTTL).Unix(),
+	})
+
+	return page, nil
+}
+
+// pagePosition is an entry of the index a listing reads from. score is
+// only used by OrderByCreatedAt.
+type pagePosition struct {
+	score  float64
+	member string
+}
+
+func (p pagePosition) encode(order PageOrder) string {
+	if order == OrderByID {
+		return p.member
+	}
+
+	return strconv.FormatFloat(p.score, 'f', -1, 64) + " " + p.member
+}
+
+func parsePagePosition(order PageOrder, s string) (pagePosition, error) {
+	if order == OrderByID {
+		return pagePosition{member: s}, nil
+	}
+
+	score, member, ok := strings.Cut(s, " ")
+	f, err := strconv.ParseFloat(score, 64)
+	if !ok || err != nil {
+		return pagePosition{}, fmt.Errorf("%w: bad position", ErrInvalidPageToken)
+	}
+
+	return pagePosition{score: f, member: member}, nil
+}
+
+// pageCandidates returns up to n index entries of kind after pos in order;
+// fewer only once the index is exhausted. An empty pos is the start.
+func (db *RedisObjectDB) pageCandidates(ctx context.Context, kind string, order PageOrder, pos pagePosition, n int) ([]pagePosition, error) {
+	if order == OrderByID {
+		min := "-"
+		if pos.member != "" {
+			min = "(" + pos.member
+		}
+		members, err := db.redisClient.ZRangeByLex(ctx, idIndexKey(kind), &redis.ZRangeBy{
+			Min:   min,
+			Max:   "+",
+			Count: int64(n),
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]pagePosition, len(members))
+		for i, member := range members {
+			candidates[i] = pagePosition{member: member}
+		}
+		return candidates, nil
+	}
+
+	// Objects created in the same microsecond share a score and sort by
+	// member, so the range starts at pos's score and skips those up to and
+	// including pos itself.
+	min := "-inf"
+	if pos.member != "" {
+		min = strconv.FormatFloat(pos.score, 'f', -1, 64)
+	}
+	var candidates []pagePosition
+	var offset int64
+	for len(candidates) < n {
+		want := n - len(candidates)
+		zs, err := db.redisClient.ZRangeByScoreWithScores(ctx, rangeIndexKey(kind, "created_at"), &redis.ZRangeBy{
+			Min:    min,
+			Max:    "+inf",
+			Offset: offset,
+			Count:  int64(want),
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, z := range zs {
+			c := pagePosition{score: z.Score, member: z.Member.(string)}
+			if pos.member != "" && c.score == pos.score && c.member <= pos.member {
+				continue
+			}
+			candidates = append(candidates, c)
+		}
+		if len(zs) < want {
+			break
+		}
+		offset += int64(len(zs))
+	}
+
+	return candidates, nil
+}
+
+// pageQuery fingerprints order and filters, in order, as the token has no
+// room for the filters themselves.
+func pageQuery(order PageOrder, filters []Filter) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", order)
+	for _, f := range filters {
+		fmt.Fprintf(h, "%s\x00", f)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Tokens are the base64url JSON of a pageToken, a dot and the base64url
+// HMAC-SHA256 of the former.
+func (db *RedisObjectDB) signPageToken(token pageToken) string {
+	data, _ := json.Marshal(token)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(db.pageTokenMAC(payload))
+}
+
+func (db *RedisObjectDB) openPageToken(s string) (pageToken, error) {
+	payload, sig, ok := strings.Cut(s, ".")
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if !ok || err != nil || !hmac.Equal(mac, db.pageTokenMAC(payload)) {
+		return pageToken{}, fmt.Errorf("%w: bad signature", ErrInvalidPageToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return pageToken{}, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	var token pageToken
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&token); err != nil {
+		return pageToken{}, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	if time.Now().Unix() > token.Expires {
+		return pageToken{}, fmt.Errorf("%w: token expired", ErrInvalidPageToken)
+	}
+
+	return token, nil
+}
+
+func (db *RedisObjectDB) pageTokenMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, db.pageTokenKey)
+	mac.Write([]byte("page token\x00" + payload))
+	return mac.Sum(nil)
+}
+
+func randomPageTokenKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("page token key: %v", err))
+	}
+
+	return key
+}
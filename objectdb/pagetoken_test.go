@@ -0,0 +1,148 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func storePersons(t *testing.T, db *RedisObjectDB, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		person := &Person{ObjectMeta: ObjectMeta{ID: fmt.Sprint(i), Name: fmt.Sprintf("Person %d", i)}, LastName: "Doe"}
+		if err := db.Store(context.Background(), person); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listAll pages through req, limit objects at a time, returning the IDs
+// in the order they were listed.
+func listAll(t *testing.T, db *RedisObjectDB, req PageRequest) []string {
+	t.Helper()
+
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("listing does not end")
+		}
+		page, err := db.ListPage(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, object := range page.Items {
+			ids = append(ids, object.GetID())
+		}
+		if page.Next == "" {
+			return ids
+		}
+		req.Token = page.Next
+	}
+}
+
+func firstPageToken(t *testing.T, db *RedisObjectDB, req PageRequest) string {
+	t.Helper()
+
+	page, err := db.ListPage(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Next == "" {
+		t.Fatal("first page is the last")
+	}
+
+	return page.Next
+}
+
+func TestPageTokensRejectTampering(t *testing.T) {
+	db, _ := newTestDB(t, WithPageTokens([]byte("key"), 0))
+	storePersons(t, db, 3)
+	req := PageRequest{Kind: "person", Limit: 1}
+	token := firstPageToken(t, db, req)
+
+	payload, sig, _ := strings.Cut(token, ".")
+	flipped := []byte(payload)
+	flipped[len(flipped)/2] ^= 1
+	forged := db.signPageToken(pageToken{Kind: (&Person{}).GetKind(), Query: pageQuery(OrderByID, nil), Cursor: "zzz", Expires: time.Now().Add(time.Hour).Unix()})
+	other, _ := newTestDB(t, WithPageTokens([]byte("other key"), 0))
+	tokens := map[string]string{
+		"flipped payload":     string(flipped) + "." + sig,
+		"foreign payload":     strings.SplitN(forged, ".", 2)[0] + "." + sig,
+		"no signature":        payload,
+		"garbage":             "not a token",
+		"signed with another": other.signPageToken(pageToken{Kind: (&Person{}).GetKind(), Query: pageQuery(OrderByID, nil), Expires: time.Now().Add(time.Hour).Unix()}),
+		"truncated signature": token[:len(token)-2],
+	}
+	for name, tampered := range tokens {
+		req.Token = tampered
+		if _, err := db.ListPage(context.Background(), req); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("%s: ListPage() = %v, want %v", name, err, ErrInvalidPageToken)
+		}
+	}
+}
+
+func TestPageTokensExpire(t *testing.T) {
+	db, _ := newTestDB(t)
+	storePersons(t, db, 2)
+
+	expired := db.signPageToken(pageToken{
+		Kind:    (&Person{}).GetKind(),
+		Query:   pageQuery(OrderByID, nil),
+		Cursor:  "person:0",
+		Expires: time.Now().Add(-time.Minute).Unix(),
+	})
+	_, err := db.ListPage(context.Background(), PageRequest{Kind: "person", Limit: 1, Token: expired})
+	if !errors.Is(err, ErrInvalidPageToken) || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("ListPage() with an expired token = %v, want it expired", err)
+	}
+}
+
+func TestPageTokensBelongToOneListing(t *testing.T) {
+	db, _ := newTestDB(t)
+	storePersons(t, db, 3)
+	doe, err := ParseFilter("last_name=Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := firstPageToken(t, db, PageRequest{Kind: "person", Filters: []Filter{doe}, Limit: 1})
+
+	for name, req := range map[string]PageRequest{
+		"other kind":    {Kind: "animal", Filters: nil, Limit: 1},
+		"other filters": {Kind: "person", Limit: 1},
+		"other order":   {Kind: "person", Filters: []Filter{doe}, Order: OrderByCreatedAt, Limit: 1},
+	} {
+		req.Token = token
+		if _, err := db.ListPage(context.Background(), req); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("%s: ListPage() = %v, want %v", name, err, ErrInvalidPageToken)
+		}
+	}
+
+	// The limit may change between pages.
+	if _, err := db.ListPage(context.Background(), PageRequest{Kind: "person", Filters: []Filter{doe}, Limit: 5, Token: token}); err != nil {
+		t.Fatalf("ListPage() with another limit: %v", err)
+	}
+}
+
+func TestPageByCreatedAtResumesAmongEqualScores(t *testing.T) {
+	db, mr := newTestDB(t)
+	storePersons(t, db, 7)
+
+	// Objects created in the same microsecond share a score.
+	kind := (&Person{}).GetKind()
+	for i := 0; i < 7; i++ {
+		if _, err := mr.ZAdd(rangeIndexKey(kind, "created_at"), 1, objectKey(kind, fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, limit := range []int{1, 2, 3} {
+		ids := listAll(t, db, PageRequest{Kind: "person", Order: OrderByCreatedAt, Limit: limit})
+		if got := strings.Join(ids, ","); got != "0,1,2,3,4,5,6" {
+			t.Errorf("limit %d: listed %s, want every object once, by ID", limit, got)
+		}
+	}
+}
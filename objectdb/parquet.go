@@ -0,0 +1,219 @@
+package objectdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is how many rows ExportParquet buffers before writing
+// them out as a row group.
+const parquetRowGroupSize = 64 * 1024
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	rawJSONType       = reflect.TypeOf(json.RawMessage(nil))
+	bytesType         = reflect.TypeOf([]byte(nil))
+)
+
+// ExportParquet writes every object of kind to w as a Snappy-compressed
+// Parquet file and returns how many objects it wrote. The schema is
+// derived from the kind's struct: columns are named by JSON path, embedded
+// structs are flattened as in JSON, nested structs become groups, slices
+// lists and maps with string keys maps, while fields Parquet has no type
+// for, or with their own JSON encoding, are written as JSON, null when
+// absent. Times are timestamps in microseconds. As by ExportNDJSON,
+// encrypted fields are written decrypted. Unstructured kinds have no
+// struct to derive a schema from and cannot be exported.
+func (db *RedisObjectDB) ExportParquet(ctx context.Context, kind string, w io.Writer) (int64, error) {
+	name, ok := lookupKind(kind)
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	schema, rowType, err := parquetSchema(name)
+	if err != nil {
+		return 0, err
+	}
+
+	pw := parquet.NewWriter(w, schema,
+		parquet.Compression(&parquet.Snappy),
+		parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+	)
+	var written int64
+
+	it := db.ListObjectsStream(ctx, name)
+	for it.Next(ctx) {
+		object := it.Object()
+		if object.GetKind() != name {
+			continue
+		}
+
+		// The row type has the same JSON encoding as the kind, so a round
+		// trip through JSON copies the fields across.
+		data, err := json.Marshal(object)
+		if err != nil {
+			return written, err
+		}
+		row := reflect.New(rowType).Interface()
+		if err := json.Unmarshal(data, row); err != nil {
+			return written, fmt.Errorf("%s '%s': %w", name, object.GetID(), err)
+		}
+		if err := pw.Write(row); err != nil {
+			return written, err
+		}
+		written++
+	}
+	if err := it.Err(); err != nil {
+		return written, err
+	}
+
+	return written, pw.Close()
+}
+
+// parquetSchema derives the Parquet schema of kind, and the struct type
+// rows are written as, from the kind's struct.
+func parquetSchema(kind string) (schema *parquet.Schema, rowType reflect.Type, err error) {
+	info := kinds[kind]
+	t := reflect.TypeOf(info.newObject())
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if info.unstructured || t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s has no struct to derive a Parquet schema from", kind)
+	}
+
+	// parquet-go panics on types it cannot map.
+	defer func() {
+		if r := recover(); r != nil {
+			schema, rowType, err = nil, nil, fmt.Errorf("parquet schema of %s: %v", kind, r)
+		}
+	}()
+
+	rowType = parquetStruct(t, map[reflect.Type]bool{})
+	schema = parquet.NewSchema(shortKindName(kind), parquet.SchemaOf(reflect.New(rowType).Interface()))
+
+	return schema, rowType, nil
+}
+
+// parquetStruct returns a struct type with a field for every field t has in
+// JSON, of a type parquet-go maps to a column, tagged with its JSON name
+// for both. building holds the structs being converted, so recursive types
+// fall back to JSON.
+func parquetStruct(t reflect.Type, building map[reflect.Type]bool) reflect.Type {
+	building[t] = true
+	defer delete(building, t)
+
+	var fields []reflect.StructField
+	seen := map[string]bool{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			jsonTag, parquetTag := name, name
+			if opts == "string" || strings.HasPrefix(opts, "string,") || strings.Contains(opts, ",string") {
+				jsonTag += ",string"
+			}
+			typ, option := parquetType(f.Type, building)
+			if option != "" {
+				parquetTag += "," + option
+			}
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("F%d", len(fields)),
+				Type: typ,
+				Tag:  reflect.StructTag(fmt.Sprintf("json:%q parquet:%q", jsonTag, parquetTag)),
+			})
+		}
+	}
+	walk(t)
+
+	return reflect.StructOf(fields)
+}
+
+// parquetType returns the type a field of type t is written as, and the
+// parquet tag option it needs.
+func parquetType(t reflect.Type, building map[reflect.Type]bool) (reflect.Type, string) {
+	switch {
+	case t == timeType:
+		return timeType, "timestamp(microsecond)"
+	case t.Kind() == reflect.Pointer:
+		elem, option := parquetType(t.Elem(), building)
+		switch elem {
+		case rawJSONType:
+			return elem, option
+		case timeType:
+			// Timestamps cannot be tagged on pointers, but nil and the zero
+			// time are both written as null.
+			return elem, "optional," + option
+		}
+		return reflect.PointerTo(elem), "optional"
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return rawJSONType, "optional,json"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.TypeOf(""), ""
+	case reflect.Bool:
+		return reflect.TypeOf(false), ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.TypeOf(int64(0)), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.TypeOf(uint64(0)), ""
+	case reflect.Float32, reflect.Float64:
+		return reflect.TypeOf(float64(0)), ""
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return bytesType, ""
+		}
+		elem, _ := parquetType(t.Elem(), building)
+		if elem == rawJSONType || (elem.Kind() == reflect.Slice && elem != bytesType) {
+			return rawJSONType, "optional,json"
+		}
+		return reflect.SliceOf(elem), "list"
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return rawJSONType, "optional,json"
+		}
+		elem, _ := parquetType(t.Elem(), building)
+		if elem.Kind() == reflect.Slice && elem != bytesType {
+			return rawJSONType, "optional,json"
+		}
+		return reflect.MapOf(reflect.TypeOf(""), elem), ""
+
+	case reflect.Struct:
+		if building[t] {
+			return rawJSONType, "optional,json"
+		}
+		return parquetStruct(t, building), ""
+	}
+
+	return rawJSONType, "optional,json"
+}
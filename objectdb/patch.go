@@ -0,0 +1,99 @@
+package objectdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// ErrInvalidPatch is returned for JSON Patch documents that are malformed
+// or do not apply to the object, including patches whose "test" operations
+// fail.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// PatchOption configures ApplyPatch.
+type PatchOption func(*patchOptions)
+
+type patchOptions struct {
+	access *AccessPolicy
+}
+
+// AuthorizePatch makes ApplyPatch check policy for the object, both as it
+// is and as the patch leaves it: the principal in the context needs store
+// access to each, so a patch cannot move an object out of or into what the
+// principal may write.
+func AuthorizePatch(policy *AccessPolicy) PatchOption {
+	return func(o *patchOptions) {
+		o.access = policy
+	}
+}
+
+// ApplyPatch applies the RFC 6902 JSON Patch document patch to the object
+// of kind, by registered or short name, with id and stores the result,
+// provided nothing else wrote the object in between; a concurrent write
+// makes it apply the patch again to the new value, up to a few times.
+// Paths are those of the object's JSON, e.g. "/labels/env". A patch must
+// not change the kind or ID, and changes to the rest of the metadata
+// CreatedAt, UpdatedAt and Version are overwritten as by Store.
+func (db *RedisObjectDB) ApplyPatch(ctx context.Context, kind, id string, patch []byte, opts ...PatchOption) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "ApplyPatch")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	var options patchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	check := func(object Object) (bool, error) {
+		if err := options.access.AuthorizeObject(ctx, VerbStore, object); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return db.casUpdate(ctx, name, id, check, func(object Object) error {
+		if err := applyPatch(object, decoded); err != nil {
+			return err
+		}
+		return options.access.AuthorizeObject(ctx, VerbStore, object)
+	}, true)
+}
+
+func applyPatch(object Object, patch jsonpatch.Patch) error {
+	doc, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	doc, err = patch.Apply(doc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	// Decoding into a new object drops the fields the patch removed.
+	patched, err := NewObject(object.GetKind())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(doc, patched); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if patched.GetID() != object.GetID() {
+		return fmt.Errorf("%w: it changes the ID of %s '%s'", ErrInvalidPatch, object.GetKind(), object.GetID())
+	}
+	reflect.ValueOf(object).Elem().Set(reflect.ValueOf(patched).Elem())
+
+	return nil
+}
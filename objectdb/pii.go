@@ -0,0 +1,229 @@
+package objectdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Fields tagged `pii:"encrypt"` are stored encrypted with AES-GCM when the
+// store has a field encryption key, while the rest of the object stays in
+// the clear and queryable. Only string fields can be encrypted. Ciphertext
+// is bound to the object's kind, ID and field, so it cannot be copied into
+// another object or field and still decrypt.
+const piiPrefix = "enc:v1:"
+
+// WithFieldEncryption enables encryption of pii-tagged fields. key must be
+// 16, 24 or 32 bytes long, selecting AES-128, AES-192 or AES-256.
+func WithFieldEncryption(key []byte) Option {
+	return func(db *RedisObjectDB) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(fmt.Sprintf("field encryption key: %v", err))
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(fmt.Sprintf("field encryption key: %v", err))
+		}
+		db.piiAEAD = aead
+	}
+}
+
+// piiFieldIndexes lists the pii-tagged string fields of t, including those
+// of embedded structs.
+func piiFieldIndexes(t reflect.Type) [][]int {
+	var indexes [][]int
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Tag.Get("pii") == "encrypt" && f.Type.Kind() == reflect.String {
+			indexes = append(indexes, f.Index)
+		}
+	}
+
+	return indexes
+}
+
+// encryptFields returns a copy of object with its pii fields encrypted, or
+// object itself when there is nothing to encrypt. With namespace keys the
+// namespace's data key is used, see WithNamespaceKeys.
+func (db *RedisObjectDB) encryptFields(object Object) (Object, error) {
+	info := kinds[object.GetKind()]
+	if db.piiAEAD == nil && db.namespaceKeys == nil || info == nil || len(info.piiFields) == 0 {
+		return object, nil
+	}
+
+	clone := reflect.New(reflect.TypeOf(object).Elem())
+	clone.Elem().Set(reflect.ValueOf(object).Elem())
+
+	aead, prefix, namespace := db.piiAEAD, piiPrefix, objectNamespace(object)
+	for _, index := range info.piiFields {
+		field := clone.Elem().FieldByIndex(index)
+		if field.String() == "" || strings.HasPrefix(field.String(), piiPrefix) || strings.HasPrefix(field.String(), namespacePIIPrefix) {
+			continue
+		}
+
+		additionalData := piiAdditionalData(object.GetKind(), object, index)
+		if db.namespaceKeys != nil {
+			// Only fetched once there is a field to encrypt, so objects
+			// without pii can still be written to revoked namespaces.
+			if prefix == piiPrefix {
+				key, err := db.namespaceKey(namespace, true)
+				if err != nil {
+					return nil, err
+				}
+				aead, prefix = key, namespacePIIPrefix+base64.RawURLEncoding.EncodeToString([]byte(namespace))+":"
+			}
+			additionalData = append(additionalData, "\x00"+namespace...)
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+
+		sealed := aead.Seal(nonce, nonce, []byte(field.String()), additionalData)
+		field.SetString(prefix + base64.RawStdEncoding.EncodeToString(sealed))
+	}
+
+	return clone.Interface().(Object), nil
+}
+
+// decryptFields decrypts the pii fields of object in place. Values that are
+// not ciphertext, written before encryption was enabled, and ciphertext the
+// store has no key for are left alone; fields of namespaces whose key was
+// revoked are cleared.
+func (db *RedisObjectDB) decryptFields(object Object) error {
+	return db.decryptFieldsAs(object, object.GetKind())
+}
+
+// decryptFieldsAs is decryptFields for ciphertext bound to kind rather than
+// the object's own kind, as that of objects stored before MigrateKind.
+func (db *RedisObjectDB) decryptFieldsAs(object Object, kind string) error {
+	info := kinds[object.GetKind()]
+	if db.piiAEAD == nil && db.namespaceKeys == nil || info == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(object).Elem()
+	for _, index := range info.piiFields {
+		field := v.FieldByIndex(index)
+		value := field.String()
+		additionalData := piiAdditionalData(kind, object, index)
+
+		var aead cipher.AEAD
+		switch {
+		case strings.HasPrefix(value, piiPrefix) && db.piiAEAD != nil:
+			aead, value = db.piiAEAD, strings.TrimPrefix(value, piiPrefix)
+		case strings.HasPrefix(value, namespacePIIPrefix) && db.namespaceKeys != nil:
+			encoded, rest, ok := strings.Cut(strings.TrimPrefix(value, namespacePIIPrefix), ":")
+			namespace, err := base64.RawURLEncoding.DecodeString(encoded)
+			if !ok || err != nil {
+				return fmt.Errorf("decrypt %s '%s': malformed ciphertext", object.GetKind(), object.GetID())
+			}
+
+			aead, err = db.namespaceKey(string(namespace), false)
+			if errors.Is(err, ErrKeyRevoked) {
+				field.SetString("")
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("decrypt %s '%s': %w", object.GetKind(), object.GetID(), err)
+			}
+			value = rest
+			additionalData = append(additionalData, "\x00"+string(namespace)...)
+		default:
+			continue
+		}
+
+		sealed, err := base64.RawStdEncoding.DecodeString(value)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("decrypt %s '%s': malformed ciphertext", object.GetKind(), object.GetID())
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+		if err != nil {
+			return fmt.Errorf("decrypt %s '%s': %w", object.GetKind(), object.GetID(), err)
+		}
+		field.SetString(string(plaintext))
+	}
+
+	return nil
+}
+
+func piiAdditionalData(kind string, object Object, index []int) []byte {
+	field := reflect.TypeOf(object).Elem().FieldByIndex(index)
+	return []byte(kind + "\x00" + object.GetID() + "\x00" + field.Name)
+}
+
+// decodeSealed is decode without decrypting pii fields, for values that
+// are never archive stubs.
+func (db *RedisObjectDB) decodeSealed(kind string, data []byte) (Object, error) {
+	inner, err := db.verify(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeObject(kind, inner)
+}
+
+// decode is decodeObject plus the store-specific steps that need its
+// configuration: verifying signatures, fetching archived values and
+// decrypting pii fields. It never writes; see load for reads that store
+// archived values in Redis again.
+func (db *RedisObjectDB) decode(kind string, data []byte) (Object, error) {
+	object, _, err := db.decodeArchived(kind, data)
+	return object, err
+}
+
+// load is decode for the reads of a single object asked for by the
+// caller, GetObjectByID, GetObject and GetObjectByName, which also store
+// an archived value in Redis again, see rehydrate. Listing, updates and
+// background jobs decode instead, so that they neither write behind the
+// caller's back nor abort the WATCH of a concurrent update.
+func (db *RedisObjectDB) load(kind string, data []byte) (Object, error) {
+	object, archived, err := db.decodeArchived(kind, data)
+	if err != nil {
+		return nil, err
+	}
+	if archived != nil {
+		db.rehydrate(object, data, archived)
+	}
+
+	return object, nil
+}
+
+// decodeArchived is decode also returning the archived value fetched for
+// data, nil if it is not an archive stub.
+func (db *RedisObjectDB) decodeArchived(kind string, data []byte) (Object, []byte, error) {
+	inner, err := db.verify(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var archived []byte
+	if isArchiveStub(inner) {
+		if archived, err = db.unarchive(inner); err != nil {
+			return nil, nil, err
+		}
+		if inner, err = db.verify(archived); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	object, err := decodeObject(kind, inner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = db.decryptFields(object)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return object, archived, nil
+}
@@ -0,0 +1,192 @@
+package objectdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-assignment/objectpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrNoProtoMessage is returned for kinds that have no objectpb message.
+var ErrNoProtoMessage = errors.New("no protobuf message")
+
+// protoConverter maps one kind to and from its objectpb message.
+type protoConverter struct {
+	newMessage func() proto.Message
+	toProto    func(Object) proto.Message
+	fromProto  func(proto.Message, Object)
+}
+
+var protoConverters = map[string]protoConverter{
+	(&Person{}).GetKind(): {
+		newMessage: func() proto.Message { return &objectpb.Person{} },
+		toProto:    func(o Object) proto.Message { return PersonToProto(o.(*Person)) },
+		fromProto:  func(m proto.Message, o Object) { *o.(*Person) = *PersonFromProto(m.(*objectpb.Person)) },
+	},
+	(&Animal{}).GetKind(): {
+		newMessage: func() proto.Message { return &objectpb.Animal{} },
+		toProto:    func(o Object) proto.Message { return AnimalToProto(o.(*Animal)) },
+		fromProto:  func(m proto.Message, o Object) { *o.(*Animal) = *AnimalFromProto(m.(*objectpb.Animal)) },
+	},
+}
+
+// ProtobufCodec stores objects as their objectpb message. Unlike the other
+// codecs it only handles kinds with a converter, and cannot decode into a
+// plain map, so migrations do not apply to protobuf values.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Format() byte {
+	return FormatProtobuf
+}
+
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	conv, err := protoConverterFor(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(conv.toProto(v.(Object)))
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	conv, err := protoConverterFor(v)
+	if err != nil {
+		return err
+	}
+
+	msg := conv.newMessage()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+
+	conv.fromProto(msg, v.(Object))
+	return nil
+}
+
+func protoConverterFor(v interface{}) (protoConverter, error) {
+	object, ok := v.(Object)
+	if !ok {
+		return protoConverter{}, fmt.Errorf("protobuf codec cannot handle %T", v)
+	}
+
+	conv, ok := protoConverters[object.GetKind()]
+	if !ok {
+		return protoConverter{}, fmt.Errorf("%w for kind '%s'", ErrNoProtoMessage, object.GetKind())
+	}
+
+	return conv, nil
+}
+
+// ObjectToProto converts object to its kind's objectpb message.
+func ObjectToProto(object Object) (proto.Message, error) {
+	conv, err := protoConverterFor(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return conv.toProto(object), nil
+}
+
+// ObjectFromProto converts msg back to an object of kind.
+func ObjectFromProto(kind string, msg proto.Message) (Object, error) {
+	object, err := NewObject(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := protoConverterFor(object)
+	if err != nil {
+		return nil, err
+	}
+
+	want := conv.newMessage().ProtoReflect().Descriptor().FullName()
+	if got := msg.ProtoReflect().Descriptor().FullName(); got != want {
+		return nil, fmt.Errorf("%s is stored as %s, not %s", object.GetKind(), want, got)
+	}
+
+	conv.fromProto(msg, object)
+	return object, nil
+}
+
+func ObjectMetaToProto(m *ObjectMeta) *objectpb.ObjectMeta {
+	return &objectpb.ObjectMeta{
+		Name:          m.Name,
+		Id:            m.ID,
+		Labels:        m.Labels,
+		Annotations:   m.Annotations,
+		CreatedAt:     timeToProto(m.CreatedAt),
+		UpdatedAt:     timeToProto(m.UpdatedAt),
+		Version:       m.Version,
+		SchemaVersion: int32(m.SchemaVersion),
+		ApiVersion:    m.APIVersion,
+	}
+}
+
+func ObjectMetaFromProto(m *objectpb.ObjectMeta) ObjectMeta {
+	return ObjectMeta{
+		Name:          m.GetName(),
+		ID:            m.GetId(),
+		Labels:        m.GetLabels(),
+		Annotations:   m.GetAnnotations(),
+		CreatedAt:     timeFromProto(m.GetCreatedAt()),
+		UpdatedAt:     timeFromProto(m.GetUpdatedAt()),
+		Version:       m.GetVersion(),
+		SchemaVersion: int(m.GetSchemaVersion()),
+		APIVersion:    m.GetApiVersion(),
+	}
+}
+
+func PersonToProto(p *Person) *objectpb.Person {
+	return &objectpb.Person{
+		Meta:      ObjectMetaToProto(&p.ObjectMeta),
+		LastName:  p.LastName,
+		Birthday:  p.Birthday,
+		BirthDate: timeToProto(p.BirthDate),
+	}
+}
+
+func PersonFromProto(p *objectpb.Person) *Person {
+	return &Person{
+		ObjectMeta: ObjectMetaFromProto(p.GetMeta()),
+		LastName:   p.GetLastName(),
+		Birthday:   p.GetBirthday(),
+		BirthDate:  timeFromProto(p.GetBirthDate()),
+	}
+}
+
+func AnimalToProto(a *Animal) *objectpb.Animal {
+	return &objectpb.Animal{
+		Meta:    ObjectMetaToProto(&a.ObjectMeta),
+		Type:    a.Type,
+		OwnerId: a.OwnerID,
+	}
+}
+
+func AnimalFromProto(a *objectpb.Animal) *Animal {
+	return &Animal{
+		ObjectMeta: ObjectMetaFromProto(a.GetMeta()),
+		Type:       a.GetType(),
+		OwnerID:    a.GetOwnerId(),
+	}
+}
+
+// timeToProto keeps the zero time unset rather than encoding year 1.
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
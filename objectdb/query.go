@@ -0,0 +1,169 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FilterOp is how a Filter compares a field with its value.
+type FilterOp string
+
+const (
+	OpEq  FilterOp = "="
+	OpLt  FilterOp = "<"
+	OpLte FilterOp = "<="
+	OpGt  FilterOp = ">"
+	OpGte FilterOp = ">="
+)
+
+// Filter matches objects whose Field compares with Value as Op says; the
+// zero Op means equality. Field is a JSON or Go field path as accepted by
+// fieldValue; Value is converted to the field's type, so non-string fields
+// can be matched too and times can be given as RFC 3339 timestamps or
+// dates like 2006-01-02.
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value interface{}
+}
+
+// ParseFilter parses FIELD=VALUE, FIELD<VALUE, FIELD<=VALUE, FIELD>VALUE or
+// FIELD>=VALUE.
+func ParseFilter(s string) (Filter, error) {
+	i := strings.IndexAny(s, "<>=")
+	if i <= 0 {
+		return Filter{}, fmt.Errorf("filter '%s' is not FIELD=VALUE, FIELD<VALUE or FIELD>VALUE", s)
+	}
+
+	op := FilterOp(s[i : i+1])
+	if op != OpEq && strings.HasPrefix(s[i+1:], "=") {
+		op += "="
+	}
+	return Filter{Field: s[:i], Op: op, Value: s[i+len(op):]}, nil
+}
+
+// String formats f the way ParseFilter reads it.
+func (f Filter) String() string {
+	op := f.Op
+	if op == "" {
+		op = OpEq
+	}
+	if t, ok := f.Value.(time.Time); ok {
+		return f.Field + string(op) + t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprintf("%s%s%v", f.Field, op, f.Value)
+}
+
+func (op FilterOp) valid() bool {
+	switch op {
+	case "", OpEq, OpLt, OpLte, OpGt, OpGte:
+		return true
+	}
+
+	return false
+}
+
+// checkFilters reports the first of filters with an unknown operator or a
+// field kind does not have.
+func checkFilters(kind string, filters []Filter) error {
+	for _, f := range filters {
+		if !f.Op.valid() {
+			return fmt.Errorf("unknown filter operator '%s'", f.Op)
+		}
+		if !hasField(kind, f.Field) {
+			return fmt.Errorf("%s has no field '%s'", kind, f.Field)
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether object satisfies f, for filtering objects that
+// were fetched some other way.
+func (f Filter) Matches(object Object) bool {
+	v, ok := fieldValue(object, f.Field)
+	if !ok {
+		return false
+	}
+
+	cmp, ok := compareValues(v, f.Value)
+	if !ok {
+		return false
+	}
+
+	switch f.Op {
+	case "", OpEq:
+		return cmp == 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	}
+
+	return false
+}
+
+// Query returns the objects of kind matching all filters. An empty kind
+// queries every registered kind; objects lacking a filtered field simply
+// do not match. When kind is given and a filter constrains a field with a
+// range index, such as created_at, only the objects in range are read;
+// otherwise every object of the kind is scanned.
+func (db *RedisObjectDB) Query(ctx context.Context, kind string, filters ...Filter) ([]Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "Query")
+	defer cancel()
+
+	for _, f := range filters {
+		if !f.Op.valid() {
+			return nil, fmt.Errorf("unknown filter operator '%s'", f.Op)
+		}
+	}
+
+	var objects []Object
+	collect := func(object Object) error {
+		for _, f := range filters {
+			if !f.Matches(object) {
+				return nil
+			}
+		}
+		objects = append(objects, object)
+		return nil
+	}
+
+	pattern := "*"
+	if kind != "" {
+		for _, f := range filters {
+			if !hasField(kind, f.Field) {
+				return nil, fmt.Errorf("%s has no field '%s'", kind, f.Field)
+			}
+		}
+		pattern = fmt.Sprintf("%s:*", kind)
+
+		keys, ok, err := db.rangeCandidates(ctx, kind, filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			candidates, err := db.fetchKeys(ctx, keys)
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range candidates {
+				collect(object)
+			}
+			return objects, nil
+		}
+	}
+
+	if err := db.scanObjects(ctx, pattern, collect); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,256 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrQuotaExceeded is returned by Store when a write would take a kind, a
+// namespace or the store past its configured limits.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// usageKey counts the bytes held by object values, and usageNamespaceKey
+// those held by the values of one namespace, the NamespaceLabel of their
+// objects. usageNamespacesKey maps each object key to the namespace its
+// value is counted in, so that deletes and moves between namespaces take
+// it off the right counter. Every write through queueSet and queueDel keeps
+// them up to date; RecountUsage rebuilds them for data written before they
+// existed.
+const (
+	usageKey           = "usage:bytes"
+	usageNamespacesKey = "usage:namespaces"
+)
+
+func usageNamespaceKey(namespace string) string {
+	return fmt.Sprintf("usage:bytes:%s", namespace)
+}
+
+// Quotas limits what one store may hold. Zero means unlimited.
+type Quotas struct {
+	// MaxObjects limits the number of objects per kind name.
+	MaxObjects map[string]int64
+	// MaxNamespaceBytes limits the total size of the stored values of each
+	// namespace, so that one tenant cannot use up memory shared with the
+	// others. Entries are keyed by namespace, with "" for objects without
+	// one and "*" for every namespace without an entry of its own.
+	MaxNamespaceBytes map[string]int64
+	// MaxBytes limits the total size of stored values, across namespaces.
+	MaxBytes int64
+}
+
+// namespaceLimit returns the byte limit of namespace, 0 for none.
+func (q *Quotas) namespaceLimit(namespace string) int64 {
+	if n, ok := q.MaxNamespaceBytes[namespace]; ok {
+		return n
+	}
+
+	return q.MaxNamespaceBytes["*"]
+}
+
+// WithQuotas makes Store reject writes that would exceed q with
+// ErrQuotaExceeded. The check runs just before the write and is not part
+// of it, so concurrent writes can overshoot a limit by a few objects.
+func WithQuotas(q Quotas) Option {
+	return func(db *RedisObjectDB) {
+		maxObjects := make(map[string]int64, len(q.MaxObjects))
+		for kind, n := range q.MaxObjects {
+			if name, ok := lookupKind(kind); ok {
+				kind = name
+			}
+			maxObjects[kind] = n
+		}
+		q.MaxObjects = maxObjects
+		db.quotas = &q
+	}
+}
+
+// Setting and deleting values goes through these scripts so the usage
+// counters move in the same MULTI as the value itself. The counter of the
+// namespace a value was counted in is only known inside the script, which
+// builds its key from the usage:bytes: prefix in ARGV. They are sent with
+// EVAL rather than EVALSHA, as a NOSCRIPT error inside MULTI cannot be
+// retried.
+const (
+	setValueScript = `
+local old = redis.call("STRLEN", KEYS[1])
+local namespace = redis.call("HGET", KEYS[3], KEYS[1])
+redis.call("SET", KEYS[1], ARGV[1])
+if namespace then
+	redis.call("DECRBY", ARGV[3] .. namespace, old)
+end
+redis.call("INCRBY", KEYS[4], string.len(ARGV[1]))
+redis.call("HSET", KEYS[3], KEYS[1], ARGV[2])
+return redis.call("INCRBY", KEYS[2], string.len(ARGV[1]) - old)`
+	delValueScript = `
+local old = redis.call("STRLEN", KEYS[1])
+local namespace = redis.call("HGET", KEYS[3], KEYS[1])
+redis.call("DEL", KEYS[1])
+if namespace then
+	redis.call("DECRBY", ARGV[1] .. namespace, old)
+	redis.call("HDEL", KEYS[3], KEYS[1])
+end
+return redis.call("DECRBY", KEYS[2], old)`
+)
+
+// queueSet queues storing value at key, the value of an object in
+// namespace, on pipe, updating the usage counters.
+func queueSet(ctx context.Context, pipe redis.Pipeliner, key, namespace string, value []byte) {
+	pipe.Eval(ctx, setValueScript, []string{key, usageKey, usageNamespacesKey, usageNamespaceKey(namespace)},
+		value, namespace, usageNamespaceKey(""))
+}
+
+// queueDel queues deleting key on pipe, updating the usage counters.
+func queueDel(ctx context.Context, pipe redis.Pipeliner, key string) {
+	pipe.Eval(ctx, delValueScript, []string{key, usageKey, usageNamespacesKey}, usageNamespaceKey(""))
+}
+
+// checkQuotas fails with ErrQuotaExceeded if writing value for object
+// would break a limit. previous is the version being replaced, if any.
+func (db *RedisObjectDB) checkQuotas(ctx context.Context, object, previous Object, value []byte) error {
+	if db.quotas == nil {
+		return nil
+	}
+	kind := object.GetKind()
+	key := objectKey(kind, object.GetID())
+	namespace := objectNamespace(object)
+
+	pipe := db.redisClient.Pipeline()
+	count := pipe.SCard(ctx, kindIndexKey(kind))
+	oldSize := pipe.StrLen(ctx, key)
+	oldNamespace := pipe.HGet(ctx, usageNamespacesKey, key)
+	usage := pipe.Get(ctx, usageKey)
+	namespaceUsage := pipe.Get(ctx, usageNamespaceKey(namespace))
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	maxObjects := db.quotas.MaxObjects[kind]
+	if previous == nil && maxObjects > 0 && count.Val() >= maxObjects {
+		return fmt.Errorf("%s already has %d objects, the most allowed: %w", kind, count.Val(), ErrQuotaExceeded)
+	}
+
+	used, _ := usage.Int64()
+	grown := used + int64(len(value)) - oldSize.Val()
+	if db.quotas.MaxBytes > 0 && grown > db.quotas.MaxBytes && grown > used {
+		return fmt.Errorf("storing %s '%s' would use %d of %d bytes: %w",
+			kind, object.GetID(), grown, db.quotas.MaxBytes, ErrQuotaExceeded)
+	}
+
+	// A value moving in from another namespace brings all of its size.
+	limit := db.quotas.namespaceLimit(namespace)
+	used, _ = namespaceUsage.Int64()
+	grown = used + int64(len(value))
+	if oldNamespace.Err() == nil && oldNamespace.Val() == namespace {
+		grown -= oldSize.Val()
+	}
+	if limit > 0 && grown > limit && grown > used {
+		return fmt.Errorf("storing %s '%s' would use %d of the %d bytes of namespace '%s': %w",
+			kind, object.GetID(), grown, limit, namespace, ErrQuotaExceeded)
+	}
+
+	return nil
+}
+
+// Usage returns the bytes held by stored values, as counted by writes.
+func (db *RedisObjectDB) Usage(ctx context.Context) (int64, error) {
+	used, err := db.redisClient.Get(ctx, usageKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return used, err
+}
+
+// NamespaceUsage returns the bytes held by the stored values of namespace,
+// "" for objects without one, as counted by writes.
+func (db *RedisObjectDB) NamespaceUsage(ctx context.Context, namespace string) (int64, error) {
+	used, err := db.redisClient.Get(ctx, usageNamespaceKey(namespace)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return used, err
+}
+
+// RecountUsage scans every stored object, sums the size of their values
+// and resets the usage counters to it, the store's and each namespace's.
+// Run it once when enabling quotas on existing data; writes made during
+// the scan may be counted wrongly. Archived objects are counted in the
+// namespace they were counted in before, if any.
+func (db *RedisObjectDB) RecountUsage(ctx context.Context) (int64, error) {
+	var total int64
+	namespaces := map[string]interface{}{}
+	perNamespace := map[string]int64{}
+	it := db.redisClient.Scan(ctx, 0, "*", db.scanCount).Iterator()
+	var keys []string
+	sum := func() error {
+		pipe := db.redisClient.Pipeline()
+		values := pipe.MGet(ctx, keys...)
+		counted := pipe.HMGet(ctx, usageNamespacesKey, keys...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			data, ok := values.Val()[i].(string)
+			if !ok {
+				continue
+			}
+			kind, _, _ := parseObjectKey(key)
+			namespace, _ := counted.Val()[i].(string)
+			if object, err := db.decodeSealed(kind, []byte(data)); err == nil {
+				namespace = objectNamespace(object)
+			}
+			total += int64(len(data))
+			perNamespace[namespace] += int64(len(data))
+			namespaces[key] = namespace
+		}
+		keys = keys[:0]
+		return nil
+	}
+
+	for it.Next(ctx) {
+		if _, _, ok := parseObjectKey(it.Val()); !ok {
+			continue
+		}
+		keys = append(keys, it.Val())
+		if len(keys) >= db.fetchBatchSize {
+			if err := sum(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+	if len(keys) > 0 {
+		if err := sum(); err != nil {
+			return 0, err
+		}
+	}
+
+	var stale []string
+	counters := db.redisClient.Scan(ctx, 0, usageNamespaceKey("*"), db.scanCount).Iterator()
+	for counters.Next(ctx) {
+		stale = append(stale, counters.Val())
+	}
+	if err := counters.Err(); err != nil {
+		return 0, err
+	}
+	_, err := db.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, append(stale, usageNamespacesKey)...)
+		if len(namespaces) > 0 {
+			pipe.HSet(ctx, usageNamespacesKey, namespaces)
+		}
+		for namespace, used := range perNamespace {
+			pipe.Set(ctx, usageNamespaceKey(namespace), used, 0)
+		}
+		pipe.Set(ctx, usageKey, total, 0)
+		return nil
+	})
+
+	return total, err
+}
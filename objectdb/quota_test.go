@@ -0,0 +1,130 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func namespacedPerson(id, namespace, lastName string) *Person {
+	return &Person{
+		ObjectMeta: ObjectMeta{ID: id, Name: "Person " + id, Labels: map[string]string{NamespaceLabel: namespace}},
+		LastName:   lastName,
+	}
+}
+
+func namespaceUsage(t *testing.T, db *RedisObjectDB, namespace string) int64 {
+	t.Helper()
+
+	used, err := db.NamespaceUsage(context.Background(), namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return used
+}
+
+func TestNamespaceQuotaLimitsOneNamespace(t *testing.T) {
+	db, _ := newTestDB(t, WithQuotas(Quotas{MaxNamespaceBytes: map[string]int64{"*": 400}}))
+	ctx := context.Background()
+
+	if err := db.Store(ctx, namespacedPerson("1", "a", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+	big := namespacedPerson("2", "a", string(make([]byte, 400)))
+	if err := db.Store(ctx, big); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Store past the namespace limit = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	// Another namespace has a limit of its own.
+	if err := db.Store(ctx, namespacedPerson("3", "b", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+	total, err := db.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := namespaceUsage(t, db, "a"), namespaceUsage(t, db, "b")
+	if a == 0 || b == 0 || a+b != total {
+		t.Fatalf("usage of a = %d, of b = %d, want non-zero and summing to %d", a, b, total)
+	}
+}
+
+func TestNamespaceUsageFollowsMovesAndDeletes(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	if err := db.Store(ctx, namespacedPerson("1", "a", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+	if namespaceUsage(t, db, "a") == 0 {
+		t.Fatal("storing did not count the value in its namespace")
+	}
+
+	if err := db.Store(ctx, namespacedPerson("1", "b", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+	total, err := db.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := namespaceUsage(t, db, "a"), namespaceUsage(t, db, "b"); a != 0 || b != total {
+		t.Fatalf("after the move, usage of a = %d, of b = %d, want 0 and %d", a, b, total)
+	}
+
+	if err := db.DeleteObject(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if b := namespaceUsage(t, db, "b"); b != 0 {
+		t.Fatalf("after the delete, usage of b = %d, want 0", b)
+	}
+	if used, err := db.Usage(ctx); err != nil || used != 0 {
+		t.Fatalf("Usage() = %d, %v, want 0", used, err)
+	}
+}
+
+func TestRecountUsageRebuildsNamespaceCounters(t *testing.T) {
+	db, mr := newTestDB(t)
+	ctx := context.Background()
+
+	for _, p := range []*Person{namespacedPerson("1", "a", "Doe"), namespacedPerson("2", "a", "Roe"), namespacedPerson("3", "", "Poe")} {
+		if err := db.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, none := namespaceUsage(t, db, "a"), namespaceUsage(t, db, "")
+	total, err := db.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mr.Del(usageKey)
+	mr.Del(usageNamespacesKey)
+	mr.Del(usageNamespaceKey("a"))
+	mr.Set(usageNamespaceKey("gone"), "42")
+
+	recounted, err := db.RecountUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recounted != total {
+		t.Fatalf("RecountUsage() = %d, want %d", recounted, total)
+	}
+	if got := namespaceUsage(t, db, "a"); got != a {
+		t.Fatalf("usage of a = %d, want %d", got, a)
+	}
+	if got := namespaceUsage(t, db, ""); got != none {
+		t.Fatalf("usage without a namespace = %d, want %d", got, none)
+	}
+	if got := namespaceUsage(t, db, "gone"); got != 0 {
+		t.Fatalf("usage of an emptied namespace = %d, want 0", got)
+	}
+
+	// Deletes after the recount take values off the rebuilt counters.
+	if err := db.DeleteObject(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := namespaceUsage(t, db, "a"); got >= a {
+		t.Fatalf("usage of a after a delete = %d, want less than %d", got, a)
+	}
+}
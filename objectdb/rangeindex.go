@@ -0,0 +1,169 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Range indexes are sorted sets of object keys scored by a time or numeric
+// field, so Query can answer range filters with ZRANGEBYSCORE instead of a
+// scan. Times are scored in microseconds since the epoch, which float64
+// holds exactly for any date people are likely to store. Objects written
+// before an index was registered are missing from it until RebuildIndexes
+// runs for their kind.
+
+// indexedField is a field of a kind with a range or text index.
+type indexedField struct {
+	name string
+	fieldInfo
+}
+
+// This runs after the generated registerKind calls in objects_gen.go, as
+// init functions run in file name order.
+func init() {
+	RegisterRangeIndex((&Person{}).GetKind(), "birth_date")
+}
+
+// RegisterRangeIndex maintains a sorted-set index over field of kind, which
+// must be a time.Time or a number. Register indexes before opening a store;
+// registering one twice is a no-op.
+func RegisterRangeIndex(kind, field string) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	f, ok := info.fields[field]
+	if !ok {
+		return fmt.Errorf("%s has no field '%s'", kind, field)
+	}
+	if !rangeable(f.typ) {
+		return fmt.Errorf("field '%s' of %s is neither a time nor a number", field, kind)
+	}
+
+	if _, ok := info.rangeIndexOn(f); ok {
+		return nil
+	}
+	info.rangeFields = append(info.rangeFields, indexedField{name: field, fieldInfo: f})
+	return nil
+}
+
+func rangeable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// rangeIndexOn returns the range index over f, whichever of its paths it
+// was registered under.
+func (info *kindInfo) rangeIndexOn(f fieldInfo) (indexedField, bool) {
+	for _, r := range info.rangeFields {
+		if reflect.DeepEqual(r.index, f.index) {
+			return r, true
+		}
+	}
+
+	return indexedField{}, false
+}
+
+func rangeIndexKey(kind, field string) string {
+	return fmt.Sprintf("idx:range:%s:%s", kind, field)
+}
+
+// rangeScore encodes a field value, or a filter value converted to the
+// field's type, as a sorted-set score.
+func rangeScore(typ reflect.Type, v interface{}) (float64, bool) {
+	if typ == timeType {
+		t, ok := toTime(v)
+		return float64(t.UnixMicro()), ok
+	}
+
+	return toFloat(v)
+}
+
+func rangeIndexEntries(object Object) []indexEntry {
+	info, ok := kinds[object.GetKind()]
+	if !ok || len(info.rangeFields) == 0 {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+	member := objectKey(object.GetKind(), object.GetID())
+
+	var entries []indexEntry
+	for _, r := range info.rangeFields {
+		fv, err := v.FieldByIndexErr(r.index)
+		if err != nil {
+			continue
+		}
+		score, ok := rangeScore(r.typ, fv.Interface())
+		if !ok {
+			continue
+		}
+		entries = append(entries, indexEntry{
+			key:    rangeIndexKey(object.GetKind(), r.name),
+			member: member,
+			sorted: true,
+			score:  score,
+		})
+	}
+
+	return entries
+}
+
+// rangeCandidates looks up the keys of objects of kind that may match
+// filters in the first range index some of them constrain. The bounds are
+// inclusive and times are truncated to microseconds, so the candidates
+// are a superset of the matches and still have to be filtered. ok is false
+// if no range index applies.
+func (db *RedisObjectDB) rangeCandidates(ctx context.Context, kind string, filters []Filter) (keys []string, ok bool, err error) {
+	info := kinds[kind]
+	for _, r := range info.rangeFields {
+		by := redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+		var lo, hi *float64
+		for _, f := range filters {
+			fi, found := info.fields[f.Field]
+			if !found {
+				continue
+			}
+			if on, _ := info.rangeIndexOn(fi); on.name != r.name {
+				continue
+			}
+			score, valid := rangeScore(r.typ, f.Value)
+			if !valid {
+				continue
+			}
+			if f.Op != OpLt && f.Op != OpLte && (lo == nil || score > *lo) {
+				lo = &score
+			}
+			if f.Op != OpGt && f.Op != OpGte && (hi == nil || score < *hi) {
+				hi = &score
+			}
+		}
+		if lo == nil && hi == nil {
+			continue
+		}
+		if lo != nil {
+			by.Min = strconv.FormatFloat(*lo, 'g', -1, 64)
+		}
+		if hi != nil {
+			by.Max = strconv.FormatFloat(*hi, 'g', -1, 64)
+		}
+
+		keys, err := db.redisClient.ZRangeByScore(ctx, rangeIndexKey(kind, r.name), &by).Result()
+		return keys, true, err
+	}
+
+	return nil, false, nil
+}
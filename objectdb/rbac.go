@@ -0,0 +1,304 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is returned for operations the caller's principal is not
+// granted, see AccessPolicy.
+var ErrForbidden = errors.New("forbidden")
+
+// Verb is an operation an AccessRule grants.
+type Verb string
+
+const (
+	VerbGet    Verb = "get"
+	VerbList   Verb = "list"
+	VerbStore  Verb = "store"
+	VerbDelete Verb = "delete"
+)
+
+// NamespaceLabel is the label that puts an object in a namespace. Objects
+// without it are in the empty namespace.
+const NamespaceLabel = "namespace"
+
+// AllNamespaces, in a rule, matches every namespace; passed to Authorize,
+// it asks for access to all of them at once, as counting or watching a
+// kind needs.
+const AllNamespaces = "*"
+
+// Principal is who an operation runs for. Servers put it in the request
+// context, see ContextWithPrincipal.
+type Principal struct {
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying p.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal ctx carries, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// AccessRule grants Verbs on Kinds in Namespaces to Subjects. Subjects are
+// principal names, or groups written as "group:admins". Kinds take
+// registered or short names. In every list "*" matches anything.
+type AccessRule struct {
+	Subjects   []string `json:"subjects"`
+	Verbs      []Verb   `json:"verbs"`
+	Kinds      []string `json:"kinds"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// AccessPolicy is a set of rules, each granting access on its own; what no
+// rule grants is forbidden, including every operation without a principal.
+// A nil policy allows everything.
+type AccessPolicy struct {
+	Rules []AccessRule `json:"rules"`
+}
+
+// Authorize returns nil if the principal in ctx may apply verb to objects
+// of kind in namespace, and an error wrapping ErrForbidden otherwise.
+func (p *AccessPolicy) Authorize(ctx context.Context, verb Verb, kind, namespace string) error {
+	if p == nil {
+		return nil
+	}
+	if name, ok := lookupKind(kind); ok {
+		kind = name
+	}
+
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: no principal to %s %s", ErrForbidden, verb, kind)
+	}
+	for _, rule := range p.Rules {
+		if rule.grants(principal, verb, kind, namespace) {
+			return nil
+		}
+	}
+
+	if namespace == AllNamespaces {
+		return fmt.Errorf("%w: %s may not %s %s in all namespaces", ErrForbidden, principal.Name, verb, kind)
+	}
+	return fmt.Errorf("%w: %s may not %s %s in namespace '%s'", ErrForbidden, principal.Name, verb, kind, namespace)
+}
+
+// AuthorizeObject is Authorize for object's kind and namespace.
+func (p *AccessPolicy) AuthorizeObject(ctx context.Context, verb Verb, object Object) error {
+	return p.Authorize(ctx, verb, object.GetKind(), objectNamespace(object))
+}
+
+// FilterObjects returns the objects the principal in ctx may apply verb to.
+func (p *AccessPolicy) FilterObjects(ctx context.Context, verb Verb, objects []Object) []Object {
+	if p == nil {
+		return objects
+	}
+
+	var allowed []Object
+	for _, object := range objects {
+		if p.AuthorizeObject(ctx, verb, object) == nil {
+			allowed = append(allowed, object)
+		}
+	}
+
+	return allowed
+}
+
+func (r AccessRule) grants(principal Principal, verb Verb, kind, namespace string) bool {
+	subjects := append([]string{principal.Name}, principal.Groups...)
+	for i := 1; i < len(subjects); i++ {
+		subjects[i] = "group:" + subjects[i]
+	}
+	if !matchesAny(r.Subjects, subjects...) || !matchesAny(verbNames(r.Verbs), string(verb)) {
+		return false
+	}
+
+	kindMatched := false
+	for _, k := range r.Kinds {
+		if name, ok := lookupKind(k); k == "*" || ok && name == kind {
+			kindMatched = true
+		}
+	}
+	if !kindMatched {
+		return false
+	}
+
+	// A request for all namespaces needs a rule for all of them.
+	if namespace == AllNamespaces {
+		return matchesAny(r.Namespaces)
+	}
+	return matchesAny(r.Namespaces, namespace)
+}
+
+// matchesAny reports whether patterns hold "*" or one of values.
+func matchesAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		for _, v := range values {
+			if pattern == v {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func verbNames(verbs []Verb) []string {
+	names := make([]string, len(verbs))
+	for i, v := range verbs {
+		names[i] = string(v)
+	}
+
+	return names
+}
+
+func objectNamespace(object Object) string {
+	return object.GetObjectMeta().Labels[NamespaceLabel]
+}
+
+type authorizeDependents struct {
+	policy *AccessPolicy
+}
+
+func (a authorizeDependents) applyDelete(o *deleteOptions) {
+	o.access = a.policy
+}
+
+// AuthorizeDependents makes DeleteObject check policy for the objects its
+// delete policy changes, before changing any: the principal in the context
+// needs delete access to every object DeleteCascade deletes and store
+// access to every object DeleteOrphan clears a reference in.
+func AuthorizeDependents(policy *AccessPolicy) DeleteOption {
+	return authorizeDependents{policy: policy}
+}
+
+// authorizeDependents walks the dependents of object as handleDependents
+// would and checks options.access for each.
+func (db *RedisObjectDB) authorizeDependents(ctx context.Context, object Object, options deleteOptions, seen map[string]bool) error {
+	if options.policy != DeleteCascade && options.policy != DeleteOrphan {
+		return nil
+	}
+	seen[objectKey(object.GetKind(), object.GetID())] = true
+
+	dependents, err := db.dependents(ctx, object.GetKind(), object.GetID())
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range dependents {
+		if seen[objectKey(dep.object.GetKind(), dep.object.GetID())] {
+			continue
+		}
+
+		if options.policy == DeleteOrphan {
+			err = options.access.AuthorizeObject(ctx, VerbStore, dep.object)
+		} else if err = options.access.AuthorizeObject(ctx, VerbDelete, dep.object); err == nil {
+			err = db.authorizeDependents(ctx, dep.object, options, seen)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AuthorizingObjectDB checks every call against an AccessPolicy before
+// passing it on, using the principal in the call's context, including the
+// objects a delete cascades to or orphans, see AuthorizeDependents.
+type AuthorizingObjectDB struct {
+	db     ObjectDB
+	policy *AccessPolicy
+}
+
+func NewAuthorizingObjectDB(db ObjectDB, policy *AccessPolicy) *AuthorizingObjectDB {
+	return &AuthorizingObjectDB{db: db, policy: policy}
+}
+
+// Store needs store access to the object's namespace and, when it replaces
+// an object in another namespace, to that one too.
+func (db *AuthorizingObjectDB) Store(ctx context.Context, object Object, opts ...StoreOption) error {
+	if err := db.policy.AuthorizeObject(ctx, VerbStore, object); err != nil {
+		return err
+	}
+
+	previous, err := db.db.GetObjectByID(ctx, object.GetID())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if previous != nil && previous.GetKind() == object.GetKind() {
+		if err := db.policy.AuthorizeObject(ctx, VerbStore, previous); err != nil {
+			return err
+		}
+	}
+
+	return db.db.Store(ctx, object, opts...)
+}
+
+// GetObjectByID fails with ErrNotFound, as if there were no such object,
+// for objects the caller may not get, so that IDs in namespaces it cannot
+// see are not told apart from IDs that are free.
+func (db *AuthorizingObjectDB) GetObjectByID(ctx context.Context, id string) (Object, error) {
+	object, err := db.db.GetObjectByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if db.policy.AuthorizeObject(ctx, VerbGet, object) != nil {
+		return nil, fmt.Errorf("object with ID '%s' %w", id, ErrNotFound)
+	}
+
+	return object, nil
+}
+
+// GetObjectByName, like GetObjectByID, fails with ErrNotFound for objects
+// the caller may not get.
+func (db *AuthorizingObjectDB) GetObjectByName(ctx context.Context, name string) (Object, error) {
+	object, err := db.db.GetObjectByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if db.policy.AuthorizeObject(ctx, VerbGet, object) != nil {
+		return nil, fmt.Errorf("object with name '%s' %w", name, ErrNotFound)
+	}
+
+	return object, nil
+}
+
+// ListObjects returns the objects of kind in the namespaces the caller may
+// list.
+func (db *AuthorizingObjectDB) ListObjects(ctx context.Context, kind string) ([]Object, error) {
+	objects, err := db.db.ListObjects(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.policy.FilterObjects(ctx, VerbList, objects), nil
+}
+
+func (db *AuthorizingObjectDB) DeleteObject(ctx context.Context, id string, opts ...DeleteOption) error {
+	object, err := db.db.GetObjectByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := db.policy.AuthorizeObject(ctx, VerbDelete, object); err != nil {
+		return err
+	}
+
+	return db.db.DeleteObject(ctx, id, append(opts, AuthorizeDependents(db.policy))...)
+}
+
+func (db *AuthorizingObjectDB) Close() error {
+	return db.db.Close()
+}
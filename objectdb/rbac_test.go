@@ -0,0 +1,127 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// teamPolicy lets "ann" get and list persons in namespace "a" only.
+var teamPolicy = &AccessPolicy{Rules: []AccessRule{
+	{Subjects: []string{"ann"}, Verbs: []Verb{VerbGet, VerbList}, Kinds: []string{"person"}, Namespaces: []string{"a"}},
+}}
+
+func TestAuthorizingGetHidesForbiddenObjects(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+	if err := db.Store(ctx, namespacedPerson("1", "b", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+
+	authz := NewAuthorizingObjectDB(db, teamPolicy)
+	ctx = ContextWithPrincipal(ctx, Principal{Name: "ann"})
+
+	_, forbidden := authz.GetObjectByID(ctx, "1")
+	_, missing := authz.GetObjectByID(ctx, "2")
+	if !errors.Is(forbidden, ErrNotFound) || errors.Is(forbidden, ErrForbidden) {
+		t.Fatalf("GetObjectByID of a forbidden object = %v, want %v", forbidden, ErrNotFound)
+	}
+	if forbidden.Error() != "object with ID '1' not found" || missing.Error() != "object with ID '2' not found" {
+		t.Fatalf("forbidden and missing objects read differently: %v, %v", forbidden, missing)
+	}
+
+	if _, err := authz.GetObjectByName(ctx, "Person 1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetObjectByName of a forbidden object = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestAccessPolicyAuthorize(t *testing.T) {
+	policy := &AccessPolicy{Rules: []AccessRule{
+		{Subjects: []string{"ann"}, Verbs: []Verb{VerbGet, VerbList}, Kinds: []string{"person"}, Namespaces: []string{"a"}},
+		{Subjects: []string{"group:admins"}, Verbs: []Verb{"*"}, Kinds: []string{"*"}, Namespaces: []string{"*"}},
+	}}
+	ann := Principal{Name: "ann"}
+	bob := Principal{Name: "bob", Groups: []string{"admins"}}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		verb      Verb
+		kind      string
+		namespace string
+		allowed   bool
+	}{
+		{"granted verb", &ann, VerbGet, "person", "a", true},
+		{"registered kind name", &ann, VerbList, (&Person{}).GetKind(), "a", true},
+		{"other verb", &ann, VerbStore, "person", "a", false},
+		{"other kind", &ann, VerbGet, "animal", "a", false},
+		{"other namespace", &ann, VerbGet, "person", "b", false},
+		{"all namespaces from one", &ann, VerbList, "person", AllNamespaces, false},
+		{"group", &bob, VerbDelete, "animal", "b", true},
+		{"group in all namespaces", &bob, VerbList, "person", AllNamespaces, true},
+		{"no principal", nil, VerbGet, "person", "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = ContextWithPrincipal(ctx, *tt.principal)
+			}
+			err := policy.Authorize(ctx, tt.verb, tt.kind, tt.namespace)
+			if tt.allowed && err != nil {
+				t.Fatalf("Authorize() = %v, want allowed", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Fatalf("Authorize() = %v, want %v", err, ErrForbidden)
+			}
+		})
+	}
+
+	var none *AccessPolicy
+	if err := none.Authorize(context.Background(), VerbDelete, "person", "a"); err != nil {
+		t.Fatalf("nil policy Authorize() = %v, want allowed", err)
+	}
+}
+
+func TestAuthorizingObjectDBChecksWrites(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+	policy := &AccessPolicy{Rules: []AccessRule{
+		{Subjects: []string{"ann"}, Verbs: []Verb{"*"}, Kinds: []string{"person"}, Namespaces: []string{"a"}},
+	}}
+	authz := NewAuthorizingObjectDB(db, policy)
+	ann := ContextWithPrincipal(ctx, Principal{Name: "ann"})
+
+	if err := authz.Store(ann, namespacedPerson("1", "a", "Doe")); err != nil {
+		t.Fatalf("Store in a granted namespace: %v", err)
+	}
+	if err := authz.Store(ann, namespacedPerson("2", "b", "Doe")); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Store in another namespace = %v, want %v", err, ErrForbidden)
+	}
+	if err := authz.Store(ctx, namespacedPerson("3", "a", "Doe")); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Store without a principal = %v, want %v", err, ErrForbidden)
+	}
+
+	// Moving an object out of a namespace needs access to that one too.
+	if err := db.Store(ctx, namespacedPerson("4", "b", "Doe")); err != nil {
+		t.Fatal(err)
+	}
+	if err := authz.Store(ann, namespacedPerson("4", "a", "Doe")); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Store moving an object from another namespace = %v, want %v", err, ErrForbidden)
+	}
+
+	objects, err := authz.ListObjects(ann, (&Person{}).GetKind())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].GetID() != "1" {
+		t.Fatalf("ListObjects() = %v, want only the object in namespace a", objects)
+	}
+
+	if err := authz.DeleteObject(ann, "4"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("DeleteObject in another namespace = %v, want %v", err, ErrForbidden)
+	}
+	if err := authz.DeleteObject(ann, "1"); err != nil {
+		t.Fatalf("DeleteObject in a granted namespace: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RebuildReport describes what RebuildIndexes changed.
+type RebuildReport struct {
+	// Objects is the number of objects of the kind that were indexed.
+	Objects int64 `json:"objects"`
+	// Added entries were missing; Removed ones were stale.
+	Added   int64 `json:"added"`
+	Removed int64 `json:"removed"`
+	// Skipped entries were written to during the rebuild and left as
+	// they are.
+	Skipped int64 `json:"skipped"`
+}
+
+// RebuildIndexes regenerates every index entry for the objects of kind
+// from the objects themselves: first each object's entries are added
+// where missing, then entries of the kind that no object accounts for are
+// removed, so queries keep working while it runs. Use it after index
+// corruption or to index existing data after new indexes are introduced;
+// once it has run for a kind, GetObjectByName no longer scans its objects
+// for names missing from the index.
+func (db *RedisObjectDB) RebuildIndexes(ctx context.Context, kind string) (RebuildReport, error) {
+	var report RebuildReport
+	name, ok := lookupKind(kind)
+	if !ok {
+		return report, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+
+	it := db.ListObjectsStream(ctx, kind)
+	var batch []Object
+	add := func() error {
+		pipe := db.redisClient.Pipeline()
+		var cmds []*redis.IntCmd
+		for _, object := range batch {
+			for _, entry := range objectIndexEntries(object) {
+				cmds = append(cmds, queueIndexAdd(ctx, pipe, entry))
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			report.Added += cmd.Val()
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for it.Next(ctx) {
+		if it.Object().GetKind() != kind {
+			continue
+		}
+		report.Objects++
+		batch = append(batch, it.Object())
+		if len(batch) >= db.fetchBatchSize {
+			if err := add(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return report, err
+	}
+	if err := add(); err != nil {
+		return report, err
+	}
+
+	var gc GCReport
+	ownMember := func(member string) bool {
+		k, _, ok := parseObjectKey(member)
+		return ok && k == kind
+	}
+	for _, index := range []string{kindIndexKey(kind), idIndexKey(kind), geoIndexKey(kind), nameIndexKey(kind)} {
+		if err := db.gcIndex(ctx, index, nil, &gc); err != nil {
+			return report, err
+		}
+	}
+	prefixes := []string{
+		fmt.Sprintf("idx:label:%s:", kind),
+		labelKeyIndexKey(kind, ""),
+		rangeIndexKey(kind, ""),
+		textIndexKey(kind, ""),
+		trigramIndexKey(kind, ""),
+		groupIndexKey(kind, ""),
+		"idx:ref:",
+	}
+	for _, prefix := range prefixes {
+		keep := ownMember
+		if prefix != "idx:ref:" {
+			keep = nil
+		}
+		keys := db.redisClient.Scan(ctx, 0, prefix+"*", db.scanCount).Iterator()
+		for keys.Next(ctx) {
+			// Kind names may contain glob characters, so matches are
+			// confirmed by prefix.
+			if !strings.HasPrefix(keys.Val(), prefix) {
+				continue
+			}
+			if err := db.gcIndex(ctx, keys.Val(), keep, &gc); err != nil {
+				return report, err
+			}
+		}
+		if err := keys.Err(); err != nil {
+			return report, err
+		}
+	}
+	report.Removed, report.Skipped = gc.Removed, gc.Skipped
+
+	// Every object of kind is in its name index now, and writes keep it
+	// so: GetObjectByName need not scan for it any more.
+	if err := db.redisClient.SAdd(ctx, nameIndexedKey, kind).Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,143 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// reference is the value of one string field tagged `ref:"<kind>"`.
+type reference struct {
+	Field string
+	Tag   string
+	Kind  string
+	ID    string
+}
+
+// objectReferences lists the non-empty references held by object. Tags
+// naming a kind that is not registered are reported with an empty Kind.
+func objectReferences(object Object) []reference {
+	v := reflect.Indirect(reflect.ValueOf(object))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var refs []reference
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("ref")
+		if !ok || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		id := v.Field(i).String()
+		if id == "" {
+			continue
+		}
+
+		kind, _ := lookupKind(tag)
+		refs = append(refs, reference{Field: field.Name, Tag: tag, Kind: kind, ID: id})
+	}
+
+	return refs
+}
+
+// ErrBrokenReference is returned by Store, when reference validation is on,
+// for objects referencing a target that does not exist.
+var ErrBrokenReference = errors.New("broken reference")
+
+func (db *RedisObjectDB) checkReferences(ctx context.Context, object Object, pending map[string]bool) error {
+	for _, ref := range objectReferences(object) {
+		if ref.Kind == "" {
+			return fmt.Errorf("field '%s' of %s references an unknown kind: %w", ref.Field, object.GetKind(), ErrBrokenReference)
+		}
+		if ref.Kind == object.GetKind() && ref.ID == object.GetID() {
+			continue
+		}
+		if pending[objectKey(ref.Kind, ref.ID)] {
+			continue
+		}
+
+		n, err := db.redisClient.Exists(ctx, objectKey(ref.Kind, ref.ID)).Result()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("field '%s' of %s '%s' points at missing %s '%s': %w",
+				ref.Field, object.GetKind(), object.GetID(), ref.Kind, ref.ID, ErrBrokenReference)
+		}
+	}
+
+	return nil
+}
+
+// ResolveReference loads the object that the `ref`-tagged field of object
+// points at. field is the Go field name, e.g. "OwnerID".
+func (db *RedisObjectDB) ResolveReference(ctx context.Context, object Object, field string) (Object, error) {
+	structField, ok := reflect.Indirect(reflect.ValueOf(object)).Type().FieldByName(field)
+	if !ok {
+		return nil, fmt.Errorf("%s has no field '%s'", object.GetKind(), field)
+	}
+
+	tag, ok := structField.Tag.Lookup("ref")
+	if !ok {
+		return nil, fmt.Errorf("field '%s' of %s is not a reference", field, object.GetKind())
+	}
+
+	kind, ok := lookupKind(tag)
+	if !ok {
+		return nil, fmt.Errorf("field '%s' of %s references unknown kind '%s'", field, object.GetKind(), tag)
+	}
+
+	id := reflect.Indirect(reflect.ValueOf(object)).FieldByName(field).String()
+	if id == "" {
+		return nil, fmt.Errorf("field '%s' of %s is empty", field, object.GetKind())
+	}
+
+	return db.getObject(ctx, kind, id)
+}
+
+func (db *RedisObjectDB) GetOwner(ctx context.Context, animal *Animal) (*Person, error) {
+	object, err := db.ResolveReference(ctx, animal, "OwnerID")
+	if err != nil {
+		return nil, err
+	}
+
+	owner, ok := object.(*Person)
+	if !ok {
+		return nil, fmt.Errorf("owner of animal '%s' is a %s", animal.ID, object.GetKind())
+	}
+
+	return owner, nil
+}
+
+// GetObject reads a single object of kind, given by its registered or short
+// name.
+func (db *RedisObjectDB) GetObject(ctx context.Context, kind, id string) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "GetObject")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+
+	return db.getObject(ctx, name, id)
+}
+
+// getObject reads a single object when its kind is already known, without
+// scanning the keyspace.
+func (db *RedisObjectDB) getObject(ctx context.Context, kind, id string) (Object, error) {
+	val, err := db.redisClient.Get(ctx, objectKey(kind, id)).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%s with ID '%s' %w", kind, id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return db.load(kind, val)
+}
@@ -0,0 +1,382 @@
+package objectdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReplicatingObjectDB writes to a primary ObjectDB and mirrors every write
+// to a secondary one in the background, for moving data between backends
+// without downtime. Reads are served by the primary only. Writes that fail
+// to replicate are counted in Stats and left for Reconcile to repair.
+type ReplicatingObjectDB struct {
+	primary   ObjectDB
+	secondary ObjectDB
+
+	queue   chan replicationOp
+	retries int
+	onError func(error)
+
+	// closeMu keeps Close from closing queue under a blocked send; mu
+	// guards the counters.
+	closeMu  sync.RWMutex
+	closed   bool
+	mu       sync.Mutex
+	inFlight time.Time
+	stats    ReplicationStats
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// ReplicationOption configures a ReplicatingObjectDB at construction time.
+type ReplicationOption func(*ReplicatingObjectDB)
+
+const (
+	defaultReplicationQueue   = 1000
+	defaultReplicationRetries = 3
+)
+
+// WithReplicationQueue bounds how many writes may wait for the secondary.
+// Once it is full, writes block until the secondary catches up or their
+// context ends.
+func WithReplicationQueue(n int) ReplicationOption {
+	return func(db *ReplicatingObjectDB) {
+		if n > 0 {
+			db.queue = make(chan replicationOp, n)
+		}
+	}
+}
+
+// WithReplicationErrorHandler sets a function called with every write the
+// secondary rejected, after all retries or at once if IsRetryable says the
+// error is permanent, e.g. for logging.
+func WithReplicationErrorHandler(fn func(error)) ReplicationOption {
+	return func(db *ReplicatingObjectDB) {
+		db.onError = fn
+	}
+}
+
+// ReplicationStats describes how far the secondary is behind.
+type ReplicationStats struct {
+	// Pending writes are queued or being applied to the secondary.
+	Pending int `json:"pending"`
+	// Lag is how long ago the oldest pending write reached the primary,
+	// or zero when the secondary has caught up.
+	Lag        time.Duration `json:"lag"`
+	Replicated int64         `json:"replicated"`
+	Failed     int64         `json:"failed"`
+	// Dropped writes were never queued because their context ended while
+	// the queue was full.
+	Dropped   int64 `json:"dropped"`
+	LastError error `json:"-"`
+}
+
+type replicationOp struct {
+	object   Object // nil for deletes
+	id       string
+	opts     []DeleteOption
+	queuedAt time.Time
+}
+
+func NewReplicatingObjectDB(primary, secondary ObjectDB, opts ...ReplicationOption) *ReplicatingObjectDB {
+	db := &ReplicatingObjectDB{
+		primary:   primary,
+		secondary: secondary,
+		queue:     make(chan replicationOp, defaultReplicationQueue),
+		retries:   defaultReplicationRetries,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+
+	go db.replicate()
+
+	return db
+}
+
+// Store passes opts to the primary only; the secondary gets every write the
+// primary accepted. Dry runs are not replicated.
+func (db *ReplicatingObjectDB) Store(ctx context.Context, object Object, opts ...StoreOption) error {
+	err := db.primary.Store(ctx, object, opts...)
+	if err != nil {
+		return err
+	}
+
+	var options storeOptions
+	for _, opt := range opts {
+		opt.applyStore(&options)
+	}
+	if options.dryRun != nil {
+		return nil
+	}
+
+	// The caller keeps its object and may change it before the
+	// secondary write happens, so queue a copy.
+	clone, err := cloneObject(object)
+	if err != nil {
+		return err
+	}
+	db.enqueue(ctx, replicationOp{object: clone, id: object.GetID()})
+
+	return nil
+}
+
+func (db *ReplicatingObjectDB) GetObjectByID(ctx context.Context, id string) (Object, error) {
+	return db.primary.GetObjectByID(ctx, id)
+}
+
+func (db *ReplicatingObjectDB) GetObjectByName(ctx context.Context, name string) (Object, error) {
+	return db.primary.GetObjectByName(ctx, name)
+}
+
+func (db *ReplicatingObjectDB) ListObjects(ctx context.Context, kind string) ([]Object, error) {
+	return db.primary.ListObjects(ctx, kind)
+}
+
+// DeleteObject deletes from the primary and then from the secondary with
+// the same options, so a cascade is repeated there.
+func (db *ReplicatingObjectDB) DeleteObject(ctx context.Context, id string, opts ...DeleteOption) error {
+	err := db.primary.DeleteObject(ctx, id, opts...)
+	if err != nil {
+		return err
+	}
+
+	var options deleteOptions
+	for _, opt := range opts {
+		opt.applyDelete(&options)
+	}
+	if options.dryRun != nil {
+		return nil
+	}
+
+	db.enqueue(ctx, replicationOp{id: id, opts: opts})
+	return nil
+}
+
+// Stats reports the secondary's current lag and write counters.
+func (db *ReplicatingObjectDB) Stats() ReplicationStats {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	stats := db.stats
+	stats.Pending = len(db.queue)
+	if !db.inFlight.IsZero() {
+		stats.Pending++
+		stats.Lag = time.Since(db.inFlight)
+	}
+
+	return stats
+}
+
+// Close waits for queued writes to reach the secondary and then closes
+// both backends. Writes made after Close are not replicated.
+func (db *ReplicatingObjectDB) Close() error {
+	db.closeOnce.Do(func() {
+		db.closeMu.Lock()
+		db.closed = true
+		close(db.queue)
+		db.closeMu.Unlock()
+		<-db.done
+
+		db.closeErr = errors.Join(db.primary.Close(), db.secondary.Close())
+	})
+
+	return db.closeErr
+}
+
+func (db *ReplicatingObjectDB) enqueue(ctx context.Context, op replicationOp) {
+	op.queuedAt = time.Now()
+
+	db.closeMu.RLock()
+	defer db.closeMu.RUnlock()
+	if db.closed {
+		return
+	}
+
+	select {
+	case db.queue <- op:
+	case <-ctx.Done():
+		db.mu.Lock()
+		db.stats.Dropped++
+		db.mu.Unlock()
+	}
+}
+
+// replicate applies queued writes to the secondary in order until the
+// queue is closed and drained.
+func (db *ReplicatingObjectDB) replicate() {
+	defer close(db.done)
+
+	for op := range db.queue {
+		db.mu.Lock()
+		db.inFlight = op.queuedAt
+		db.mu.Unlock()
+
+		var err error
+		for attempt := 0; attempt < db.retries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+			}
+			err = db.apply(op)
+			if !IsRetryable(err) {
+				break
+			}
+		}
+
+		db.mu.Lock()
+		db.inFlight = time.Time{}
+		if err != nil {
+			db.stats.Failed++
+			db.stats.LastError = err
+		} else {
+			db.stats.Replicated++
+		}
+		db.mu.Unlock()
+
+		if err != nil && db.onError != nil {
+			db.onError(err)
+		}
+	}
+}
+
+func (db *ReplicatingObjectDB) apply(op replicationOp) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if op.object != nil {
+		err := db.secondary.Store(ctx, op.object)
+		if err != nil {
+			return fmt.Errorf("replicate store '%s': %w", op.id, err)
+		}
+		return nil
+	}
+
+	err := db.secondary.DeleteObject(ctx, op.id, op.opts...)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("replicate delete '%s': %w", op.id, err)
+	}
+
+	return nil
+}
+
+// ReconcileResult counts what Reconcile changed on the secondary.
+type ReconcileResult struct {
+	Checked int `json:"checked"`
+	Stored  int `json:"stored"`
+	Deleted int `json:"deleted"`
+}
+
+// Reconcile makes the secondary match the primary for each of kinds, or
+// every registered kind if none are given: objects missing or different on
+// the secondary are stored again, and objects only the secondary has are
+// deleted. Metadata that Store maintains is ignored when comparing.
+// Writes made while Reconcile runs may be reported as differences.
+func (db *ReplicatingObjectDB) Reconcile(ctx context.Context, kindNames ...string) (ReconcileResult, error) {
+	var result ReconcileResult
+	if len(kindNames) == 0 {
+		kindNames = Kinds()
+	}
+
+	for _, name := range kindNames {
+		kind, ok := lookupKind(name)
+		if !ok {
+			return result, fmt.Errorf("%w '%s'", ErrUnknownKind, name)
+		}
+
+		want, err := db.primary.ListObjects(ctx, kind)
+		if err != nil {
+			return result, fmt.Errorf("list primary %s: %w", kind, err)
+		}
+		have, err := db.secondary.ListObjects(ctx, kind)
+		if err != nil {
+			return result, fmt.Errorf("list secondary %s: %w", kind, err)
+		}
+
+		existing := make(map[string]Object, len(have))
+		for _, object := range have {
+			existing[object.GetID()] = object
+		}
+
+		for _, object := range want {
+			result.Checked++
+
+			current, ok := existing[object.GetID()]
+			delete(existing, object.GetID())
+			if ok {
+				same, err := sameContent(object, current)
+				if err != nil {
+					return result, err
+				}
+				if same {
+					continue
+				}
+			}
+
+			if err := db.secondary.Store(ctx, object); err != nil {
+				return result, fmt.Errorf("store '%s' on secondary: %w", object.GetID(), err)
+			}
+			result.Stored++
+		}
+
+		for id := range existing {
+			err := db.secondary.DeleteObject(ctx, id)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return result, fmt.Errorf("delete '%s' on secondary: %w", id, err)
+			}
+			result.Deleted++
+		}
+	}
+
+	return result, nil
+}
+
+func cloneObject(object Object) (Object, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	clone, err := NewObject(object.GetKind())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// sameContent compares a and b as JSON, ignoring the metadata each store
+// stamps for itself.
+func sameContent(a, b Object) (bool, error) {
+	encode := func(object Object) ([]byte, error) {
+		clone, err := cloneObject(object)
+		if err != nil {
+			return nil, err
+		}
+		meta := clone.GetObjectMeta()
+		meta.CreatedAt, meta.UpdatedAt = time.Time{}, time.Time{}
+		meta.Version, meta.SchemaVersion, meta.APIVersion = 0, 0, ""
+
+		return json.Marshal(clone)
+	}
+
+	ja, err := encode(a)
+	if err != nil {
+		return false, err
+	}
+	jb, err := encode(b)
+	if err != nil {
+		return false, err
+	}
+
+	return string(ja) == string(jb), nil
+}
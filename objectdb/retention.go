@@ -0,0 +1,229 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RetentionRule deletes the objects of a kind once they have gone MaxAge
+// without an update.
+type RetentionRule struct {
+	// Kind is the kind the rule applies to, by registered or short name.
+	Kind   string
+	MaxAge time.Duration
+	// Match, if given, limits the rule to objects matching every filter,
+	// as parsed by ParseFilter, e.g. "labels.tier=scratch".
+	Match []Filter
+}
+
+// RetentionReport describes one pass of RunRetention.
+type RetentionReport struct {
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+	// Kinds has one entry per rule, in the order the rules were given.
+	Kinds []KindRetention `json:"kinds"`
+	// Purged is the number of objects the pass deleted and TotalPurged
+	// the number deleted by every pass since the store was opened, by
+	// kind.
+	Purged      int64            `json:"purged"`
+	TotalPurged map[string]int64 `json:"total_purged"`
+}
+
+// KindRetention is what one retention rule did in a pass.
+type KindRetention struct {
+	Kind string `json:"kind"`
+	// Checked objects were last updated before Cutoff according to the
+	// updated_at index.
+	Cutoff  time.Time `json:"cutoff"`
+	Checked int64     `json:"checked"`
+	Purged  int64     `json:"purged"`
+	// Skipped objects were written to while being checked, and are left
+	// for the next pass.
+	Skipped int64 `json:"skipped"`
+}
+
+type retentionState struct {
+	mu    sync.Mutex
+	last  RetentionReport
+	total map[string]int64
+}
+
+// WithRetention runs RunRetention with rules every interval in the
+// background until Close. Rules for kinds that are not registered, or
+// without a MaxAge, are ignored.
+func WithRetention(interval time.Duration, rules ...RetentionRule) Option {
+	return func(db *RedisObjectDB) {
+		for _, rule := range rules {
+			kind, ok := lookupKind(rule.Kind)
+			if !ok || rule.MaxAge <= 0 {
+				continue
+			}
+			rule.Kind = kind
+			db.retentionRules = append(db.retentionRules, rule)
+		}
+		if interval > 0 {
+			db.retentionInterval = interval
+		}
+	}
+}
+
+func matches(object Object, filters []Filter) bool {
+	for _, f := range filters {
+		if !f.Matches(object) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (db *RedisObjectDB) startRetention() {
+	db.goBackground(func(ctx context.Context) {
+		ticker := time.NewTicker(db.retentionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				db.RunRetention(ctx)
+			}
+		}
+	})
+}
+
+// LastRetention returns the report of the most recent RunRetention pass,
+// finished or not.
+func (db *RedisObjectDB) LastRetention() RetentionReport {
+	db.retention.mu.Lock()
+	defer db.retention.mu.Unlock()
+
+	return db.retention.last
+}
+
+// RunRetention deletes the objects the WithRetention rules have expired.
+// Candidates are read from each kind's updated_at range index, oldest
+// first, and deleted like DeleteObject without a policy would, leaving
+// objects that reference them alone. Each batch is checked under WATCH, so
+// an object updated in the meantime is never deleted.
+func (db *RedisObjectDB) RunRetention(ctx context.Context) (RetentionReport, error) {
+	report := RetentionReport{Started: time.Now()}
+	publish := func() {
+		report.Elapsed = time.Since(report.Started)
+		db.retention.mu.Lock()
+		defer db.retention.mu.Unlock()
+		report.TotalPurged = map[string]int64{}
+		for kind, n := range db.retention.total {
+			report.TotalPurged[kind] = n
+		}
+		db.retention.last = report
+	}
+	defer publish()
+
+	for _, rule := range db.retentionRules {
+		report.Kinds = append(report.Kinds, KindRetention{Kind: rule.Kind, Cutoff: report.Started.Add(-rule.MaxAge)})
+		kr := &report.Kinds[len(report.Kinds)-1]
+
+		err := db.expireKind(ctx, rule, kr, func(purged int) {
+			report.Purged += int64(purged)
+			db.retention.mu.Lock()
+			db.retention.total[rule.Kind] += int64(purged)
+			db.retention.mu.Unlock()
+		})
+		if err != nil {
+			return report, err
+		}
+		publish()
+	}
+
+	return report, nil
+}
+
+// expireKind pages through the objects of rule's kind last updated before
+// kr.Cutoff. Purged objects drop out of the index, so the page offset only
+// moves past the ones kept.
+func (db *RedisObjectDB) expireKind(ctx context.Context, rule RetentionRule, kr *KindRetention, purged func(int)) error {
+	max := strconv.FormatInt(kr.Cutoff.UnixMicro(), 10)
+	var offset int64
+	for {
+		keys, err := db.redisClient.ZRangeByScore(ctx, rangeIndexKey(rule.Kind, "updated_at"), &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    "(" + max,
+			Offset: offset,
+			Count:  int64(db.fetchBatchSize),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		n, err := db.expireBatch(ctx, rule, kr, keys)
+		if err != nil {
+			return err
+		}
+		purged(n)
+		offset += int64(len(keys) - n)
+	}
+}
+
+func (db *RedisObjectDB) expireBatch(ctx context.Context, rule RetentionRule, kr *KindRetention, keys []string) (int, error) {
+	kr.Checked += int64(len(keys))
+
+	var purged int
+	err := db.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		vals, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		var expired []Object
+		for _, val := range vals {
+			data, ok := val.(string)
+			if !ok {
+				continue
+			}
+			object, err := db.decode(rule.Kind, []byte(data))
+			if err != nil || !object.GetObjectMeta().UpdatedAt.Before(kr.Cutoff) {
+				continue
+			}
+			if matches(object, rule.Match) {
+				expired = append(expired, object)
+			}
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, object := range expired {
+				queueDel(ctx, pipe, objectKey(object.GetKind(), object.GetID()))
+				updateIndexes(ctx, pipe, object, nil)
+				db.recordChange(ctx, pipe, ChangeDelete, object.GetKind(), object.GetID(), nil)
+			}
+			return nil
+		})
+		if err == nil {
+			purged = len(expired)
+		}
+		return err
+	}, keys...)
+	if errors.Is(err, redis.TxFailedErr) {
+		kr.Skipped += int64(len(keys))
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("expire %s: %w", rule.Kind, err)
+	}
+
+	kr.Purged += int64(purged)
+	return purged, nil
+}
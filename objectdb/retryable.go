@@ -0,0 +1,76 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IsRetryable reports whether the operation that returned err may succeed
+// if tried again unchanged: timeouts, dropped connections, a Redis that is
+// loading or failing over, a lost WATCH race or a held lock. Everything
+// else, including not found, validation and quota errors, is permanent, as
+// are errors IsRetryable does not recognize. Errors with a Retryable() bool
+// method, anywhere in their chain, decide for themselves.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var classified interface{ Retryable() bool }
+	if errors.As(err, &classified) {
+		return classified.Retryable()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrLockHeld),
+		errors.Is(err, redis.TxFailedErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range []string{"LOADING", "READONLY", "MASTERDOWN", "TRYAGAIN", "CLUSTERDOWN", "BUSY "} {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// go-redis does not export its pool timeout error.
+	return strings.Contains(err.Error(), "connection pool timeout")
+}
+
+// permanentError marks an error that must not be retried even though its
+// cause looks transient, such as a truncated value that fails to decode
+// with io.EOF.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func (permanentError) Retryable() bool {
+	return false
+}
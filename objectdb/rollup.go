@@ -0,0 +1,406 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Group indexes are sorted sets with every score 0, one per kind and
+// field, whose members are the field's value and the object key separated
+// by a NUL byte. Redis orders them by member, so the objects sharing a
+// value are adjacent and a script counts them without any leaving Redis.
+// Numeric and time fields are summarized from their range indexes, whose
+// scores are the field values already. Objects written before a field was
+// registered are missing from its index until RebuildIndexes runs.
+
+// statsLua accumulates into and formats the stats of groupStatsScript and
+// statsScript. Scores go back as strings, since Redis truncates Lua
+// numbers to integers.
+const statsLua = `
+local function add(s, score)
+	score = tonumber(score)
+	s.n, s.sum = s.n + 1, s.sum + score
+	if not s.min or score < s.min then s.min = score end
+	if not s.max or score > s.max then s.max = score end
+end
+local function num(x)
+	if not x then return "" end
+	return string.format("%.17g", x)
+end
+local function emit(out, s)
+	out[#out + 1] = s.n
+	out[#out + 1] = num(s.sum)
+	out[#out + 1] = num(s.min)
+	out[#out + 1] = num(s.max)
+end
+`
+
+// groupStatsScript returns, for each value in the group index KEYS[1], the
+// value, how many objects have it and the count, sum, min and max of their
+// scores in each range index KEYS[2:].
+var groupStatsScript = redis.NewScript(statsLua + `
+local out = {}
+local value, n, stats
+local function flush()
+	if not value then return end
+	out[#out + 1] = value
+	out[#out + 1] = n
+	for j = 2, #KEYS do emit(out, stats[j]) end
+end
+for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+	local i = string.find(member, "\0", 1, true)
+	local v, key = string.sub(member, 1, i - 1), string.sub(member, i + 1)
+	if v ~= value then
+		flush()
+		value, n, stats = v, 0, {}
+		for j = 2, #KEYS do stats[j] = {n = 0, sum = 0} end
+	end
+	n = n + 1
+	for j = 2, #KEYS do
+		local score = redis.call("ZSCORE", KEYS[j], key)
+		if score then add(stats[j], score) end
+	end
+end
+flush()
+return out`)
+
+// statsScript returns the count, sum, min and max of the scores in each
+// range index KEYS.
+var statsScript = redis.NewScript(statsLua + `
+local out = {}
+for _, key in ipairs(KEYS) do
+	local s = {n = 0, sum = 0}
+	local scores = redis.call("ZRANGE", key, 0, -1, "WITHSCORES")
+	for i = 2, #scores, 2 do add(s, scores[i]) end
+	emit(out, s)
+end
+return out`)
+
+// Group is the objects of a kind sharing one value of the field Aggregate
+// groups them by.
+type Group struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	// Stats summarizes each measured field over the group, by the name it
+	// was given as.
+	Stats map[string]Stats `json:"stats,omitempty"`
+}
+
+// Stats summarizes a numeric or time field over a group. Times are in
+// microseconds since the epoch, as range indexes score them, so the
+// average birth date is time.UnixMicro(int64(s.Avg)).
+type Stats struct {
+	// Count is the number of objects in the group with the field set;
+	// Min, Max and Avg are 0 if there are none.
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+func (s *Stats) add(score float64) {
+	if s.Count == 0 || score < s.Min {
+		s.Min = score
+	}
+	if s.Count == 0 || score > s.Max {
+		s.Max = score
+	}
+	s.Count++
+	s.Sum += score
+	s.Avg = s.Sum / float64(s.Count)
+}
+
+// This runs after the generated registerKind calls in objects_gen.go, as
+// init functions run in file name order.
+func init() {
+	RegisterGroupIndex((&Animal{}).GetKind(), "type")
+}
+
+// RegisterGroupIndex lets Aggregate group the objects of kind by field, a
+// string, bool or integer, from an index instead of reading them. Register
+// indexes before opening a store; registering one twice is a no-op.
+func RegisterGroupIndex(kind, field string) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	f, ok := info.fields[field]
+	if !ok {
+		return fmt.Errorf("%s has no field '%s'", kind, field)
+	}
+	if !groupable(f.typ) {
+		return fmt.Errorf("field '%s' of %s is not a string, bool or integer", field, kind)
+	}
+
+	if _, ok := info.groupIndexOn(f); ok {
+		return nil
+	}
+	info.groupFields = append(info.groupFields, indexedField{name: field, fieldInfo: f})
+	return nil
+}
+
+func groupable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// groupIndexOn returns the group index over f, whichever of its paths it
+// was registered under.
+func (info *kindInfo) groupIndexOn(f fieldInfo) (indexedField, bool) {
+	for _, g := range info.groupFields {
+		if reflect.DeepEqual(g.index, f.index) {
+			return g, true
+		}
+	}
+
+	return indexedField{}, false
+}
+
+func groupIndexKey(kind, field string) string {
+	return fmt.Sprintf("idx:group:%s:%s", kind, field)
+}
+
+func groupMember(value, key string) string {
+	return strings.ReplaceAll(value, "\x00", "") + "\x00" + key
+}
+
+func parseGroupMember(member string) (value, key string, ok bool) {
+	return strings.Cut(member, "\x00")
+}
+
+// groupValue formats a field value as the group it falls in; ok is false
+// for nil pointers, which fall in none.
+func groupValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	return strings.ReplaceAll(fmt.Sprint(v.Interface()), "\x00", ""), true
+}
+
+func groupIndexEntries(object Object) []indexEntry {
+	info, ok := kinds[object.GetKind()]
+	if !ok || len(info.groupFields) == 0 {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+	key := objectKey(object.GetKind(), object.GetID())
+
+	var entries []indexEntry
+	for _, g := range info.groupFields {
+		fv, err := v.FieldByIndexErr(g.index)
+		if err != nil {
+			continue
+		}
+		value, ok := groupValue(fv)
+		if !ok {
+			continue
+		}
+		entries = append(entries, indexEntry{
+			key:    groupIndexKey(object.GetKind(), g.name),
+			member: groupMember(value, key),
+			sorted: true,
+		})
+	}
+
+	return entries
+}
+
+// measure returns a numeric or time field value as a Stats score.
+func measure(v reflect.Value) (float64, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return float64(t.UnixMicro()), true
+	}
+
+	return toFloat(v.Interface())
+}
+
+// Aggregate counts the objects of kind by their value of groupBy, e.g.
+// the animals of each type, in byte-wise order of the values, and
+// summarizes the numeric or time fields measures over each group:
+//
+//	Aggregate(ctx, "person", "", "birth_date")
+//
+// returns a single group of every person with the range of their birth
+// dates. An empty groupBy puts every object in one group with an empty
+// Value; otherwise objects whose field is a nil pointer are not counted.
+// With a group index on groupBy and range indexes on measures everything
+// is computed in Redis; otherwise every object of the kind is read.
+func (db *RedisObjectDB) Aggregate(ctx context.Context, kind, groupBy string, measures ...string) ([]Group, error) {
+	ctx, cancel := db.withTimeout(ctx, "Aggregate")
+	defer cancel()
+
+	name, ok := lookupKind(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	info := kinds[kind]
+	if groupBy != "" && !hasField(kind, groupBy) {
+		return nil, fmt.Errorf("%s has no field '%s'", kind, groupBy)
+	}
+	indexed := true
+	var keys []string
+	for _, m := range measures {
+		f, ok := info.fields[m]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field '%s'", kind, m)
+		}
+		if t := f.typ; !rangeable(t) && (t.Kind() != reflect.Pointer || !rangeable(t.Elem())) {
+			return nil, fmt.Errorf("field '%s' of %s is neither a time nor a number", m, kind)
+		}
+		r, ok := info.rangeIndexOn(f)
+		indexed = indexed && ok
+		keys = append(keys, rangeIndexKey(kind, r.name))
+	}
+
+	if !indexed {
+		return db.aggregateObjects(ctx, kind, groupBy, measures)
+	}
+	if groupBy == "" {
+		return db.aggregateAll(ctx, kind, keys, measures)
+	}
+	g, ok := info.groupIndexOn(info.fields[groupBy])
+	if !ok {
+		return db.aggregateObjects(ctx, kind, groupBy, measures)
+	}
+
+	keys = append([]string{groupIndexKey(kind, g.name)}, keys...)
+	res, err := groupStatsScript.Run(ctx, db.redisClient, keys).Slice()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	groups := []Group{}
+	for width := 2 + 4*len(measures); len(res) >= width; res = res[width:] {
+		value, _ := res[0].(string)
+		n, _ := res[1].(int64)
+		groups = append(groups, Group{Value: value, Count: n, Stats: parseStats(res[2:width], measures)})
+	}
+
+	return groups, nil
+}
+
+func (db *RedisObjectDB) aggregateAll(ctx context.Context, kind string, keys, measures []string) ([]Group, error) {
+	n, err := db.redisClient.SCard(ctx, kindIndexKey(kind)).Result()
+	if err != nil {
+		return nil, err
+	}
+	group := Group{Count: n}
+	if len(keys) > 0 {
+		res, err := statsScript.Run(ctx, db.redisClient, keys).Slice()
+		if err != nil {
+			return nil, err
+		}
+		group.Stats = parseStats(res, measures)
+	}
+
+	return []Group{group}, nil
+}
+
+// parseStats reads the count, sum, min and max the scripts return for each
+// of measures.
+func parseStats(res []interface{}, measures []string) map[string]Stats {
+	if len(measures) == 0 {
+		return nil
+	}
+	stats := map[string]Stats{}
+	for i, m := range measures {
+		if len(res) < 4*(i+1) {
+			break
+		}
+		var s Stats
+		s.Count, _ = res[4*i].(int64)
+		s.Sum, _ = toFloat(res[4*i+1])
+		s.Min, _ = toFloat(res[4*i+2])
+		s.Max, _ = toFloat(res[4*i+3])
+		if s.Count > 0 {
+			s.Avg = s.Sum / float64(s.Count)
+		}
+		stats[m] = s
+	}
+
+	return stats
+}
+
+// aggregateObjects is Aggregate reading every object of kind, for fields
+// without the indexes to do it in Redis.
+func (db *RedisObjectDB) aggregateObjects(ctx context.Context, kind, groupBy string, measures []string) ([]Group, error) {
+	groups := map[string]*Group{}
+	newGroup := func(value string) *Group {
+		group := &Group{Value: value}
+		if len(measures) > 0 {
+			group.Stats = map[string]Stats{}
+			for _, m := range measures {
+				group.Stats[m] = Stats{}
+			}
+		}
+		groups[value] = group
+		return group
+	}
+	if groupBy == "" {
+		newGroup("")
+	}
+	err := db.scanObjects(ctx, fmt.Sprintf("%s:*", kind), func(object Object) error {
+		var value string
+		if groupBy != "" {
+			fv, ok := fieldValue(object, groupBy)
+			if !ok {
+				return nil
+			}
+			if value, ok = groupValue(fv); !ok {
+				return nil
+			}
+		}
+		group := groups[value]
+		if group == nil {
+			group = newGroup(value)
+		}
+		group.Count++
+		for _, m := range measures {
+			s := group.Stats[m]
+			if fv, ok := fieldValue(object, m); ok {
+				if score, ok := measure(fv); ok {
+					s.add(score)
+				}
+			}
+			group.Stats[m] = s
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Group, 0, len(groups))
+	for _, group := range groups {
+		list = append(list, *group)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Value < list[j].Value })
+
+	return list, nil
+}
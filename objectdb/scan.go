@@ -0,0 +1,60 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListObjectsPage runs a single SCAN step over the objects of kind, starting
+// at cursor. It returns the objects found in that step and the cursor to
+// resume from; a returned cursor of 0 means the iteration is complete.
+// Background jobs can persist the cursor and pick up where they left off.
+//
+// As with SCAN itself, a page may be empty while the cursor is not yet 0,
+// and objects written during the iteration may or may not be returned.
+func (db *RedisObjectDB) ListObjectsPage(ctx context.Context, kind string, cursor uint64) ([]Object, uint64, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListObjectsPage")
+	defer cancel()
+
+	keys, next, err := db.redisClient.Scan(ctx, cursor, fmt.Sprintf("%s:*", kind), db.scanCount).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	objects, err := db.fetchKeys(ctx, keys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return objects, next, nil
+}
+
+// fetchKeys decodes the objects stored at keys, in fetchBatchSize chunks.
+// Keys that are not object keys or no longer exist are skipped.
+func (db *RedisObjectDB) fetchKeys(ctx context.Context, keys []string) ([]Object, error) {
+	var objectKeys []string
+	for _, key := range keys {
+		if _, _, ok := parseObjectKey(key); ok {
+			objectKeys = append(objectKeys, key)
+		}
+	}
+
+	var objects []Object
+	collect := func(object Object) error {
+		objects = append(objects, object)
+		return nil
+	}
+	for len(objectKeys) > 0 {
+		n := len(objectKeys)
+		if n > db.fetchBatchSize {
+			n = db.fetchBatchSize
+		}
+		err := db.fetchObjects(ctx, objectKeys[:n], collect)
+		if err != nil {
+			return nil, err
+		}
+		objectKeys = objectKeys[n:]
+	}
+
+	return objects, nil
+}
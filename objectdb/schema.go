@@ -0,0 +1,170 @@
+package objectdb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Validator is implemented by kinds with domain rules of their own. Store
+// calls Validate after the registered schema passes and returns its error
+// unchanged.
+type Validator interface {
+	Validate() error
+}
+
+// Defaulter is implemented by kinds that fill in or normalize their own
+// fields. Store calls Default first, before any validation or encoding, so
+// every write path applies the same defaults.
+type Defaulter interface {
+	Default()
+}
+
+// Schema holds the validation rules of one kind, keyed by field path.
+type Schema struct {
+	Fields map[string]FieldRule
+}
+
+// FieldRule constrains a single field. Format and Enum only apply to fields
+// that are set, so combine them with Required to forbid empty values.
+type FieldRule struct {
+	Required bool
+	// Format is one of the names in formats, e.g. "email" or "date".
+	Format string
+	// Pattern, if set, must match the whole value.
+	Pattern string
+	Enum    []string
+}
+
+var formats = map[string]*regexp.Regexp{
+	"date":      regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`),
+	"date-time": regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`),
+	"email":     regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`),
+}
+
+var schemas = map[string]compiledSchema{}
+
+type compiledSchema struct {
+	fields   map[string]FieldRule
+	patterns map[string]*regexp.Regexp
+}
+
+func init() {
+	mustRegisterSchema((&Person{}).GetKind(), Schema{Fields: map[string]FieldRule{
+		"id":   {Required: true},
+		"name": {Required: true},
+	}})
+	mustRegisterSchema((&Animal{}).GetKind(), Schema{Fields: map[string]FieldRule{
+		"id":   {Required: true},
+		"name": {Required: true},
+		"type": {Enum: []string{"Bird", "Cat", "Dog", "Fish", "Horse", "Rabbit", "Other"}},
+	}})
+}
+
+func mustRegisterSchema(kind string, schema Schema) {
+	if err := RegisterSchema(kind, schema); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterSchema replaces the validation rules of kind. Store rejects
+// objects breaking them with a *ValidationError.
+func RegisterSchema(kind string, schema Schema) error {
+	compiled := compiledSchema{fields: schema.Fields, patterns: map[string]*regexp.Regexp{}}
+	for path, rule := range schema.Fields {
+		if !hasField(kind, path) {
+			return fmt.Errorf("%s has no field '%s'", kind, path)
+		}
+		if rule.Format != "" && formats[rule.Format] == nil {
+			return fmt.Errorf("unknown format '%s' for field '%s'", rule.Format, path)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("pattern for field '%s': %w", path, err)
+			}
+			compiled.patterns[path] = re
+		}
+	}
+
+	schemas[kind] = compiled
+	return nil
+}
+
+// LookupSchema returns the validation rules registered for kind.
+func LookupSchema(kind string) (Schema, bool) {
+	compiled, ok := schemas[kind]
+	if !ok {
+		return Schema{}, false
+	}
+
+	return Schema{Fields: compiled.fields}, true
+}
+
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule an object broke, not just the first.
+type ValidationError struct {
+	Kind       string      `json:"kind"`
+	ID         string      `json:"id"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+
+	return fmt.Sprintf("invalid %s '%s': %s", e.Kind, e.ID, strings.Join(msgs, "; "))
+}
+
+func validateSchema(object Object) error {
+	schema, ok := schemas[object.GetKind()]
+	if !ok {
+		return nil
+	}
+
+	var violations []Violation
+	for path, rule := range schema.fields {
+		v, ok := fieldValue(object, path)
+		if !ok || v.IsZero() {
+			if rule.Required {
+				violations = append(violations, Violation{Field: path, Message: "is required"})
+			}
+			continue
+		}
+
+		value := fmt.Sprint(reflect.Indirect(v).Interface())
+		if rule.Format != "" && !formats[rule.Format].MatchString(value) {
+			violations = append(violations, Violation{Field: path, Message: fmt.Sprintf("'%s' is not a valid %s", value, rule.Format)})
+		}
+		if re := schema.patterns[path]; re != nil && !re.MatchString(value) {
+			violations = append(violations, Violation{Field: path, Message: fmt.Sprintf("'%s' does not match %s", value, rule.Pattern)})
+		}
+		if len(rule.Enum) > 0 && !containsString(rule.Enum, value) {
+			violations = append(violations, Violation{Field: path, Message: fmt.Sprintf("'%s' is not one of %s", value, strings.Join(rule.Enum, ", "))})
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return &ValidationError{Kind: object.GetKind(), ID: object.GetID(), Violations: violations}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
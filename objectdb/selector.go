@@ -0,0 +1,208 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type SelectorOperator string
+
+const (
+	SelectorEquals       SelectorOperator = "="
+	SelectorNotEquals    SelectorOperator = "!="
+	SelectorIn           SelectorOperator = "in"
+	SelectorNotIn        SelectorOperator = "notin"
+	SelectorExists       SelectorOperator = "exists"
+	SelectorDoesNotExist SelectorOperator = "!"
+)
+
+// Requirement is one clause of a label selector. Equality operators take a
+// single value, set operators any number and existence operators none.
+type Requirement struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+// validate checks that r has as many values as its operator takes.
+func (r Requirement) validate() error {
+	switch r.Operator {
+	case SelectorEquals, SelectorNotEquals:
+		if len(r.Values) != 1 {
+			return fmt.Errorf("selector requirement '%s %s' takes one value, got %d", r.Key, r.Operator, len(r.Values))
+		}
+	case SelectorExists, SelectorDoesNotExist:
+		if len(r.Values) != 0 {
+			return fmt.Errorf("selector requirement '%s %s' takes no values, got %d", r.Key, r.Operator, len(r.Values))
+		}
+	case SelectorIn, SelectorNotIn:
+	default:
+		return fmt.Errorf("unknown selector operator '%s'", r.Operator)
+	}
+
+	return nil
+}
+
+// Selector matches objects satisfying all of its requirements. As with
+// Kubernetes selectors, != and notin also match objects without the label.
+type Selector []Requirement
+
+// ParseSelector reads the textual selector form, for example
+//
+//	env=prod,tier in (web,api),!legacy,team
+func ParseSelector(s string) (Selector, error) {
+	var selector Selector
+	for _, clause := range splitClauses(s) {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		req, err := parseRequirement(clause)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, req)
+	}
+
+	return selector, nil
+}
+
+// splitClauses splits on commas that are not inside a value list.
+func splitClauses(s string) []string {
+	var clauses []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				clauses = append(clauses, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(clauses, s[start:])
+}
+
+func parseRequirement(clause string) (Requirement, error) {
+	if strings.HasPrefix(clause, "!") && !strings.Contains(clause, "=") {
+		return Requirement{Key: strings.TrimSpace(clause[1:]), Operator: SelectorDoesNotExist}, nil
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		if key, value, ok := strings.Cut(clause, op); ok {
+			operator := SelectorEquals
+			if op == "!=" {
+				operator = SelectorNotEquals
+			}
+			return Requirement{Key: strings.TrimSpace(key), Operator: operator, Values: []string{strings.TrimSpace(value)}}, nil
+		}
+	}
+
+	fields := strings.Fields(clause)
+	if len(fields) == 1 {
+		return Requirement{Key: fields[0], Operator: SelectorExists}, nil
+	}
+	if len(fields) < 2 || (fields[1] != string(SelectorIn) && fields[1] != string(SelectorNotIn)) {
+		return Requirement{}, fmt.Errorf("invalid selector clause '%s'", clause)
+	}
+
+	list := strings.TrimSpace(strings.Join(fields[2:], " "))
+	if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+		return Requirement{}, fmt.Errorf("invalid value list in selector clause '%s'", clause)
+	}
+
+	var values []string
+	for _, v := range strings.Split(list[1:len(list)-1], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return Requirement{Key: fields[0], Operator: SelectorOperator(fields[1]), Values: values}, nil
+}
+
+// ListObjectsByLabels returns the objects of kind matching selector. The
+// match is computed inside Redis from the label index sets, so only
+// matching objects are fetched. Requirements with the wrong number of
+// values for their operator are rejected with an error.
+func (db *RedisObjectDB) ListObjectsByLabels(ctx context.Context, kind string, selector Selector) ([]Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "ListObjectsByLabels")
+	defer cancel()
+
+	for _, req := range selector {
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpPrefix, err := tempKeyPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := db.redisClient.TxPipeline()
+
+	var include, exclude, temps []string
+	for i, req := range selector {
+		switch req.Operator {
+		case SelectorEquals:
+			include = append(include, labelIndexKey(kind, req.Key, req.Values[0]))
+		case SelectorExists:
+			include = append(include, labelKeyIndexKey(kind, req.Key))
+		case SelectorIn:
+			union := fmt.Sprintf("%s:%d", tmpPrefix, i)
+			var sets []string
+			for _, value := range req.Values {
+				sets = append(sets, labelIndexKey(kind, req.Key, value))
+			}
+			if len(sets) == 0 {
+				return nil, nil
+			}
+			pipe.SUnionStore(ctx, union, sets...)
+			include = append(include, union)
+			temps = append(temps, union)
+		case SelectorNotEquals, SelectorNotIn:
+			for _, value := range req.Values {
+				exclude = append(exclude, labelIndexKey(kind, req.Key, value))
+			}
+		case SelectorDoesNotExist:
+			exclude = append(exclude, labelKeyIndexKey(kind, req.Key))
+		default:
+			return nil, fmt.Errorf("unknown selector operator '%s'", req.Operator)
+		}
+	}
+	if len(include) == 0 {
+		include = append(include, kindIndexKey(kind))
+	}
+
+	result := tmpPrefix + ":result"
+	temps = append(temps, result)
+	pipe.SInterStore(ctx, result, include...)
+	members := pipe.SDiff(ctx, append([]string{result}, exclude...)...)
+	pipe.Del(ctx, temps...)
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.fetchKeys(ctx, members.Val())
+}
+
+func tempKeyPrefix() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "tmp:" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,128 @@
+package objectdb
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type SentinelConfig struct {
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	Username string
+	Password string
+	DB       int
+
+	// TLS and TLSConfig apply to both the sentinels and the master.
+	TLS       bool
+	TLSConfig *tls.Config
+
+	// MaxRetries is how many times a command is retried while the client
+	// reconnects to a newly promoted master. Zero uses the go-redis default.
+	MaxRetries int
+
+	// OnFailover, if set, is called every time Sentinel announces that the
+	// master has moved to a new address.
+	OnFailover func(FailoverEvent)
+}
+
+type FailoverEvent struct {
+	MasterName string
+	OldAddr    string
+	NewAddr    string
+}
+
+// NewSentinelObjectDB builds a store on top of a Sentinel-managed master.
+// The underlying client follows failovers on its own; OnFailover is only
+// there so applications can log or react to them.
+func NewSentinelObjectDB(cfg SentinelConfig, opts ...Option) *RedisObjectDB {
+	client := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       cfg.MasterName,
+		SentinelAddrs:    cfg.SentinelAddrs,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MaxRetries:       cfg.MaxRetries,
+		MinRetryBackoff:  100 * time.Millisecond,
+		MaxRetryBackoff:  2 * time.Second,
+		TLSConfig:        tlsConfig(cfg.TLS, cfg.TLSConfig),
+	})
+
+	db := NewRedisObjectDB(client, opts...)
+	if cfg.OnFailover != nil && len(cfg.SentinelAddrs) > 0 {
+		db.goBackground(func(ctx context.Context) {
+			watchFailovers(ctx, cfg)
+		})
+	}
+
+	return db
+}
+
+// watchFailovers subscribes to +switch-master on one sentinel at a time and
+// moves on to the next address whenever the subscription breaks.
+func watchFailovers(ctx context.Context, cfg SentinelConfig) {
+	for i := 0; ctx.Err() == nil; i++ {
+		sentinel := redis.NewSentinelClient(&redis.Options{
+			Addr:      cfg.SentinelAddrs[i%len(cfg.SentinelAddrs)],
+			Username:  cfg.SentinelUsername,
+			Password:  cfg.SentinelPassword,
+			TLSConfig: tlsConfig(cfg.TLS, cfg.TLSConfig),
+		})
+
+		pubsub := sentinel.Subscribe(ctx, "+switch-master")
+
+		// ReceiveMessage does not return on cancellation by itself, so
+		// closing the subscription is what unblocks it during Close.
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				pubsub.Close()
+			case <-stop:
+			}
+		}()
+
+		for {
+			msg, err := pubsub.ReceiveMessage(ctx)
+			if err != nil {
+				break
+			}
+
+			event, ok := parseSwitchMaster(msg.Payload)
+			if ok && event.MasterName == cfg.MasterName {
+				cfg.OnFailover(event)
+			}
+		}
+
+		close(stop)
+		pubsub.Close()
+		sentinel.Close()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// parseSwitchMaster decodes "<name> <old-ip> <old-port> <new-ip> <new-port>".
+func parseSwitchMaster(payload string) (FailoverEvent, bool) {
+	parts := strings.Fields(payload)
+	if len(parts) != 5 {
+		return FailoverEvent{}, false
+	}
+
+	return FailoverEvent{
+		MasterName: parts[0],
+		OldAddr:    parts[1] + ":" + parts[2],
+		NewAddr:    parts[3] + ":" + parts[4],
+	}, true
+}
@@ -0,0 +1,34 @@
+package objectdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrObjectTooLarge is returned by Store for objects whose stored value
+// exceeds WithMaxObjectSize.
+var ErrObjectTooLarge = errors.New("object too large")
+
+// WithMaxObjectSize makes Store reject objects whose value, as it would be
+// written to Redis after encoding and compression, is larger than n bytes.
+func WithMaxObjectSize(n int) Option {
+	return func(db *RedisObjectDB) {
+		if n > 0 {
+			db.maxObjectSize = n
+		}
+	}
+}
+
+// MaxObjectSize returns the limit set with WithMaxObjectSize, 0 for none.
+func (db *RedisObjectDB) MaxObjectSize() int {
+	return db.maxObjectSize
+}
+
+func (db *RedisObjectDB) checkObjectSize(object Object, value []byte) error {
+	if db.maxObjectSize == 0 || len(value) <= db.maxObjectSize {
+		return nil
+	}
+
+	return fmt.Errorf("%s '%s' is %d bytes, more than the %d allowed: %w",
+		object.GetKind(), object.GetID(), len(value), db.maxObjectSize, ErrObjectTooLarge)
+}
@@ -0,0 +1,90 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KindStats summarizes the stored objects of one kind.
+type KindStats struct {
+	Kind    string `json:"kind"`
+	Objects int64  `json:"objects"`
+	// Bytes is the total size of the stored values, as encoded.
+	Bytes       int64 `json:"bytes"`
+	AverageSize int64 `json:"average_size"`
+	// LastWrite is the latest UpdatedAt among the objects, zero for an
+	// empty kind.
+	LastWrite time.Time `json:"last_write"`
+}
+
+// Stats scans every stored object and returns one KindStats per registered
+// kind, in Kinds order. It reads all values, so it suits occasional
+// capacity planning rather than frequent polling. Values that no longer
+// decode are counted but do not move LastWrite.
+func (db *RedisObjectDB) Stats(ctx context.Context) ([]KindStats, error) {
+	var stats []KindStats
+	for _, kind := range Kinds() {
+		s, err := db.kindStats(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+func (db *RedisObjectDB) kindStats(ctx context.Context, kind string) (KindStats, error) {
+	s := KindStats{Kind: kind}
+
+	var keys []string
+	sum := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		vals, err := db.redisClient.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for _, val := range vals {
+			data, ok := val.(string)
+			if !ok {
+				continue
+			}
+			s.Objects++
+			s.Bytes += int64(len(data))
+
+			object, err := db.decode(kind, []byte(data))
+			if err == nil && object.GetObjectMeta().UpdatedAt.After(s.LastWrite) {
+				s.LastWrite = object.GetObjectMeta().UpdatedAt
+			}
+		}
+		keys = keys[:0]
+		return nil
+	}
+
+	it := db.redisClient.Scan(ctx, 0, fmt.Sprintf("%s:*", kind), db.scanCount).Iterator()
+	for it.Next(ctx) {
+		if k, _, ok := parseObjectKey(it.Val()); !ok || k != kind {
+			continue
+		}
+		keys = append(keys, it.Val())
+		if len(keys) >= db.fetchBatchSize {
+			if err := sum(); err != nil {
+				return s, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return s, err
+	}
+	if err := sum(); err != nil {
+		return s, err
+	}
+
+	if s.Objects > 0 {
+		s.AverageSize = s.Bytes / s.Objects
+	}
+	return s, nil
+}
@@ -0,0 +1,74 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ObjectIterator yields the objects of one kind lazily, holding at most one
+// fetch batch in memory. It follows the shape of redis.ScanIterator:
+//
+//	it := db.ListObjectsStream(ctx, kind)
+//	for it.Next(ctx) {
+//		use(it.Object())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ObjectIterator struct {
+	db   *RedisObjectDB
+	scan *redis.ScanIterator
+
+	buf []Object
+	cur Object
+	err error
+}
+
+func (db *RedisObjectDB) ListObjectsStream(ctx context.Context, kind string) *ObjectIterator {
+	return &ObjectIterator{
+		db:   db,
+		scan: db.redisClient.Scan(ctx, 0, fmt.Sprintf("%s:*", kind), db.scanCount).Iterator(),
+	}
+}
+
+// Next advances to the next object, fetching another batch when needed. It
+// returns false when the kind is exhausted or an error occurred.
+func (it *ObjectIterator) Next(ctx context.Context) bool {
+	for len(it.buf) == 0 {
+		if it.err != nil {
+			return false
+		}
+
+		keys := make([]string, 0, it.db.fetchBatchSize)
+		for len(keys) < it.db.fetchBatchSize && it.scan.Next(ctx) {
+			if _, _, ok := parseObjectKey(it.scan.Val()); ok {
+				keys = append(keys, it.scan.Val())
+			}
+		}
+		if err := it.scan.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(keys) == 0 {
+			return false
+		}
+
+		it.err = it.db.fetchObjects(ctx, keys, func(object Object) error {
+			it.buf = append(it.buf, object)
+			return nil
+		})
+	}
+
+	it.cur, it.buf[0] = it.buf[0], nil
+	it.buf = it.buf[1:]
+	return true
+}
+
+// Object returns the object Next moved to.
+func (it *ObjectIterator) Object() Object {
+	return it.cur
+}
+
+func (it *ObjectIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,116 @@
+// Package testsupport starts throwaway backends for integration tests, so
+// they run anywhere Docker does without a Redis started by hand:
+//
+//	func TestStore(t *testing.T) {
+//		db := testsupport.NewObjectDB(t)
+//		testsupport.Seed(t, db, &objectdb.Person{ObjectMeta: objectdb.ObjectMeta{ID: "1", Name: "John"}})
+//		...
+//	}
+//
+// Containers are started with testcontainers and removed when the test
+// ends. Tests are skipped, not failed, when no Docker daemon is reachable.
+package testsupport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"go-assignment/objectdb"
+)
+
+const defaultRedisImage = "redis:7-alpine"
+
+type config struct {
+	image string
+}
+
+// Option configures the container started by StartRedis.
+type Option func(*config)
+
+// WithImage runs image instead of redis:7-alpine, e.g. to test against an
+// older server.
+func WithImage(image string) Option {
+	return func(c *config) {
+		if image != "" {
+			c.image = image
+		}
+	}
+}
+
+// StartRedis starts a Redis container for the duration of t and returns its
+// URL.
+func StartRedis(t testing.TB, opts ...Option) string {
+	t.Helper()
+
+	cfg := config{image: defaultRedisImage}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx := context.Background()
+	provider, err := testcontainers.NewDockerProvider()
+	if err == nil {
+		err = provider.Health(ctx)
+		provider.Close()
+	}
+	if err != nil {
+		t.Skipf("testsupport: Docker is not available: %v", err)
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        cfg.image,
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("testsupport: starting %s: %v", cfg.image, err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("testsupport: removing %s: %v", cfg.image, err)
+		}
+	})
+
+	endpoint, err := container.PortEndpoint(ctx, "6379/tcp", "redis")
+	if err != nil {
+		t.Fatalf("testsupport: %v", err)
+	}
+
+	return endpoint + "/0"
+}
+
+// NewObjectDB starts a Redis container and returns a store on it with opts,
+// closed when t ends. Use StartRedis directly to pick the image.
+func NewObjectDB(t testing.TB, opts ...objectdb.Option) *objectdb.RedisObjectDB {
+	t.Helper()
+
+	db, err := objectdb.NewRedisObjectDBFromURL(StartRedis(t), opts...)
+	if err != nil {
+		t.Fatalf("testsupport: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Healthy(context.Background()); err != nil {
+		t.Fatalf("testsupport: %v", err)
+	}
+
+	return db
+}
+
+// Seed stores objects in db as fixtures, failing t on the first error.
+func Seed(t testing.TB, db objectdb.ObjectDB, objects ...objectdb.Object) {
+	t.Helper()
+
+	for i, object := range objects {
+		if err := db.Store(context.Background(), object); err != nil {
+			t.Fatalf("testsupport: seeding object %d (%s '%s'): %v", i, object.GetKind(), object.GetID(), err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Text indexes are inverted indexes: one set per kind and word, holding the
+// keys of the objects with that word in one of the kind's text fields.
+// Words are runs of letters and digits, lower-cased, with apostrophes
+// dropped so "O'Brien" is found by "obrien" too. Objects written before a
+// field was registered are missing from its index until RebuildIndexes runs.
+
+func init() {
+	RegisterTextIndex((&Person{}).GetKind(), "last_name")
+	RegisterTextIndex((&Animal{}).GetKind(), "type")
+}
+
+// RegisterTextIndex makes the words of field, a string field of kind,
+// searchable with SearchObjects. Register fields before opening a store;
+// registering one twice is a no-op.
+func RegisterTextIndex(kind, field string) error {
+	info, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	f, ok := info.fields[field]
+	if !ok {
+		return fmt.Errorf("%s has no field '%s'", kind, field)
+	}
+	if f.typ.Kind() != reflect.String {
+		return fmt.Errorf("field '%s' of %s is not a string", field, kind)
+	}
+
+	for _, t := range info.textFields {
+		if reflect.DeepEqual(t.index, f.index) {
+			return nil
+		}
+	}
+	info.textFields = append(info.textFields, indexedField{name: field, fieldInfo: f})
+	return nil
+}
+
+func textIndexKey(kind, word string) string {
+	return fmt.Sprintf("idx:text:%s:%s", kind, word)
+}
+
+// tokenize splits s into its distinct lower-case words.
+func tokenize(s string) []string {
+	// Apostrophes join rather than split, as in names like O'Brien.
+	s = strings.NewReplacer("'", "", "’", "").Replace(s)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	distinct := words[:0]
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			distinct = append(distinct, word)
+		}
+	}
+
+	return distinct
+}
+
+// objectWords returns the distinct words of object's text fields.
+func objectWords(object Object) []string {
+	info, ok := kinds[object.GetKind()]
+	if !ok || len(info.textFields) == 0 {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(object))
+
+	var text []string
+	for _, t := range info.textFields {
+		if fv, err := v.FieldByIndexErr(t.index); err == nil {
+			text = append(text, fv.String())
+		}
+	}
+
+	return tokenize(strings.Join(text, " "))
+}
+
+func textIndexEntries(object Object) []indexEntry {
+	member := objectKey(object.GetKind(), object.GetID())
+
+	var entries []indexEntry
+	for _, word := range objectWords(object) {
+		entries = append(entries, indexEntry{key: textIndexKey(object.GetKind(), word), member: member})
+	}
+
+	return entries
+}
+
+// SearchMatch is one result of SearchObjects. Score is the number of the
+// query's words found in the object.
+type SearchMatch struct {
+	Object Object `json:"object"`
+	Score  int    `json:"score"`
+}
+
+// SearchObjects returns the objects of kind, or of every kind if kind is
+// empty, with any word of query in a text field such as the name. Matches
+// come best first: by how many of the query's words they contain, then by
+// kind and ID.
+func (db *RedisObjectDB) SearchObjects(ctx context.Context, kind, query string) ([]SearchMatch, error) {
+	ctx, cancel := db.withTimeout(ctx, "SearchObjects")
+	defer cancel()
+
+	searched := Kinds()
+	if kind != "" {
+		name, ok := lookupKind(kind)
+		if !ok {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+		}
+		searched = []string{name}
+	}
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil, errors.New("search query has no words")
+	}
+
+	pipe := db.redisClient.Pipeline()
+	var cmds []*redis.StringSliceCmd
+	for _, k := range searched {
+		for _, word := range words {
+			cmds = append(cmds, pipe.SMembers(ctx, textIndexKey(k, word)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var keys []string
+	for _, cmd := range cmds {
+		for _, key := range cmd.Val() {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	objects, err := db.fetchKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scores come from the objects rather than the index, so entries left
+	// behind by interrupted writes do not turn up as matches.
+	wanted := make(map[string]bool, len(words))
+	for _, word := range words {
+		wanted[word] = true
+	}
+	var matches []SearchMatch
+	for _, object := range objects {
+		score := 0
+		for _, word := range objectWords(object) {
+			if wanted[word] {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, SearchMatch{Object: object, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Object.GetKind() != b.Object.GetKind() {
+			return a.Object.GetKind() < b.Object.GetKind()
+		}
+		return a.Object.GetID() < b.Object.GetID()
+	})
+
+	return matches, nil
+}
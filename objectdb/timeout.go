@@ -0,0 +1,43 @@
+package objectdb
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout bounds every read and write made with a context that has no
+// deadline of its own, so a slow Redis cannot hang callers indefinitely.
+// It covers Store, StoreTx, UpdateIf, DeleteObject and the lookups and
+// listings; long-running jobs such as exports, migrations and RunGC are
+// left to the caller's context.
+func WithTimeout(d time.Duration) Option {
+	return func(db *RedisObjectDB) {
+		db.timeout = d
+	}
+}
+
+// WithOperationTimeout overrides WithTimeout for one operation, named after
+// its method, e.g. a longer "ListObjects". A zero d leaves op unbounded.
+func WithOperationTimeout(op string, d time.Duration) Option {
+	return func(db *RedisObjectDB) {
+		if db.opTimeouts == nil {
+			db.opTimeouts = map[string]time.Duration{}
+		}
+		db.opTimeouts[op] = d
+	}
+}
+
+// withTimeout returns ctx bounded by the timeout configured for op, unless
+// it already has a deadline. Calls nested in another bounded call thus
+// share its deadline.
+func (db *RedisObjectDB) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	d, ok := db.opTimeouts[op]
+	if !ok {
+		d = db.timeout
+	}
+	if _, hasDeadline := ctx.Deadline(); hasDeadline || d <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,133 @@
+package objectdb
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIToken is a bearer token and what it grants. Scopes are written
+// "verb:kind:namespace", where the kind and namespace may be left out and
+// any part may be "*": "get:person" reads people in every namespace,
+// "store:*:team-a" writes anything in team-a, and "*" grants everything.
+type APIToken struct {
+	Token string `json:"token"`
+	// Name and Groups make up the principal the token authenticates, named
+	// "token:<name>" so that policy rules for a client certificate subject
+	// never apply to a token of the same name, or the other way round.
+	// Tokens sharing a name share their scopes.
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+	Scopes []string `json:"scopes"`
+}
+
+// tokenPrincipalPrefix starts the names of token principals. Like
+// "group:", which starts group subjects, and "admin:", which starts the
+// admin UI's principals, see httpserver.AdminPrincipal, certificates cannot
+// claim it.
+const tokenPrincipalPrefix = "token:"
+
+var reservedPrincipalPrefixes = []string{tokenPrincipalPrefix, "group:", "admin:"}
+
+// TokenSet authenticates API tokens. Only their SHA-256 hashes are kept.
+type TokenSet struct {
+	principals map[[sha256.Size]byte]Principal
+	rules      []AccessRule
+}
+
+// NewTokenSet checks tokens and their scopes and indexes them.
+func NewTokenSet(tokens ...APIToken) (*TokenSet, error) {
+	s := &TokenSet{principals: map[[sha256.Size]byte]Principal{}}
+	for _, t := range tokens {
+		if t.Token == "" || t.Name == "" {
+			return nil, errors.New("API tokens need a token and a name")
+		}
+		hash := sha256.Sum256([]byte(t.Token))
+		if _, ok := s.principals[hash]; ok {
+			return nil, fmt.Errorf("API token of '%s' is used twice", t.Name)
+		}
+		name := tokenPrincipalPrefix + t.Name
+		s.principals[hash] = Principal{Name: name, Groups: t.Groups}
+
+		for _, scope := range t.Scopes {
+			rule, err := parseScope(scope)
+			if err != nil {
+				return nil, fmt.Errorf("API token of '%s': %w", t.Name, err)
+			}
+			rule.Subjects = []string{name}
+			s.rules = append(s.rules, rule)
+		}
+	}
+
+	return s, nil
+}
+
+// LoadTokenSet reads a JSON array of APITokens from path.
+func LoadTokenSet(path string) (*TokenSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewTokenSet(tokens...)
+}
+
+// Authenticate returns the principal token belongs to.
+func (s *TokenSet) Authenticate(token string) (Principal, bool) {
+	p, ok := s.principals[sha256.Sum256([]byte(token))]
+	return p, ok
+}
+
+// Rules returns the access rules the tokens' scopes grant, to be placed in
+// an AccessPolicy, alone or next to other rules.
+func (s *TokenSet) Rules() []AccessRule {
+	return append([]AccessRule(nil), s.rules...)
+}
+
+// parseScope turns a scope into the rule it grants, without subjects.
+func parseScope(scope string) (AccessRule, error) {
+	parts := strings.Split(scope, ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return AccessRule{}, fmt.Errorf("invalid scope '%s'", scope)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "*")
+	}
+
+	verb := Verb(parts[0])
+	switch verb {
+	case VerbGet, VerbList, VerbStore, VerbDelete, "*":
+	default:
+		return AccessRule{}, fmt.Errorf("scope '%s' has unknown verb '%s'", scope, verb)
+	}
+	if _, ok := lookupKind(parts[1]); !ok && parts[1] != "*" {
+		return AccessRule{}, fmt.Errorf("scope '%s': %w '%s'", scope, ErrUnknownKind, parts[1])
+	}
+
+	return AccessRule{Verbs: []Verb{verb}, Kinds: []string{parts[1]}, Namespaces: []string{parts[2]}}, nil
+}
+
+// PrincipalFromCertificate names the principal behind a verified client
+// certificate: its subject's common name, with its organizational units as
+// groups. Common names starting with "token:", "group:" or "admin:" are
+// rejected, as they would pass for a token, a group or the admin UI.
+func PrincipalFromCertificate(cert *x509.Certificate) (Principal, bool) {
+	if cert.Subject.CommonName == "" {
+		return Principal{}, false
+	}
+	for _, prefix := range reservedPrincipalPrefixes {
+		if strings.HasPrefix(cert.Subject.CommonName, prefix) {
+			return Principal{}, false
+		}
+	}
+
+	return Principal{Name: cert.Subject.CommonName, Groups: cert.Subject.OrganizationalUnit}, true
+}
@@ -0,0 +1,112 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+)
+
+// Edge is one reference followed by Traverse: the From object's Field holds
+// the ID of the To object.
+type Edge struct {
+	FromKind string `json:"from_kind"`
+	FromID   string `json:"from_id"`
+	Field    string `json:"field"`
+	ToKind   string `json:"to_kind"`
+	ToID     string `json:"to_id"`
+}
+
+type Subgraph struct {
+	Objects []Object `json:"objects"`
+	Edges   []Edge   `json:"edges"`
+}
+
+// Traverse walks references outwards from the object with startID, in both
+// directions: fields the object holds and fields of other objects pointing
+// at it. depth bounds how many hops are taken; 0 returns only the start
+// object. edgeTags restricts the walk to references whose `ref` tag is
+// listed, and an empty list follows every reference.
+//
+// Traverse(ctx, personID, 1) returns a person and everything they own.
+func (db *RedisObjectDB) Traverse(ctx context.Context, startID string, depth int, edgeTags ...string) (*Subgraph, error) {
+	start, err := db.GetObjectByID(ctx, startID)
+	if err != nil {
+		return nil, err
+	}
+
+	follow := func(tag string) bool {
+		if len(edgeTags) == 0 {
+			return true
+		}
+		for _, t := range edgeTags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	graph := &Subgraph{Objects: []Object{start}}
+	visited := map[string]bool{objectKey(start.GetKind(), start.GetID()): true}
+	edges := map[Edge]bool{}
+
+	addEdge := func(from Object, ref reference) {
+		edge := Edge{FromKind: from.GetKind(), FromID: from.GetID(), Field: ref.Field, ToKind: ref.Kind, ToID: ref.ID}
+		if !edges[edge] {
+			edges[edge] = true
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	frontier := []Object{start}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []Object
+		visit := func(object Object) {
+			key := objectKey(object.GetKind(), object.GetID())
+			if !visited[key] {
+				visited[key] = true
+				graph.Objects = append(graph.Objects, object)
+				next = append(next, object)
+			}
+		}
+
+		for _, object := range frontier {
+			for _, ref := range objectReferences(object) {
+				if ref.Kind == "" || !follow(ref.Tag) {
+					continue
+				}
+
+				target, err := db.getObject(ctx, ref.Kind, ref.ID)
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				addEdge(object, ref)
+				visit(target)
+			}
+
+			referrers, err := db.ListReferrers(ctx, object.GetKind(), object.GetID())
+			if err != nil {
+				return nil, err
+			}
+			for _, referrer := range referrers {
+				linked := false
+				for _, ref := range objectReferences(referrer) {
+					if ref.Kind == object.GetKind() && ref.ID == object.GetID() && follow(ref.Tag) {
+						addEdge(referrer, ref)
+						linked = true
+					}
+				}
+				if linked {
+					visit(referrer)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return graph, nil
+}
@@ -0,0 +1,29 @@
+package objectdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// StoreTx stores several objects atomically: either all of them are
+// written, in one MULTI, or none are. Objects may reference each other, so
+// a Person and their Animals can be created together with reference
+// validation on. The error for the first invalid object is returned. In
+// write-behind mode StoreTx still writes immediately.
+func (db *RedisObjectDB) StoreTx(ctx context.Context, objects ...Object) error {
+	ctx, cancel := db.withTimeout(ctx, "StoreTx")
+	defer cancel()
+
+	errs, err := db.storeBatch(ctx, objects, true)
+	if err != nil {
+		return err
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("object %d (%s '%s'): %w", i, objects[i].GetKind(), objects[i].GetID(), err)
+		}
+	}
+
+	return nil
+}
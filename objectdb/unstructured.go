@@ -0,0 +1,228 @@
+package objectdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Unstructured is an object of a kind that has no Go type: its metadata
+// lives in ObjectMeta like any other object's, and every other field in
+// Fields. It encodes as one flat object, the way a struct kind does, so
+// generic tools can read and write data without knowing its shape.
+type Unstructured struct {
+	ObjectMeta
+	Fields map[string]interface{} `json:"-"`
+
+	kind string
+}
+
+// NewUnstructured returns an empty object of kind.
+func NewUnstructured(kind string) *Unstructured {
+	return &Unstructured{kind: kind, Fields: map[string]interface{}{}}
+}
+
+// RegisterUnstructuredKind registers kind with *Unstructured as its type,
+// so its objects can be stored, read and queried, by any field, without
+// declaring a struct.
+func RegisterUnstructuredKind(kind string) error {
+	if err := RegisterKind(func() Object { return NewUnstructured(kind) }); err != nil {
+		return err
+	}
+
+	kinds[kind].unstructured = true
+	return nil
+}
+
+func (u *Unstructured) GetKind() string {
+	return u.kind
+}
+
+func (u *Unstructured) SetKind(kind string) {
+	u.kind = kind
+}
+
+// Get returns the field at path, where dots step into nested objects.
+func (u *Unstructured) Get(path string) (interface{}, bool) {
+	var v interface{} = u.Fields
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// Set sets the field at path, creating the nested objects it steps into
+// and replacing any values in their way.
+func (u *Unstructured) Set(path string, value interface{}) {
+	if u.Fields == nil {
+		u.Fields = map[string]interface{}{}
+	}
+
+	m := u.Fields
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		next, ok := m[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[name] = next
+		}
+		m = next
+	}
+	m[names[len(names)-1]] = value
+}
+
+// metaFieldNames are the JSON names of the ObjectMeta fields, which an
+// Unstructured keeps out of Fields.
+var metaFieldNames = func() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(ObjectMeta{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		names[name] = true
+	}
+
+	return names
+}()
+
+// flatten merges the metadata into a copy of Fields.
+func (u *Unstructured) flatten() (map[string]interface{}, error) {
+	data, err := json.Marshal(&u.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]interface{}, len(u.Fields)+len(metaFieldNames))
+	for name, v := range u.Fields {
+		if !metaFieldNames[name] {
+			flat[name] = v
+		}
+	}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, err
+	}
+
+	return flat, nil
+}
+
+// unflatten splits flat into the metadata and Fields.
+func (u *Unstructured) unflatten(flat map[string]interface{}) error {
+	meta := map[string]interface{}{}
+	u.Fields = make(map[string]interface{}, len(flat))
+	for name, v := range flat {
+		if metaFieldNames[name] {
+			meta[name] = v
+		} else {
+			u.Fields[name] = v
+		}
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	u.ObjectMeta = ObjectMeta{}
+	return json.Unmarshal(data, &u.ObjectMeta)
+}
+
+func (u *Unstructured) MarshalJSON() ([]byte, error) {
+	flat, err := u.flatten()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(flat)
+}
+
+func (u *Unstructured) UnmarshalJSON(data []byte) error {
+	var flat map[string]interface{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return err
+	}
+
+	return u.unflatten(flat)
+}
+
+func (u *Unstructured) EncodeMsgpack(enc *msgpack.Encoder) error {
+	flat, err := u.flatten()
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(flat)
+}
+
+func (u *Unstructured) DecodeMsgpack(dec *msgpack.Decoder) error {
+	var flat map[string]interface{}
+	if err := dec.Decode(&flat); err != nil {
+		return err
+	}
+
+	return u.unflatten(flat)
+}
+
+func (u *Unstructured) MarshalCBOR() ([]byte, error) {
+	flat, err := u.flatten()
+	if err != nil {
+		return nil, err
+	}
+
+	return cborEnc.Marshal(flat)
+}
+
+func (u *Unstructured) UnmarshalCBOR(data []byte) error {
+	var flat map[string]interface{}
+	if err := cborDec.Unmarshal(data, &flat); err != nil {
+		return err
+	}
+
+	return u.unflatten(flat)
+}
+
+// ToUnstructured converts object, of any kind, to an Unstructured holding
+// the same fields.
+func ToUnstructured(object Object) (*Unstructured, error) {
+	if u, ok := object.(*Unstructured); ok {
+		return u, nil
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	u := NewUnstructured(object.GetKind())
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// FromUnstructured converts u to an object of its kind's registered type.
+func FromUnstructured(u *Unstructured) (Object, error) {
+	object, err := NewObject(u.GetKind())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := object.(*Unstructured); ok {
+		return u, nil
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
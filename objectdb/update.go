@@ -0,0 +1,127 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrVersionConflict is returned by UpdateIf when the stored object is not
+// at the expected version.
+var ErrVersionConflict = errors.New("version conflict")
+
+// updateRetries bounds how often UpdateIf re-reads an object that changed
+// between its read and its write.
+const updateRetries = 5
+
+// UpdateIf reads object id, lets mutate change it and stores the result,
+// provided nothing else wrote the object in between. If the stored version
+// is not expectedVersion the update fails with ErrVersionConflict; an
+// expectedVersion of 0 accepts any version, and a concurrent write then
+// makes UpdateIf retry with the new value, up to a few times. mutate may be
+// called more than once and must not have side effects; an error from it
+// aborts the update and is returned as is.
+func (db *RedisObjectDB) UpdateIf(ctx context.Context, id string, expectedVersion int64, mutate func(Object) error) (Object, error) {
+	ctx, cancel := db.withTimeout(ctx, "UpdateIf")
+	defer cancel()
+
+	found, err := db.GetObjectByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	kind := found.GetKind()
+
+	check := func(previous Object) (bool, error) {
+		if expectedVersion != 0 && previous.GetObjectMeta().Version != expectedVersion {
+			return false, fmt.Errorf("%s '%s' is at version %d, not %d: %w",
+				kind, id, previous.GetObjectMeta().Version, expectedVersion, ErrVersionConflict)
+		}
+		return true, nil
+	}
+
+	return db.casUpdate(ctx, kind, id, check, mutate, true)
+}
+
+// casUpdate stores kind/id as mutate changes it, provided nothing else
+// wrote it in between, re-reading and retrying a few times if something
+// did. check sees the stored object first, and it is left alone if check
+// returns false or an error. Unless writeUnchanged is set, neither is it
+// if mutate changed nothing. The returned object is nil if nothing was
+// written.
+func (db *RedisObjectDB) casUpdate(ctx context.Context, kind, id string, check func(Object) (bool, error), mutate func(Object) error, writeUnchanged bool) (Object, error) {
+	key := objectKey(kind, id)
+
+	var updated Object
+	txf := func(tx *redis.Tx) error {
+		updated = nil
+		val, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return fmt.Errorf("%s with ID '%s' %w", kind, id, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		previous, err := db.decode(kind, val)
+		if err != nil {
+			return err
+		}
+		if ok, err := check(previous); !ok || err != nil {
+			return err
+		}
+
+		object, err := db.decode(kind, val)
+		if err != nil {
+			return err
+		}
+		err = mutate(object)
+		if err != nil {
+			return err
+		}
+		if object.GetID() != id || object.GetKind() != kind {
+			return fmt.Errorf("mutate must not change the kind or ID of %s '%s'", kind, id)
+		}
+		if !writeUnchanged && reflect.DeepEqual(object, previous) {
+			return nil
+		}
+
+		err = db.validateObject(ctx, object, nil)
+		if err != nil {
+			return err
+		}
+		value, err := db.prepareWrite(ctx, object, previous)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			db.queueWrite(ctx, pipe, object, previous, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		updated = object
+		return nil
+	}
+
+	var err error
+	for attempt := 0; attempt < updateRetries; attempt++ {
+		err = db.redisClient.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, fmt.Errorf("%s '%s' kept changing: %w", kind, id, ErrVersionConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
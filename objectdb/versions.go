@@ -0,0 +1,134 @@
+package objectdb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Conversion lets a kind change its Go type without rewriting what is
+// stored: objects of Kind stored at version From decode into the type New
+// returns, and Convert turns them into version To. Conversions chain, so a
+// kind at v3 reads v1 objects through v2; the last To is the version of the
+// kind's registered type, which Store writes.
+//
+// Unlike a Migration, which reshapes stored documents, a conversion works on
+// typed objects on every read, and stored values keep their version until
+// they are next written.
+type Conversion struct {
+	Kind string
+	From string
+	To   string
+	// New returns an empty object of version From; its GetKind must return
+	// Kind.
+	New func() Object
+	// Convert returns the version To of from. It only has to map the kind's
+	// own fields: the metadata is carried over.
+	Convert func(from Object) (Object, error)
+}
+
+// conversions holds the registered conversions of each kind, oldest first.
+var conversions = map[string][]Conversion{}
+
+// RegisterConversion adds c to its kind. The From of a kind's first
+// conversion is the version of the objects stored before the kind had
+// versions; each later conversion must start where the previous one ended.
+func RegisterConversion(c Conversion) error {
+	if _, ok := kinds[c.Kind]; !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKind, c.Kind)
+	}
+	if c.From == "" || c.To == "" || c.From == c.To {
+		return fmt.Errorf("conversion %s '%s' to '%s' needs two distinct versions", c.Kind, c.From, c.To)
+	}
+	if c.New == nil || c.Convert == nil {
+		return fmt.Errorf("conversion %s %s to %s needs New and Convert functions", c.Kind, c.From, c.To)
+	}
+	if object := c.New(); object == nil || object.GetKind() != c.Kind {
+		return fmt.Errorf("conversion %s %s to %s: New does not return a %s", c.Kind, c.From, c.To, c.Kind)
+	}
+
+	existing := conversions[c.Kind]
+	if len(existing) > 0 {
+		if current := currentKindVersion(c.Kind); c.From != current {
+			return fmt.Errorf("conversion %s %s to %s registered out of order, the kind is at %s", c.Kind, c.From, c.To, current)
+		}
+	}
+	for _, e := range existing {
+		if e.From == c.To {
+			return fmt.Errorf("conversion %s %s to %s leads back to an older version", c.Kind, c.From, c.To)
+		}
+	}
+
+	conversions[c.Kind] = append(existing, c)
+	return nil
+}
+
+// currentKindVersion is the version new objects of kind are written at,
+// empty for kinds without versions.
+func currentKindVersion(kind string) string {
+	steps := conversions[kind]
+	if len(steps) == 0 {
+		return ""
+	}
+
+	return steps[len(steps)-1].To
+}
+
+// versionPeek reads the version of a stored value and nothing else.
+type versionPeek struct {
+	APIVersion string `json:"api_version"`
+}
+
+// decodeVersion decodes payload, written by c, into kind's registered type,
+// converting it from the version it was stored at. Values the codec cannot
+// decode into a plain struct, such as protobuf ones, are taken to be at the
+// current version.
+func decodeVersion(info *kindInfo, kind string, c Codec, payload []byte) (Object, error) {
+	steps := conversions[kind]
+	var peek versionPeek
+	if len(steps) == 0 || c.Unmarshal(payload, &peek) != nil || peek.APIVersion == currentKindVersion(kind) {
+		object := info.newObject()
+		if err := c.Unmarshal(payload, object); err != nil {
+			return nil, err
+		}
+		return object, nil
+	}
+
+	version := peek.APIVersion
+	if version == "" {
+		version = steps[0].From
+	}
+	first := -1
+	for i, step := range steps {
+		if step.From == version {
+			first = i
+		}
+	}
+	if first < 0 {
+		return nil, fmt.Errorf("%s has no version '%s'", kind, version)
+	}
+
+	object := steps[first].New()
+	if err := c.Unmarshal(payload, object); err != nil {
+		return nil, err
+	}
+	for i := first; i < len(steps); i++ {
+		want := reflect.TypeOf(info.newObject())
+		if i+1 < len(steps) {
+			want = reflect.TypeOf(steps[i+1].New())
+		}
+
+		meta := *object.GetObjectMeta()
+		next, err := steps[i].Convert(object)
+		if err != nil {
+			return nil, fmt.Errorf("convert %s %s to %s: %w", kind, steps[i].From, steps[i].To, err)
+		}
+		if reflect.TypeOf(next) != want {
+			return nil, fmt.Errorf("convert %s %s to %s returned %T, not %s", kind, steps[i].From, steps[i].To, next, want)
+		}
+		meta.APIVersion = steps[i].To
+		*next.GetObjectMeta() = meta
+		object = next
+	}
+
+	return object, nil
+}
@@ -0,0 +1,203 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BulkProgress is how far a DeleteWhere or UpdateWhere call has got.
+type BulkProgress struct {
+	Kind string `json:"kind"`
+	// Checked objects were read as candidates for the filters; Changed is
+	// how many of them matched and were deleted or updated.
+	Checked int64 `json:"checked"`
+	Changed int64 `json:"changed"`
+	Done    bool  `json:"done"`
+}
+
+type bulkProgressKey struct{}
+
+// ContextWithBulkProgress returns a copy of ctx that makes DeleteWhere and
+// UpdateWhere call fn after each batch.
+func ContextWithBulkProgress(ctx context.Context, fn func(BulkProgress)) context.Context {
+	return context.WithValue(ctx, bulkProgressKey{}, fn)
+}
+
+func bulkProgressFromContext(ctx context.Context) func(BulkProgress) {
+	if fn, ok := ctx.Value(bulkProgressKey{}).(func(BulkProgress)); ok {
+		return fn
+	}
+
+	return func(BulkProgress) {}
+}
+
+// DeleteWhere deletes every object of kind matching all filters, with
+// their index entries, and returns how many it deleted. Candidates come
+// from the indexes as for Count and are deleted fetchBatchSize at a time,
+// each batch in one MULTI after re-checking the filters under WATCH, so an
+// object changed in the meantime is only deleted if it still matches.
+// Objects referencing the deleted ones are left alone, as by DeleteObject
+// without a policy. Use ContextWithBulkProgress to follow along.
+func (db *RedisObjectDB) DeleteWhere(ctx context.Context, kind string, filters ...Filter) (int, error) {
+	name, ok := lookupKind(kind)
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	if err := checkFilters(kind, filters); err != nil {
+		return 0, err
+	}
+
+	keys, err := db.filterCandidates(ctx, kind, filters)
+	if err != nil {
+		return 0, err
+	}
+
+	report := bulkProgressFromContext(ctx)
+	progress := BulkProgress{Kind: kind}
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > db.fetchBatchSize {
+			n = db.fetchBatchSize
+		}
+		deleted, err := db.deleteWhereBatch(ctx, kind, filters, keys[:n])
+		if err != nil {
+			return int(progress.Changed), err
+		}
+		keys = keys[n:]
+
+		progress.Checked += int64(n)
+		progress.Changed += int64(deleted)
+		progress.Done = len(keys) == 0
+		report(progress)
+	}
+
+	return int(progress.Changed), nil
+}
+
+func (db *RedisObjectDB) deleteWhereBatch(ctx context.Context, kind string, filters []Filter, keys []string) (int, error) {
+	var deleted int
+	txf := func(tx *redis.Tx) error {
+		vals, err := tx.MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		var doomed []Object
+		for i, val := range vals {
+			data, ok := val.(string)
+			if !ok {
+				continue
+			}
+			object, err := db.decode(kind, []byte(data))
+			if err != nil {
+				return fmt.Errorf("decode '%s': %w", keys[i], err)
+			}
+			if matches(object, filters) {
+				doomed = append(doomed, object)
+			}
+		}
+		if len(doomed) == 0 {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, object := range doomed {
+				queueDel(ctx, pipe, objectKey(kind, object.GetID()))
+				updateIndexes(ctx, pipe, object, nil)
+				db.recordChange(ctx, pipe, ChangeDelete, kind, object.GetID(), nil)
+			}
+			return nil
+		})
+		if err == nil {
+			deleted = len(doomed)
+		}
+		return err
+	}
+
+	for attempt := 0; attempt < updateRetries; attempt++ {
+		deleted = 0
+		err := db.redisClient.Watch(ctx, txf, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return deleted, err
+		}
+	}
+
+	return 0, fmt.Errorf("%s objects kept changing: %w", kind, ErrVersionConflict)
+}
+
+// UpdateWhere applies mutate to every object of kind matching all filters
+// and returns how many objects it changed:
+//
+//	db.UpdateWhere(ctx, "animal", []Filter{{Field: "owner_id", Value: "42"}}, func(o Object) error {
+//		o.(*Animal).OwnerID = "43"
+//		return nil
+//	})
+//
+// Candidates come from the indexes as for Count, and each matching one is
+// updated as by UpdateIf: re-read under WATCH, re-checked against the
+// filters and written only if nothing else wrote it meanwhile, retrying a
+// few times before failing with ErrVersionConflict. Objects mutate leaves
+// as they were are not written. An error from mutate stops the update
+// with the objects before it changed. Use ContextWithBulkProgress to
+// follow along.
+func (db *RedisObjectDB) UpdateWhere(ctx context.Context, kind string, filters []Filter, mutate func(Object) error) (int, error) {
+	name, ok := lookupKind(kind)
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", ErrUnknownKind, kind)
+	}
+	kind = name
+	if err := checkFilters(kind, filters); err != nil {
+		return 0, err
+	}
+
+	keys, err := db.filterCandidates(ctx, kind, filters)
+	if err != nil {
+		return 0, err
+	}
+
+	check := func(previous Object) (bool, error) {
+		return matches(previous, filters), nil
+	}
+	report := bulkProgressFromContext(ctx)
+	progress := BulkProgress{Kind: kind}
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > db.fetchBatchSize {
+			n = db.fetchBatchSize
+		}
+		var ids []string
+		err := db.fetchObjects(ctx, keys[:n], func(object Object) error {
+			if matches(object, filters) {
+				ids = append(ids, object.GetID())
+			}
+			return nil
+		})
+		if err != nil {
+			return int(progress.Changed), err
+		}
+		keys = keys[n:]
+
+		for _, id := range ids {
+			updated, err := db.casUpdate(ctx, kind, id, check, mutate, false)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return int(progress.Changed), err
+			}
+			if updated != nil {
+				progress.Changed++
+			}
+		}
+
+		progress.Checked += int64(n)
+		progress.Done = len(keys) == 0
+		report(progress)
+	}
+
+	return int(progress.Changed), nil
+}
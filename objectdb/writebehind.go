@@ -0,0 +1,158 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Store in write-behind mode once Close has
+// started.
+var ErrClosed = errors.New("object store is closed")
+
+type WriteBehindOptions struct {
+	// Buffer is how many objects may wait to be written, across all
+	// workers. Store blocks once it is full. Defaults to 10000.
+	Buffer int
+	// Workers is how many goroutines write batches. Defaults to 4.
+	Workers int
+	// FlushInterval is the longest an object waits in the buffer, and so
+	// the window of writes lost if the process dies. Defaults to 100ms.
+	FlushInterval time.Duration
+	// OnError is called for every object that could not be written, as
+	// Store can no longer return the error itself.
+	OnError func(Object, error)
+}
+
+type writeBehind struct {
+	opts   WriteBehindOptions
+	queues []chan Object
+
+	// mu keeps Close from closing the queues under a blocked send.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// WithWriteBehind makes Store validate the object and return once it is
+// buffered, leaving background workers to write buffered objects in
+// pipelined batches of WithFetchBatchSize. Reads do not see buffered
+// objects, the caller's object does not get its metadata stamped, and
+// writes still buffered when the process dies are lost; Close writes out
+// the buffer before returning. Writes to the same object keep their order.
+func WithWriteBehind(opts WriteBehindOptions) Option {
+	return func(db *RedisObjectDB) {
+		if opts.Buffer <= 0 {
+			opts.Buffer = 10000
+		}
+		if opts.Workers <= 0 {
+			opts.Workers = 4
+		}
+		if opts.FlushInterval <= 0 {
+			opts.FlushInterval = 100 * time.Millisecond
+		}
+		db.writeBehind = &writeBehind{opts: opts}
+	}
+}
+
+// startWriteBehind starts the workers once every option has been applied.
+func (db *RedisObjectDB) startWriteBehind() {
+	wb := db.writeBehind
+	perWorker := (wb.opts.Buffer + wb.opts.Workers - 1) / wb.opts.Workers
+
+	wb.queues = make([]chan Object, wb.opts.Workers)
+	for i := range wb.queues {
+		queue := make(chan Object, perWorker)
+		wb.queues[i] = queue
+		// Buffered objects are written even after Close cancels ctx,
+		// so the worker ignores it and stops when its queue closes.
+		db.goBackground(func(context.Context) {
+			db.writeBehindWorker(queue)
+		})
+	}
+}
+
+// bufferWrite queues a copy of object on the worker owning its key, so
+// later changes by the caller do not leak into the write.
+func (db *RedisObjectDB) bufferWrite(ctx context.Context, object Object) error {
+	clone, err := cloneObject(object)
+	if err != nil {
+		return err
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(objectKey(object.GetKind(), object.GetID())))
+
+	wb := db.writeBehind
+	wb.mu.RLock()
+	defer wb.mu.RUnlock()
+	if wb.closed {
+		return ErrClosed
+	}
+
+	select {
+	case wb.queues[h.Sum32()%uint32(len(wb.queues))] <- clone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (db *RedisObjectDB) writeBehindWorker(queue chan Object) {
+	ticker := time.NewTicker(db.writeBehind.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Object
+	for {
+		select {
+		case object, ok := <-queue:
+			if !ok {
+				db.flushWriteBehind(batch)
+				return
+			}
+			batch = append(batch, object)
+			if len(batch) >= db.fetchBatchSize {
+				db.flushWriteBehind(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			db.flushWriteBehind(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (db *RedisObjectDB) flushWriteBehind(batch []Object) {
+	if len(batch) == 0 {
+		return
+	}
+
+	onError := db.writeBehind.opts.OnError
+	errs, err := db.storeBatch(context.Background(), batch, false)
+	if onError == nil {
+		return
+	}
+	for i, object := range batch {
+		if err != nil {
+			onError(object, err)
+		} else if errs[i] != nil {
+			onError(object, errs[i])
+		}
+	}
+}
+
+// stopWriteBehind refuses further writes and lets the workers drain.
+func (db *RedisObjectDB) stopWriteBehind() {
+	wb := db.writeBehind
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if wb.closed {
+		return
+	}
+	wb.closed = true
+	for _, queue := range wb.queues {
+		close(queue)
+	}
+}
@@ -0,0 +1,7 @@
+// Package objectpb holds the Protocol Buffers form of the stored kinds, for
+// services that are not written in Go and for the protobuf codec, and the
+// ObjectService gRPC API.
+package objectpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative objects.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative objectservice.proto